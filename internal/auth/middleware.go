@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rick/go-neon-api/internal/models"
+	"github.com/rick/go-neon-api/internal/store"
+)
+
+const (
+	ctxUser   = "auth.user"
+	ctxRole   = "auth.role"
+	ctxClaims = "auth.claims"
+)
+
+// Required rejects requests without a valid bearer token and stores the
+// resolved models.User and models.Role in the gin context.
+func Required(users store.UserStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw := bearerToken(c)
+		if raw == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		claims, err := ParseToken(raw)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		u, err := users.ByID(c, claims.Subject)
+		if errors.Is(err, store.ErrNotFound) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "auth lookup failed"})
+			return
+		}
+		revoked, err := users.IsRevoked(c, claims.ID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "auth lookup failed"})
+			return
+		}
+		role := models.Role(strings.ToUpper(string(u.Role)))
+		if revoked || (role != models.RoleAdmin && role != models.RoleUser) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		// Tokens minted before the last password change are no longer valid.
+		if u.PasswordChangedAt != nil && claims.IssuedAt != nil &&
+			claims.IssuedAt.Time.Before(u.PasswordChangedAt.Truncate(time.Second)) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		u.Role = role
+		u.Password = nil // handlers that need the hash reload the user
+
+		c.Set(ctxUser, u)
+		c.Set(ctxRole, role)
+		c.Set(ctxClaims, claims)
+		c.Next()
+	}
+}
+
+// CurrentUser returns the authenticated user, or nil outside Required.
+func CurrentUser(c *gin.Context) *models.User {
+	if v, ok := c.Get(ctxUser); ok {
+		if u, ok := v.(*models.User); ok {
+			return u
+		}
+	}
+	return nil
+}
+
+// CurrentRole returns the authenticated user's role, or "" outside Required.
+func CurrentRole(c *gin.Context) models.Role {
+	if v, ok := c.Get(ctxRole); ok {
+		if r, ok := v.(models.Role); ok {
+			return r
+		}
+	}
+	return ""
+}
+
+// CurrentClaims returns the verified token claims, or nil outside Required.
+func CurrentClaims(c *gin.Context) *Claims {
+	if v, ok := c.Get(ctxClaims); ok {
+		if cl, ok := v.(*Claims); ok {
+			return cl
+		}
+	}
+	return nil
+}
+
+// IsAdmin reports whether the caller has the ADMIN role.
+func IsAdmin(c *gin.Context) bool { return CurrentRole(c) == models.RoleAdmin }
+
+func bearerToken(c *gin.Context) string {
+	h := strings.TrimSpace(c.GetHeader("Authorization"))
+	if len(h) < 7 || !strings.EqualFold(h[:7], "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(h[7:])
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MinPasswordLength is enforced on every password set through the API.
+const MinPasswordLength = 8
+
+// dummyHash is compared against when the user does not exist so that login
+// timing does not reveal which emails are registered.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
+
+// HashPassword returns the bcrypt hash stored in User.password.
+func HashPassword(plain string) (string, error) {
+	if len(plain) < MinPasswordLength {
+		return "", errors.New("password too short")
+	}
+	b, err := bcrypt.GenerateFromPassword([]byte(plain), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// CheckPassword reports whether plain matches hash. A nil hash (user without a
+// password) never matches but still costs one bcrypt comparison.
+func CheckPassword(hash *string, plain string) bool {
+	if hash == nil || *hash == "" {
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(plain))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(*hash), []byte(plain)) == nil
+}
+
+// NewResetToken returns a random URL-safe token and the SHA-256 hex digest
+// that is persisted instead of the token itself.
+func NewResetToken() (token, digest string, err error) {
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(b)
+	return token, HashResetToken(token), nil
+}
+
+// HashResetToken digests a reset token for lookup.
+func HashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
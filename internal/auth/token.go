@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/lucsky/cuid"
+)
+
+var (
+	secret   []byte
+	tokenTTL = 24 * time.Hour
+)
+
+// Configure loads the signing secret and token lifetime from the environment.
+// AUTH_SECRET is required; AUTH_TOKEN_TTL is an optional Go duration (e.g. "12h").
+func Configure() {
+	s := os.Getenv("AUTH_SECRET")
+	if s == "" {
+		log.Fatal("AUTH_SECRET not set")
+	}
+	if len(s) < 32 {
+		log.Fatal("AUTH_SECRET must be at least 32 characters")
+	}
+	secret = []byte(s)
+
+	if v := os.Getenv("AUTH_TOKEN_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("invalid AUTH_TOKEN_TTL %q", v)
+		}
+		tokenTTL = d
+	}
+}
+
+// Claims is the payload of a signed access token. Subject holds the User.id;
+// the role is always re-read from the database so demotions apply immediately.
+type Claims struct {
+	jwt.RegisteredClaims
+}
+
+// IssueToken signs a new HS256 access token for the given user.
+func IssueToken(userID string) (string, time.Time, error) {
+	if len(secret) == 0 {
+		return "", time.Time{}, errors.New("auth not configured")
+	}
+	now := time.Now()
+	exp := now.Add(tokenTTL)
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        cuid.New(),
+			Subject:   userID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(exp),
+		},
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, exp, nil
+}
+
+// ParseToken verifies the signature and expiry of a raw token.
+func ParseToken(raw string) (*Claims, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("auth not configured")
+	}
+	var claims Claims
+	_, err := jwt.ParseWithClaims(raw, &claims, func(t *jwt.Token) (any, error) {
+		return secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+	return &claims, nil
+}
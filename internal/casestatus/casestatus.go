@@ -0,0 +1,124 @@
+// Package casestatus defines the Case.status lifecycle and which roles may
+// move a case between statuses.
+package casestatus
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+
+	"github.com/rick/go-neon-api/internal/models"
+)
+
+const (
+	New                = "New"
+	SiteVisitScheduled = "Site Visit Scheduled"
+	Surveyed           = "Surveyed"
+	Quoted             = "Quoted"
+	Won                = "Won"
+	Lost               = "Lost"
+	Installed          = "Installed"
+)
+
+// All lists the statuses in lifecycle order.
+var All = []string{New, SiteVisitScheduled, Surveyed, Quoted, Won, Lost, Installed}
+
+// Any is a "from" key that matches every current status, including legacy
+// values that are not in All.
+const Any = "*"
+
+// Rules maps role → current status → statuses it may move to.
+type Rules map[models.Role]map[string][]string
+
+var userEdges = map[string][]string{
+	New:                {SiteVisitScheduled, Lost},
+	SiteVisitScheduled: {Surveyed, New, Lost},
+	Surveyed:           {Quoted, Lost},
+	Quoted:             {Won, Lost, Surveyed},
+	Won:                {Installed},
+}
+
+// DefaultRules: USERs follow the forward lifecycle; ADMINs may additionally
+// reopen lost cases, undo a win or installation, and reset anything to New.
+var DefaultRules = Rules{
+	models.RoleUser: userEdges,
+	models.RoleAdmin: merge(userEdges, map[string][]string{
+		Lost:      {New, Quoted},
+		Won:       {Quoted},
+		Installed: {Won},
+		Any:       {New},
+	}),
+}
+
+// Valid reports whether s is a known status.
+func Valid(s string) bool { return slices.Contains(All, s) }
+
+// Allowed reports whether role may move a case from → to.
+func (r Rules) Allowed(role models.Role, from, to string) bool {
+	return slices.Contains(r.Next(role, from), to)
+}
+
+// Next returns the statuses role may move a case in status from to.
+func (r Rules) Next(role models.Role, from string) []string {
+	edges := r[role]
+	out := []string{}
+	for _, to := range append(slices.Clone(edges[from]), edges[Any]...) {
+		if to != from && !slices.Contains(out, to) {
+			out = append(out, to)
+		}
+	}
+	return out
+}
+
+// MustLoad returns DefaultRules, or the rules in the JSON file named by
+// CASE_STATUS_RULES_FILE, e.g. {"USER": {"New": ["Site Visit Scheduled"]}}.
+func MustLoad() Rules {
+	path := os.Getenv("CASE_STATUS_RULES_FILE")
+	if path == "" {
+		return DefaultRules
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("read CASE_STATUS_RULES_FILE: %v", err)
+	}
+	var r Rules
+	if err := json.Unmarshal(b, &r); err != nil {
+		log.Fatalf("parse CASE_STATUS_RULES_FILE: %v", err)
+	}
+	if err := r.validate(); err != nil {
+		log.Fatalf("CASE_STATUS_RULES_FILE: %v", err)
+	}
+	return r
+}
+
+func (r Rules) validate() error {
+	for role, edges := range r {
+		if role != models.RoleAdmin && role != models.RoleUser {
+			return fmt.Errorf("unknown role %q", role)
+		}
+		for from, tos := range edges {
+			if from != Any && !Valid(from) {
+				return fmt.Errorf("%s: unknown status %q", role, from)
+			}
+			for _, to := range tos {
+				if !Valid(to) {
+					return fmt.Errorf("%s: unknown status %q", role, to)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func merge(base, extra map[string][]string) map[string][]string {
+	out := make(map[string][]string, len(base)+len(extra))
+	for k, v := range base {
+		out[k] = slices.Clone(v)
+	}
+	for k, v := range extra {
+		out[k] = append(out[k], v...)
+	}
+	return out
+}
@@ -10,16 +10,16 @@ import (
 	"gorm.io/gorm/logger"
 )
 
-var DB *gorm.DB
-
-func Connect() {
+// Connect opens the database named by DATABASE_URL using the Prisma naming
+// strategy.
+func Connect() *gorm.DB {
 	dsn := os.Getenv("DATABASE_URL")
 	if dsn == "" {
 		log.Fatal("DATABASE_URL not set")
 	}
 
-	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		NamingStrategy: NewPrismaNamer(),
 		Logger: logger.New(log.New(os.Stdout, "", log.LstdFlags), logger.Config{
 			SlowThreshold:             500 * time.Millisecond,
 			Colorful:                  false,
@@ -30,4 +30,5 @@ func Connect() {
 	if err != nil {
 		log.Fatalf("failed to connect database: %v", err)
 	}
+	return db
 }
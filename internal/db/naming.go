@@ -0,0 +1,83 @@
+package db
+
+import (
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm/schema"
+)
+
+// PrismaNamer maps models onto the schema Prisma created: tables are the
+// model name verbatim ("Case", "OnSiteVisitRoom") and columns are the
+// lowerCamel field name with initialisms title-cased ("userId", "imageUrl").
+// That is the same naming the handlers' raw SQL uses.
+type PrismaNamer struct {
+	schema.NamingStrategy
+}
+
+// NewPrismaNamer returns the naming strategy used by Connect.
+func NewPrismaNamer() PrismaNamer {
+	return PrismaNamer{schema.NamingStrategy{SingularTable: true, NoLowerCase: true}}
+}
+
+func (n PrismaNamer) ColumnName(table, column string) string {
+	return prismaColumn(column)
+}
+
+func (n PrismaNamer) IndexName(table, column string) string {
+	return table + "_" + prismaColumn(column) + "_idx"
+}
+
+func (n PrismaNamer) UniqueName(table, column string) string {
+	return table + "_" + prismaColumn(column) + "_key"
+}
+
+// initialisms are rewritten to title case when they follow a lowercase
+// letter or digit, so "OnSiteVisitID" becomes "onSiteVisitId".
+var initialisms = []string{"URL", "ID"}
+
+// prismaColumn converts a Go field name to its Prisma column name.
+func prismaColumn(field string) string {
+	if field == "" || field == strings.ToUpper(field) {
+		// "ID" → "id", "URL" → "url"; all-caps columns such as "SKU"
+		// keep their case via an explicit column tag.
+		return strings.ToLower(field)
+	}
+
+	r := []rune(field)
+	// Lower the leading upper-case run, leaving the last letter of a leading
+	// initialism to start the next word: "URLPath" → "urlPath".
+	i := 0
+	for i < len(r) && unicode.IsUpper(r[i]) {
+		i++
+	}
+	if i > 1 && i < len(r) && unicode.IsLower(r[i]) {
+		i--
+	}
+	if i == 0 {
+		i = 1
+	}
+	for j := 0; j < i; j++ {
+		r[j] = unicode.ToLower(r[j])
+	}
+	out := string(r)
+
+	for _, ini := range initialisms {
+		title := ini[:1] + strings.ToLower(ini[1:])
+		var b strings.Builder
+		for k := 0; k < len(out); {
+			if strings.HasPrefix(out[k:], ini) && k > 0 && !unicode.IsUpper(rune(out[k-1])) {
+				end := k + len(ini)
+				if end == len(out) || unicode.IsUpper(rune(out[end])) {
+					b.WriteString(title)
+					k = end
+					continue
+				}
+			}
+			b.WriteByte(out[k])
+			k++
+		}
+		out = b.String()
+	}
+	return out
+}
@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rick/go-neon-api/internal/storage"
+	"github.com/rick/go-neon-api/internal/store"
+)
+
+// Case archive layout:
+//
+//	documents/<name>
+//	photos/<name>
+//	rooms/<location>/<name>
+//	manifest.json
+//
+// Only files held in storage are packed. Rows that point at an external URL
+// are listed in the manifest with that url and no path; the server does not
+// fetch arbitrary URLs.
+
+type archiveDocument struct {
+	Path            string    `json:"path,omitempty"`
+	URL             string    `json:"url,omitempty"`
+	FileName        string    `json:"fileName"`
+	CustomName      *string   `json:"customName"`
+	Comment         *string   `json:"comment"`
+	UploadedViaLink bool      `json:"uploadedViaLink"`
+	CreatedAt       time.Time `json:"createdAt"`
+	Missing         bool      `json:"missing,omitempty"` // in the database but not in storage
+}
+
+type archivePhoto struct {
+	Path            string     `json:"path,omitempty"`
+	URL             string     `json:"url,omitempty"`
+	CustomName      *string    `json:"customName,omitempty"`
+	Comment         *string    `json:"comment"`
+	Tags            []string   `json:"tags,omitempty"`
+	UploadedViaLink bool       `json:"uploadedViaLink,omitempty"`
+	TakenAt         *time.Time `json:"takenAt"`
+	Latitude        *float64   `json:"latitude"`
+	Longitude       *float64   `json:"longitude"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	Missing         bool       `json:"missing,omitempty"`
+}
+
+type archiveRoom struct {
+	Folder      string         `json:"folder"` // location, made unique
+	Location    string         `json:"location"`
+	LocationTag *string        `json:"locationTag"`
+	Photos      []archivePhoto `json:"photos"`
+}
+
+type archiveManifest struct {
+	Case struct {
+		ID           string `json:"id"`
+		CustomerName string `json:"customerName"`
+		SchoolName   string `json:"schoolName"`
+		Status       string `json:"status"`
+	} `json:"case"`
+	GeneratedAt time.Time         `json:"generatedAt"`
+	Documents   []archiveDocument `json:"documents"`
+	Photos      []archivePhoto    `json:"photos"`
+	Rooms       []archiveRoom     `json:"rooms"`
+}
+
+// GET /api/cases/:id/archive
+// Streams a ZIP of the case's documents, photos and room photos with a
+// manifest.json. Each file is copied from storage straight into the
+// response, so memory use does not grow with the size of the case.
+func (h *Handlers) DownloadCaseArchive(c *gin.Context) {
+	id := c.Param("id")
+	head, err := h.store.Cases.Get(c, id)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load case"})
+		return
+	}
+	rooms, ok := h.caseRooms(c, id)
+	if !ok {
+		return
+	}
+
+	// Everything below writes to the response; failures can only be logged
+	// and leave a truncated archive the client will reject.
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, archiveFileName(head)))
+	c.Status(http.StatusOK)
+
+	a := &archiveWriter{h: h, c: c, zw: zip.NewWriter(c.Writer), names: map[string]bool{}}
+	m := archiveManifest{GeneratedAt: time.Now().UTC(), Documents: []archiveDocument{}, Photos: []archivePhoto{}, Rooms: []archiveRoom{}}
+	m.Case.ID, m.Case.CustomerName, m.Case.SchoolName, m.Case.Status = head.ID, head.CustomerName, head.SchoolName, head.Status
+
+	for _, d := range head.Documents {
+		name := d.FileName
+		if d.CustomName != nil {
+			name = withExt(*d.CustomName, d.FileName)
+		}
+		entry := archiveDocument{
+			FileName: d.FileName, CustomName: d.CustomName, Comment: d.Comment,
+			UploadedViaLink: d.UploadedViaLink, CreatedAt: d.CreatedAt,
+		}
+		entry.Path, entry.URL, entry.Missing = a.add("documents", name, d.URL, true)
+		m.Documents = append(m.Documents, entry)
+	}
+
+	for _, p := range head.Photos {
+		name := path.Base(p.URL)
+		if p.CustomName != nil {
+			name = withExt(*p.CustomName, p.URL)
+		}
+		entry := archivePhoto{
+			CustomName: p.CustomName, Comment: p.Comment, UploadedViaLink: p.UploadedViaLink,
+			TakenAt: p.TakenAt, Latitude: p.Latitude, Longitude: p.Longitude, CreatedAt: p.CreatedAt,
+		}
+		entry.Path, entry.URL, entry.Missing = a.add("photos", name, p.URL, false)
+		m.Photos = append(m.Photos, entry)
+	}
+
+	for _, r := range rooms {
+		dir := a.dir("rooms/" + archiveSegment(r.Location, "room"))
+		room := archiveRoom{Folder: dir, Location: r.Location, LocationTag: r.LocationTag, Photos: []archivePhoto{}}
+		for _, p := range r.Photos {
+			entry := archivePhoto{TakenAt: p.TakenAt, Latitude: p.Latitude, Longitude: p.Longitude, CreatedAt: p.CreatedAt}
+			if p.Comment != "" {
+				entry.Comment = &p.Comment
+			}
+			for _, t := range p.Tags {
+				entry.Tags = append(entry.Tags, t.Name)
+			}
+			entry.Path, entry.URL, entry.Missing = a.add(dir, path.Base(p.URL), p.URL, false)
+			room.Photos = append(room.Photos, entry)
+		}
+		m.Rooms = append(m.Rooms, room)
+	}
+
+	if a.err == nil {
+		if w, err := a.zw.CreateHeader(&zip.FileHeader{Name: "manifest.json", Method: zip.Deflate, Modified: m.GeneratedAt}); err != nil {
+			a.err = err
+		} else {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			a.err = enc.Encode(m)
+		}
+	}
+	if a.err == nil {
+		a.err = a.zw.Close()
+	}
+	if a.err != nil {
+		log.Printf("case %s archive: %v", id, a.err)
+		_ = c.Error(a.err)
+	}
+}
+
+// archiveWriter adds stored objects to the ZIP under unique names. After the
+// first write error it stops writing and only records the manifest entries.
+type archiveWriter struct {
+	h     *Handlers
+	c     *gin.Context
+	zw    *zip.Writer
+	names map[string]bool // entry names and directories used so far
+	err   error
+}
+
+// dir returns an unused directory name, suffixing " (2)", " (3)", ... so
+// rooms that share a location keep separate folders.
+func (a *archiveWriter) dir(base string) string {
+	name := base
+	for i := 2; a.names[name+"/"]; i++ {
+		name = fmt.Sprintf("%s (%d)", base, i)
+	}
+	a.names[name+"/"] = true
+	return name
+}
+
+// add copies the object stored at key into dir/name. It returns the entry
+// path, or the url itself when it is not a storage key, and whether the
+// object was missing from storage.
+func (a *archiveWriter) add(dir, name, stored string, compress bool) (entry, url string, missing bool) {
+	if !storage.IsKey(stored) {
+		return "", stored, false
+	}
+	if a.err != nil {
+		return "", "", false
+	}
+	rc, err := a.h.files.Open(a.c, stored)
+	if err != nil {
+		log.Printf("archive open %s: %v", stored, err)
+		return "", "", true
+	}
+	defer rc.Close()
+
+	entry = a.unique(dir, archiveSegment(name, "file"))
+	method := zip.Store // JPEG and PNG gain nothing from deflate
+	if compress {
+		method = zip.Deflate
+	}
+	w, err := a.zw.CreateHeader(&zip.FileHeader{Name: entry, Method: method, Modified: time.Now()})
+	if err == nil {
+		_, err = io.Copy(w, rc)
+	}
+	if err != nil {
+		a.err = err
+	}
+	return entry, "", false
+}
+
+// unique returns dir/name, suffixing the base name on a clash.
+func (a *archiveWriter) unique(dir, name string) string {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	entry := dir + "/" + name
+	for i := 2; a.names[entry]; i++ {
+		entry = fmt.Sprintf("%s/%s (%d)%s", dir, base, i, ext)
+	}
+	a.names[entry] = true
+	return entry
+}
+
+// archiveSegment makes a user-supplied name safe as one path segment.
+func archiveSegment(s, fallback string) string {
+	s = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || unicode.IsControl(r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(s))
+	s = strings.Trim(s, ".")
+	if s == "" {
+		return fallback
+	}
+	return s
+}
+
+// withExt appends the extension of original to name if name has none, so a
+// renamed "Front entrance" photo is still "Front entrance.jpg".
+func withExt(name, original string) string {
+	if path.Ext(name) == "" {
+		return name + path.Ext(original)
+	}
+	return name
+}
+
+// archiveFileName is the download name: the customer name, reduced to ASCII
+// for the header, plus the case id.
+func archiveFileName(head *store.CaseDetail) string {
+	name := strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_') {
+			return r
+		}
+		return '-'
+	}, head.CustomerName)
+	name = strings.Trim(name, "-")
+	if name == "" {
+		return "case-" + head.ID
+	}
+	return name + "-" + head.ID
+}
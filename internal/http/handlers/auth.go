@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rick/go-neon-api/internal/auth"
+	"github.com/rick/go-neon-api/internal/mail"
+	"github.com/rick/go-neon-api/internal/models"
+	"github.com/rick/go-neon-api/internal/store"
+)
+
+const passwordResetTTL = time.Hour
+
+func tokenResponse(u *models.User, token string, exp time.Time) gin.H {
+	return gin.H{
+		"token":     token,
+		"expiresAt": exp,
+		"user":      u,
+	}
+}
+
+// -------------------- Login / Logout --------------------
+
+type LoginReq struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+// POST /api/auth/login
+func (h *Handlers) Login(c *gin.Context) {
+	var req LoginReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	u, err := h.store.Users.ByEmail(c, strings.TrimSpace(req.Email))
+	if errors.Is(err, store.ErrNotFound) {
+		u, err = &models.User{}, nil
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "login failed"})
+		return
+	}
+	// CheckPassword burns a bcrypt comparison even when the user is missing.
+	if !auth.CheckPassword(u.Password, req.Password) || u.ID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid email or password"})
+		return
+	}
+
+	token, exp, err := auth.IssueToken(u.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "login failed"})
+		return
+	}
+	c.JSON(http.StatusOK, tokenResponse(u, token, exp))
+}
+
+// POST /api/auth/logout
+// Revokes the bearer token used for this request.
+func (h *Handlers) Logout(c *gin.Context) {
+	claims := auth.CurrentClaims(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	if err := h.store.Users.Revoke(c, claims.ID, claims.ExpiresAt.Time); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "logout failed"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GET /api/auth/me
+func (h *Handlers) Me(c *gin.Context) {
+	c.JSON(http.StatusOK, auth.CurrentUser(c))
+}
+
+// -------------------- Password management --------------------
+
+type ChangePasswordReq struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	NewPassword     string `json:"newPassword" binding:"required,min=8"`
+}
+
+// POST /api/auth/change-password
+// Verifies the current password, stores the new hash and returns a fresh token;
+// every previously issued token for the user stops working.
+func (h *Handlers) ChangePassword(c *gin.Context) {
+	var req ChangePasswordReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	u, err := h.store.Users.ByID(c, auth.CurrentUser(c).ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "change password failed"})
+		return
+	}
+	if !auth.CheckPassword(u.Password, req.CurrentPassword) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "current password is incorrect"})
+		return
+	}
+
+	hash, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.store.Users.SetPassword(c, u.ID, hash); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "change password failed"})
+		return
+	}
+
+	token, exp, err := auth.IssueToken(u.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "change password failed"})
+		return
+	}
+	c.JSON(http.StatusOK, tokenResponse(u, token, exp))
+}
+
+type RequestPasswordResetReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// POST /api/auth/password-reset
+// Always answers 202 so the endpoint cannot be used to enumerate accounts.
+func (h *Handlers) RequestPasswordReset(c *gin.Context) {
+	var req RequestPasswordResetReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	accepted := gin.H{"status": "if the account exists, a reset link has been sent"}
+
+	u, err := h.store.Users.ByEmail(c, strings.TrimSpace(req.Email))
+	if err != nil {
+		c.JSON(http.StatusAccepted, accepted)
+		return
+	}
+
+	token, digest, err := auth.NewResetToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "reset failed"})
+		return
+	}
+	if err := h.store.Users.CreateResetToken(c, u.ID, digest, time.Now().Add(passwordResetTTL)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "reset failed"})
+		return
+	}
+
+	// The token is a credential: it goes to the account's address only and
+	// never into the logs.
+	link := token
+	if base := os.Getenv("PASSWORD_RESET_URL"); base != "" {
+		link = base + "?token=" + token
+	}
+	log.Printf("password reset requested for user %s", u.ID)
+	if err := h.mailer.Send(c, mail.Message{
+		To:      u.Email,
+		Subject: "Reset your password",
+		Body:    "Use this link within an hour to choose a new password:\n\n" + link + "\n\nIf you did not ask for a reset, ignore this email.\n",
+	}); err != nil {
+		log.Printf("send password reset for user %s: %v", u.ID, err)
+	}
+
+	c.JSON(http.StatusAccepted, accepted)
+}
+
+type ConfirmPasswordResetReq struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required,min=8"`
+}
+
+// POST /api/auth/password-reset/confirm
+func (h *Handlers) ConfirmPasswordReset(c *gin.Context) {
+	var req ConfirmPasswordResetReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	hash, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, err = h.store.Users.ConsumeResetToken(c, auth.HashResetToken(strings.TrimSpace(req.Token)), hash)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "reset failed"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
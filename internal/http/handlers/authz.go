@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rick/go-neon-api/internal/auth"
+	"github.com/rick/go-neon-api/internal/store"
+)
+
+// Authenticate is auth.Required backed by this handler set's user store.
+func (h *Handlers) Authenticate() gin.HandlerFunc {
+	return auth.Required(h.store.Users)
+}
+
+// RequireOwner guards a route whose :param identifies res. Unknown ids get
+// 404; ids that exist but belong to another user's case get 403 unless the
+// caller is an ADMIN. Must run after Authenticate.
+func (h *Handlers) RequireOwner(res store.Resource, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := auth.CurrentUser(c)
+		if user == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		owner, err := h.store.Cases.OwnerOf(c, res, c.Param(param))
+		if errors.Is(err, store.ErrNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "authorization lookup failed"})
+			return
+		}
+		if owner != user.ID && !auth.IsAdmin(c) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+		c.Next()
+	}
+}
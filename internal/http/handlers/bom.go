@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rick/go-neon-api/internal/models"
+	"github.com/rick/go-neon-api/internal/store"
+)
+
+// Bill of materials: the suggested fixtures of every room, summed per fixture
+// type, plus the rooms' mounting kits and motion sensors, each priced from
+// the catalog. Items without a price still appear, with null totals, and are
+// counted in totals.unpricedLines.
+
+type bomRoom struct {
+	RoomID   string `json:"roomId"`
+	Location string `json:"location"`
+	Quantity int    `json:"quantity"`
+}
+
+type bomLine struct {
+	Kind          string  `json:"kind"`   // "fixture", or the accessory kind
+	ItemID        string  `json:"itemId"` // LightFixtureType or Accessory id
+	Name          string  `json:"name"`
+	SKU           *string `json:"sku"`
+	Quantity      int     `json:"quantity"`
+	store.Pricing         // per unit
+
+	TotalCost       *float64  `json:"totalCost"`
+	TotalPrice      *float64  `json:"totalPrice"`
+	TotalLaborHours *float64  `json:"totalLaborHours"`
+	Rooms           []bomRoom `json:"rooms"`
+}
+
+type bomTotals struct {
+	Cost          float64  `json:"cost"`
+	Price         float64  `json:"price"`
+	LaborHours    float64  `json:"laborHours"`
+	Margin        *float64 `json:"margin"` // price - cost; null while lines are unpriced
+	UnpricedLines int      `json:"unpricedLines"`
+}
+
+// bomReport is the bill of materials response, and the snapshot a quote
+// keeps.
+type bomReport struct {
+	CaseID   string    `json:"caseId"`
+	Lines    []bomLine `json:"lines"`
+	Totals   bomTotals `json:"totals"`
+	Warnings []string  `json:"warnings"`
+}
+
+// GET /api/cases/:id/bom
+func (h *Handlers) GetCaseBOM(c *gin.Context) {
+	id := c.Param("id")
+	rooms, ok := h.caseRooms(c, id)
+	if !ok {
+		return
+	}
+	if r, ok := h.caseBOM(c, id, rooms); ok {
+		c.JSON(http.StatusOK, r)
+	}
+}
+
+// caseRooms loads the rooms of the case's visit; none if it has no visit.
+// On failure it writes the error response and returns false.
+func (h *Handlers) caseRooms(c *gin.Context, id string) ([]store.Room, bool) {
+	visit, err := h.store.OnSite.Visit(c, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, true
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load visit"})
+		return nil, false
+	}
+	rooms, err := h.store.OnSite.Rooms(c, visit.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load rooms"})
+		return nil, false
+	}
+	return rooms, true
+}
+
+// caseBOM prices the rooms' fixtures and accessories from the current
+// catalog. On failure it writes the error response and returns false.
+func (h *Handlers) caseBOM(c *gin.Context, id string, rooms []store.Room) (*bomReport, bool) {
+	accessories, err := h.store.Catalog.Accessories(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load accessories"})
+		return nil, false
+	}
+
+	warnings := []string{}
+	fixtures := map[string]*bomLine{}
+	byKind := map[string]*bomLine{}
+	for _, a := range accessories {
+		byKind[a.Kind] = &bomLine{Kind: a.Kind, ItemID: a.ID, Name: a.Name, SKU: a.SKU, Pricing: a.Pricing}
+	}
+	missing := map[string]bool{} // kinds already warned about
+	addAccessory := func(kind string, r store.Room, qty int) {
+		if qty <= 0 {
+			return
+		}
+		l, ok := byKind[kind]
+		if !ok {
+			if !missing[kind] {
+				missing[kind] = true
+				warnings = append(warnings, fmt.Sprintf("no %s accessory in the catalog", kind))
+			}
+			return
+		}
+		l.Quantity += qty
+		l.Rooms = append(l.Rooms, bomRoom{RoomID: r.ID, Location: r.Location, Quantity: qty})
+	}
+
+	for _, r := range rooms {
+		for _, s := range r.Suggested {
+			if s.Quantity <= 0 {
+				continue
+			}
+			l, ok := fixtures[s.ProductID]
+			if !ok {
+				l = &bomLine{Kind: "fixture", ItemID: s.ProductID, Name: s.TypeName, SKU: s.SKU, Pricing: s.Pricing}
+				fixtures[s.ProductID] = l
+			}
+			l.Quantity += s.Quantity
+			l.Rooms = append(l.Rooms, bomRoom{RoomID: r.ID, Location: r.Location, Quantity: s.Quantity})
+		}
+
+		// mountingKitQty is free text in the schema; only whole numbers count.
+		if v := strings.TrimSpace(r.MountingKitQty); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				warnings = append(warnings, fmt.Sprintf("room %q: mountingKitQty %q is not a whole number; not counted", r.Location, v))
+			} else {
+				addAccessory(models.AccessoryMountingKit, r, n)
+			}
+		}
+		addAccessory(models.AccessoryMotionSensor, r, r.MotionSensorQty)
+	}
+
+	lines := make([]bomLine, 0, len(fixtures)+len(accessories))
+	for _, l := range fixtures {
+		lines = append(lines, *l)
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		if lines[i].Name != lines[j].Name {
+			return lines[i].Name < lines[j].Name
+		}
+		return lines[i].ItemID < lines[j].ItemID
+	})
+	for _, a := range accessories {
+		if l := byKind[a.Kind]; l.Quantity > 0 {
+			lines = append(lines, *l)
+		}
+	}
+
+	var totals bomTotals
+	for i := range lines {
+		l := &lines[i]
+		l.TotalCost = extend(l.UnitCost, l.Quantity)
+		l.TotalPrice = extend(l.ListPrice, l.Quantity)
+		l.TotalLaborHours = extend(l.LaborHours, l.Quantity)
+		if l.TotalCost == nil || l.TotalPrice == nil {
+			totals.UnpricedLines++
+		}
+		totals.Cost += orZero(l.TotalCost)
+		totals.Price += orZero(l.TotalPrice)
+		totals.LaborHours += orZero(l.TotalLaborHours)
+	}
+	totals.Cost, totals.Price, totals.LaborHours = round2(totals.Cost), round2(totals.Price), round2(totals.LaborHours)
+	if totals.UnpricedLines == 0 {
+		m := round2(totals.Price - totals.Cost)
+		totals.Margin = &m
+	}
+
+	return &bomReport{CaseID: id, Lines: lines, Totals: totals, Warnings: warnings}, true
+}
+
+// extend is unit × qty rounded to cents, or nil if the unit value is unknown.
+func extend(unit *float64, qty int) *float64 {
+	if unit == nil {
+		return nil
+	}
+	v := round2(*unit * float64(qty))
+	return &v
+}
+
+func orZero(v *float64) float64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
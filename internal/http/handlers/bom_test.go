@@ -0,0 +1,131 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/rick/go-neon-api/internal/models"
+)
+
+type bomLine struct {
+	Kind            string   `json:"kind"`
+	ItemID          string   `json:"itemId"`
+	Quantity        int      `json:"quantity"`
+	TotalCost       *float64 `json:"totalCost"`
+	TotalPrice      *float64 `json:"totalPrice"`
+	TotalLaborHours *float64 `json:"totalLaborHours"`
+	Rooms           []struct {
+		Location string `json:"location"`
+		Quantity int    `json:"quantity"`
+	} `json:"rooms"`
+}
+
+type bom struct {
+	Lines  []bomLine `json:"lines"`
+	Totals struct {
+		Cost          float64  `json:"cost"`
+		Price         float64  `json:"price"`
+		LaborHours    float64  `json:"laborHours"`
+		Margin        *float64 `json:"margin"`
+		UnpricedLines int      `json:"unpricedLines"`
+	} `json:"totals"`
+	Warnings []string `json:"warnings"`
+}
+
+func TestCaseBOM(t *testing.T) {
+	a := newAPI(t)
+	token := a.login("admin@example.com")
+	id := a.createCase(token, map[string]any{"customerName": "Lincoln"})
+
+	// Prices whose float products land just off the cent, to check rounding:
+	// 12.1 × 10 = 121.00000000000001 and 19.99 × 10 = 199.89999999999998.
+	panel := a.db.PutFixtureType(models.LightFixtureType{Name: "Panel", UnitCost: ptr(12.1), ListPrice: ptr(19.99), LaborHours: ptr(0.35)})
+	troffer := a.db.PutFixtureType(models.LightFixtureType{Name: "Troffer", UnitCost: ptr(40.0), LaborHours: ptr(1.0)})
+	kit := map[string]any{"name": "Mounting kit", "unitCost": 5.5, "listPrice": 9.95, "laborHours": 0.25}
+	a.want(http.StatusOK, a.do(token, http.MethodPut, "/api/accessories/mountingKit", kit), nil)
+	a.db.DeleteAccessory(models.AccessoryMotionSensor)
+
+	gym := a.room(token, id, map[string]any{"location": "Gym", "mountingKitQty": " 3 ", "motionSensorQty": 1})
+	hall := a.room(token, id, map[string]any{"location": "Hall", "mountingKitQty": "two", "motionSensorQty": 2})
+	a.room(token, id, map[string]any{"location": "Office", "mountingKitQty": "-1"})
+	a.suggest(token, gym, panel.ID, 4)
+	a.suggest(token, gym, troffer.ID, 2)
+	a.suggest(token, hall, panel.ID, 6)
+
+	var got bom
+	a.want(http.StatusOK, a.do(token, http.MethodGet, "/api/cases/"+id+"/bom", nil), &got)
+	if len(got.Lines) != 3 {
+		t.Fatalf("lines = %+v, want panel, troffer and mounting kit", got.Lines)
+	}
+	for _, tc := range []struct {
+		line                bomLine
+		kind, item          string
+		qty                 int
+		cost, price, labour *float64
+		rooms               string
+	}{
+		{got.Lines[0], "fixture", panel.ID, 10, ptr(121.0), ptr(199.9), ptr(3.5), "Hall 6, Gym 4"},
+		{got.Lines[1], "fixture", troffer.ID, 2, ptr(80.0), nil, ptr(2.0), "Gym 2"},
+		{got.Lines[2], models.AccessoryMountingKit, "accessory-mounting-kit", 3, ptr(16.5), ptr(29.85), ptr(0.75), "Gym 3"},
+	} {
+		l := tc.line
+		rooms := ""
+		for i, r := range l.Rooms {
+			if i > 0 {
+				rooms += ", "
+			}
+			rooms += fmt.Sprintf("%s %d", r.Location, r.Quantity)
+		}
+		if l.Kind != tc.kind || l.ItemID != tc.item || l.Quantity != tc.qty || rooms != tc.rooms ||
+			!reflect.DeepEqual(l.TotalCost, tc.cost) || !reflect.DeepEqual(l.TotalPrice, tc.price) ||
+			!reflect.DeepEqual(l.TotalLaborHours, tc.labour) {
+			t.Errorf("line %s %s: quantity %d in %q, totals %v %v %v", l.Kind, l.ItemID, l.Quantity, rooms,
+				deref(l.TotalCost), deref(l.TotalPrice), deref(l.TotalLaborHours))
+		}
+	}
+
+	totals := got.Totals
+	if totals.Cost != 217.5 || totals.Price != 229.75 || totals.LaborHours != 6.25 ||
+		totals.UnpricedLines != 1 || totals.Margin != nil {
+		t.Errorf("totals = %+v, want 217.5, 229.75, 6.25 and no margin with 1 unpriced line", totals)
+	}
+	// Rooms come newest first. Hall and Gym both want motion sensors; the
+	// missing accessory is reported once.
+	wantWarnings := []string{
+		`room "Office": mountingKitQty "-1" is not a whole number; not counted`,
+		`room "Hall": mountingKitQty "two" is not a whole number; not counted`,
+		"no motionSensor accessory in the catalog",
+	}
+	if !reflect.DeepEqual(got.Warnings, wantWarnings) {
+		t.Errorf("warnings = %q, want %q", got.Warnings, wantWarnings)
+	}
+
+	// Pricing the troffer gives the margin.
+	troffer.ListPrice = ptr(65.5)
+	a.db.PutFixtureType(*troffer)
+	a.want(http.StatusOK, a.do(token, http.MethodGet, "/api/cases/"+id+"/bom", nil), &got)
+	if got.Totals.Price != 360.75 || got.Totals.UnpricedLines != 0 || got.Totals.Margin == nil || *got.Totals.Margin != 143.25 {
+		t.Errorf("priced totals = %+v, want price 360.75 and margin 143.25", got.Totals)
+	}
+}
+
+func TestCaseBOMWithoutVisit(t *testing.T) {
+	a := newAPI(t)
+	token := a.login("admin@example.com")
+	id := a.createCase(token, map[string]any{"customerName": "Lincoln"})
+
+	var got bom
+	a.want(http.StatusOK, a.do(token, http.MethodGet, "/api/cases/"+id+"/bom", nil), &got)
+	if len(got.Lines) != 0 || got.Totals.Margin == nil || *got.Totals.Margin != 0 || len(got.Warnings) != 0 {
+		t.Errorf("bom = %+v, want empty with a zero margin", got)
+	}
+}
+
+func deref(v *float64) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
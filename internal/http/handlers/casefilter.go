@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rick/go-neon-api/internal/casestatus"
+	"github.com/rick/go-neon-api/internal/store"
+)
+
+// parseCaseFilter understands:
+//
+//	status=Quoted,Won          any of the listed statuses
+//	userId=...                 owner
+//	schoolName=lincoln         substring, case-insensitive
+//	installationService=Yes    exact, case-insensitive
+//	createdFrom / createdTo    RFC 3339 or YYYY-MM-DD (To is inclusive)
+//	updatedFrom / updatedTo
+//	q=term                     free text over customer, school, contact, email and
+//	                           address; all words must match
+//	sort=schoolName&order=asc  any key of store.CaseSorts; default createdAt desc
+func parseCaseFilter(c *gin.Context) (store.CaseFilter, error) {
+	var f store.CaseFilter
+
+	if raw := strings.TrimSpace(c.Query("status")); raw != "" {
+		statuses := strings.Split(raw, ",")
+		for i := range statuses {
+			statuses[i] = strings.TrimSpace(statuses[i])
+			if !casestatus.Valid(statuses[i]) {
+				return f, fmt.Errorf("unknown status: %s", statuses[i])
+			}
+		}
+		f.Statuses = statuses
+	}
+	f.OwnerID = strings.TrimSpace(c.Query("userId"))
+	f.SchoolName = strings.TrimSpace(c.Query("schoolName"))
+	f.InstallationService = strings.TrimSpace(c.Query("installationService"))
+
+	for _, r := range []struct {
+		param string
+		upper bool
+		dst   **time.Time
+	}{
+		{"createdFrom", false, &f.CreatedFrom},
+		{"createdTo", true, &f.CreatedBefore},
+		{"updatedFrom", false, &f.UpdatedFrom},
+		{"updatedTo", true, &f.UpdatedBefore},
+	} {
+		v := strings.TrimSpace(c.Query(r.param))
+		if v == "" {
+			continue
+		}
+		t, err := parseDateParam(v, r.upper)
+		if err != nil {
+			return f, fmt.Errorf("%s: %w", r.param, err)
+		}
+		*r.dst = &t
+	}
+
+	f.Words = strings.Fields(c.Query("q"))
+
+	f.Sort = c.DefaultQuery("sort", "createdAt")
+	if _, ok := store.CaseSorts[f.Sort]; !ok {
+		return f, fmt.Errorf("cannot sort by %q", f.Sort)
+	}
+	switch strings.ToLower(c.Query("order")) {
+	case "", "desc":
+		f.Desc = true
+	case "asc":
+	default:
+		return f, fmt.Errorf("order must be asc or desc")
+	}
+
+	return f, nil
+}
+
+// parseDateParam accepts RFC 3339 timestamps or bare dates. A bare date used
+// as an upper bound means "through the end of that day".
+func parseDateParam(v string, upper bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected YYYY-MM-DD or RFC 3339 timestamp")
+	}
+	if upper {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
@@ -1,88 +1,72 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
-	"strconv"
+	"net/mail"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/rick/go-neon-api/internal/db"
+	"github.com/rick/go-neon-api/internal/auth"
+	"github.com/rick/go-neon-api/internal/casestatus"
+	"github.com/rick/go-neon-api/internal/pagination"
+	"github.com/rick/go-neon-api/internal/store"
 )
 
 // ---- Cases ----
 
 type CreateCaseReq struct {
-	UserID               string `json:"userId" binding:"required"`
+	UserID               string `json:"userId"` // ADMIN only; defaults to the caller
 	CustomerName         string `json:"customerName" binding:"required"`
 	ProjectDetails       string `json:"projectDetails"`
 	SchoolName           string `json:"schoolName"`
 	ContactPerson        string `json:"contactPerson"`
-	EmailAddress         string `json:"emailAddress"`
+	EmailAddress         string `json:"emailAddress" binding:"omitempty,email"`
 	PhoneNumber          string `json:"phoneNumber"`
 	SchoolAddress        string `json:"schoolAddress"`
 	LightingPurpose      string `json:"lightingPurpose"`
 	FacilitiesUsedIn     string `json:"facilitiesUsedIn"`
 	InstallationService  string `json:"installationService"`
-	OperationDaysPerYear int    `json:"operationDaysPerYear"`
-	OperationHoursPerDay int    `json:"operationHoursPerDay"`
+	OperationDaysPerYear int    `json:"operationDaysPerYear" binding:"gte=0,lte=366"`
+	OperationHoursPerDay int    `json:"operationHoursPerDay" binding:"gte=0,lte=24"`
 }
 
+// GET /api/cases
+// Keyset-paginated; see parseCaseFilter for filters and pagination.Parse for
+// limit/cursor/total.
 func (h *Handlers) ListCases(c *gin.Context) {
-	userID := strings.TrimSpace(c.GetHeader("X-User-Id"))
-	userRole := strings.ToUpper(strings.TrimSpace(c.GetHeader("X-User-Role")))
-	if userID == "" || (userRole != "ADMIN" && userRole != "USER") {
+	user := auth.CurrentUser(c)
+	if user == nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
-	isAdmin := userRole == "ADMIN"
-
-	// Optional: support ?page=&limit=
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	if page < 1 {
-		page = 1
-	}
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
-	if limit < 1 || limit > 500 {
-		limit = 100
-	}
-	offset := (page - 1) * limit
-
-	type Row struct {
-		ID             string    `json:"id"              gorm:"column:id"`
-		CustomerName   string    `json:"customerName"    gorm:"column:customerName"`
-		ProjectDetails string    `json:"projectDetails"  gorm:"column:projectDetails"`
-		ContactPerson  string    `json:"contactPerson"   gorm:"column:contactPerson"`
-		SchoolName     string    `json:"schoolName"      gorm:"column:schoolName"`
-		EmailAddress   string    `json:"emailAddress"    gorm:"column:emailAddress"`
-		Status         string    `json:"status"          gorm:"column:status"`
-		CreatedAt      time.Time `json:"createdAt"       gorm:"column:createdAt"`
-		UpdatedAt      time.Time `json:"updatedAt"       gorm:"column:updatedAt"`
-		UserName       *string   `json:"-"               gorm:"column:user_name"`
-		UserEmail      string    `json:"-"               gorm:"column:user_email"`
-	}
-
-	var rows []Row
-	baseSQL := `
-		SELECT
-			c."id", c."customerName", c."projectDetails", c."contactPerson", c."schoolName",
-			c."emailAddress", c."status", c."createdAt", c."updatedAt",
-			u."name" AS user_name, u."email" AS user_email
-		FROM "Case" c
-		JOIN "User" u ON u."id" = c."userId"
-	`
-	where := ``
-	args := []any{}
-
-	if !isAdmin {
-		where = `WHERE c."userId" = ?`
-		args = append(args, userID)
-	}
-
-	orderLimit := ` ORDER BY c."createdAt" DESC LIMIT ? OFFSET ?`
-	args = append(args, limit, offset)
-
-	if err := db.DB.Raw(baseSQL+where+orderLimit, args...).Scan(&rows).Error; err != nil {
+	p, err := pagination.Parse(c, 100, 500)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	f, err := parseCaseFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !auth.IsAdmin(c) {
+		if f.OwnerID != "" && f.OwnerID != user.ID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "cannot list another user's cases"})
+			return
+		}
+		f.OwnerID = user.ID
+	}
+
+	page, err := h.store.Cases.List(c, f, p)
+	if errors.Is(err, pagination.ErrBadCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching cases"})
 		return
 	}
@@ -105,8 +89,8 @@ func (h *Handlers) ListCases(c *gin.Context) {
 		User           UserLite  `json:"user"`
 	}
 
-	out := make([]Item, 0, len(rows))
-	for _, r := range rows {
+	out := make([]Item, 0, len(page.Items))
+	for _, r := range page.Items {
 		out = append(out, Item{
 			ID:             r.ID,
 			CustomerName:   r.CustomerName,
@@ -124,88 +108,28 @@ func (h *Handlers) ListCases(c *gin.Context) {
 		})
 	}
 
-	c.JSON(http.StatusOK, out)
+	c.JSON(http.StatusOK, pagination.Page[Item]{
+		Items:      out,
+		NextCursor: page.NextCursor,
+		HasMore:    page.HasMore,
+		Total:      page.Total,
+	})
 }
 
 func (h *Handlers) GetCase(c *gin.Context) {
 	id := c.Param("id")
 
-	// --- 1) Load the case + user (Prisma table/column names) ---
-	var head struct {
-		ID             string    `json:"id"              gorm:"column:id"`
-		CustomerName   string    `json:"customerName"    gorm:"column:customerName"`
-		ProjectDetails string    `json:"projectDetails"  gorm:"column:projectDetails"`
-		ContactPerson  string    `json:"contactPerson"   gorm:"column:contactPerson"`
-		SchoolName     string    `json:"schoolName"      gorm:"column:schoolName"`
-		EmailAddress   string    `json:"emailAddress"    gorm:"column:emailAddress"`
-		PhoneNumber    string    `json:"phoneNumber"     gorm:"column:phoneNumber"`
-		SchoolAddress  string    `json:"schoolAddress"   gorm:"column:schoolAddress"`
-		Status         string    `json:"status"          gorm:"column:status"`
-		CreatedAt      time.Time `json:"createdAt"       gorm:"column:createdAt"`
-		UpdatedAt      time.Time `json:"updatedAt"       gorm:"column:updatedAt"`
-		UserName       *string   `json:"-"               gorm:"column:user_name"`
-		UserEmail      string    `json:"-"               gorm:"column:user_email"`
-	}
-
-	sqlCase := `
-		SELECT
-			c."id", c."customerName", c."projectDetails", c."contactPerson",
-			c."schoolName", c."emailAddress", c."phoneNumber", c."schoolAddress",
-			c."status", c."createdAt", c."updatedAt",
-			u."name" AS user_name, u."email" AS user_email
-		FROM "Case" c
-		JOIN "User" u ON u."id" = c."userId"
-		WHERE c."id" = ?
-		LIMIT 1
-	`
-	if err := db.DB.Raw(sqlCase, id).Scan(&head).Error; err != nil || head.ID == "" {
+	head, err := h.store.Cases.Get(c, id)
+	if errors.Is(err, store.ErrNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
-
-	// --- 2) Load documents for the case ---
-	type DocRow struct {
-		ID              string    `json:"id"            gorm:"column:id"`
-		URL             string    `json:"url"           gorm:"column:url"`
-		FileName        string    `json:"fileName"      gorm:"column:fileName"`
-		CustomName      *string   `json:"customName"    gorm:"column:customName"`
-		UploadedViaLink bool      `json:"uploadedViaLink" gorm:"column:uploadedViaLink"`
-		CreatedAt       time.Time `json:"createdAt"     gorm:"column:createdAt"`
-	}
-	var docs []DocRow
-	sqlDocs := `
-		SELECT "id","url","fileName","customName","uploadedViaLink","createdAt"
-		FROM "Document"
-		WHERE "caseId" = ?
-		ORDER BY "createdAt" DESC
-	`
-	if err := db.DB.Raw(sqlDocs, id).Scan(&docs).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load documents"})
-		return
-	}
-
-	// --- 3) Load photos for the case ---
-	type PhotoRow struct {
-		ID              string    `json:"id"            gorm:"column:id"`
-		URL             string    `json:"url"           gorm:"column:url"`
-		Comment         *string   `json:"comment"       gorm:"column:comment"`
-		CustomName      *string   `json:"customName"    gorm:"column:customName"`
-		UploadedViaLink bool      `json:"uploadedViaLink" gorm:"column:uploadedViaLink"`
-		CreatedAt       time.Time `json:"createdAt"     gorm:"column:createdAt"`
-	}
-	var photos []PhotoRow
-	sqlPhotos := `
-		SELECT "id","url","comment","customName","uploadedViaLink","createdAt"
-		FROM "Photo"
-		WHERE "caseId" = ?
-		ORDER BY "createdAt" DESC
-	`
-	if err := db.DB.Raw(sqlPhotos, id).Scan(&photos).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load photos"})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load case"})
 		return
 	}
 
-	// --- 4) Assemble response (similar to your Next.js select) ---
+	// Assemble response (similar to your Next.js select)
 	type UserLite struct {
 		Name  *string `json:"name"`
 		Email string  `json:"email"`
@@ -226,9 +150,190 @@ func (h *Handlers) GetCase(c *gin.Context) {
 			Name:  head.UserName,
 			Email: head.UserEmail,
 		},
-		"documents": docs,
-		"photos":    photos,
+		"documents": head.Documents,
+		"photos":    head.Photos,
 	}
+	h.signDocuments(c, head.Documents)
+	h.signPhotos(c, head.Photos)
 
 	c.JSON(http.StatusOK, resp)
 }
+
+// POST /api/cases
+// USERs always create cases for themselves; ADMINs may assign another owner.
+func (h *Handlers) CreateCase(c *gin.Context) {
+	user := auth.CurrentUser(c)
+	var req CreateCaseReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ownerID := user.ID
+	if req.UserID != "" && req.UserID != user.ID {
+		if !auth.IsAdmin(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "cannot create cases for another user"})
+			return
+		}
+		if _, err := h.store.Users.ByID(c, req.UserID); errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "userId does not exist"})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "create failed"})
+			return
+		}
+		ownerID = req.UserID
+	}
+
+	row, err := h.store.Cases.Create(c, user.ID, store.CaseInput{
+		UserID:               ownerID,
+		Status:               casestatus.New,
+		CustomerName:         strings.TrimSpace(req.CustomerName),
+		ProjectDetails:       req.ProjectDetails,
+		SchoolName:           req.SchoolName,
+		ContactPerson:        req.ContactPerson,
+		EmailAddress:         req.EmailAddress,
+		PhoneNumber:          req.PhoneNumber,
+		SchoolAddress:        req.SchoolAddress,
+		LightingPurpose:      req.LightingPurpose,
+		FacilitiesUsedIn:     req.FacilitiesUsedIn,
+		InstallationService:  req.InstallationService,
+		OperationDaysPerYear: req.OperationDaysPerYear,
+		OperationHoursPerDay: req.OperationHoursPerDay,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create failed"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, row)
+}
+
+// caseStringFields / caseIntFields are the "Case" columns PUT may change.
+var caseStringFields = map[string]bool{
+	"customerName":        true,
+	"projectDetails":      true,
+	"schoolName":          true,
+	"contactPerson":       true,
+	"emailAddress":        true,
+	"phoneNumber":         true,
+	"schoolAddress":       true,
+	"lightingPurpose":     true,
+	"facilitiesUsedIn":    true,
+	"installationService": true,
+}
+
+var caseIntFields = map[string]int{ // column → max value
+	"operationDaysPerYear": 366,
+	"operationHoursPerDay": 24,
+	"num2FtLinearHighBay":  1 << 20,
+	"num150WUFOHighBay":    1 << 20,
+	"num240WUFOHighBay":    1 << 20,
+	"num2x2LEDPanel":       1 << 20,
+	"num2x4LEDPanel":       1 << 20,
+	"num1x4LEDPanel":       1 << 20,
+	"num4FtStripLight":     1 << 20,
+}
+
+// PUT /api/cases/:id
+// Partial update; unknown keys are ignored. ADMINs may reassign "userId".
+// Status changes go through the dedicated transition endpoint.
+func (h *Handlers) UpdateCase(c *gin.Context) {
+	caseID := c.Param("id")
+	user := auth.CurrentUser(c)
+
+	var patch map[string]any
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	set := map[string]any{}
+	for k, v := range patch {
+		switch {
+		case caseStringFields[k]:
+			str, ok := v.(string)
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": k + " must be a string"})
+				return
+			}
+			if k == "customerName" && strings.TrimSpace(str) == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "customerName cannot be empty"})
+				return
+			}
+			if k == "emailAddress" && str != "" {
+				if _, err := mail.ParseAddress(str); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "emailAddress is invalid"})
+					return
+				}
+			}
+			set[k] = str
+		case caseIntFields[k] > 0:
+			n, ok := v.(float64)
+			if !ok || n != float64(int(n)) || n < 0 || int(n) > caseIntFields[k] {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s must be an integer between 0 and %d", k, caseIntFields[k])})
+				return
+			}
+			set[k] = int(n)
+		case k == "userId":
+			if !auth.IsAdmin(c) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "only admins can reassign cases"})
+				return
+			}
+			str, ok := v.(string)
+			if !ok || str == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "userId must be a string"})
+				return
+			}
+			if _, err := h.store.Users.ByID(c, str); errors.Is(err, store.ErrNotFound) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "userId does not exist"})
+				return
+			} else if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+				return
+			}
+			set[k] = str
+		}
+	}
+	if len(set) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no valid fields"})
+		return
+	}
+	if err := h.store.Cases.Update(c, user.ID, caseID, set); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// DELETE /api/cases/:id
+// The case's ActivityLog rows go with it; the deletion is logged in a row
+// without a case. Stored photos and documents are removed after the rows.
+func (h *Handlers) DeleteCase(c *gin.Context) {
+	caseID := c.Param("id")
+	user := auth.CurrentUser(c)
+
+	files, err := h.store.Cases.Delete(c, user.ID, caseID)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	case errors.Is(err, store.ErrConflict):
+		log.Printf("delete case %s: %v", caseID, err)
+		c.JSON(http.StatusConflict, gin.H{"error": "case has dependent records that cannot be deleted"})
+		return
+	case err != nil:
+		log.Printf("delete case %s: %v", caseID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+
+	for _, url := range files.Photos {
+		h.discardPhoto(c, url)
+	}
+	for _, url := range files.Documents {
+		h.discardObject(c, url)
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,534 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rick/go-neon-api/internal/sheet"
+	"github.com/rick/go-neon-api/internal/store"
+)
+
+// Catalog import and export (ADMIN). A sheet is a header row naming some of
+// the catalog's columns, in any order and case, then one row per item.
+// Export writes every column, so an export can be edited and imported back.
+//
+// Each row updates the item with the same SKU, else the one with the same
+// name, else creates one. Columns the sheet lacks keep their stored values;
+// an empty cell clears the field. Import is a dry run returning the diff
+// unless ?commit=true, and a commit writes all rows or, if any row has an
+// error, none.
+
+var (
+	productSheetColumns     = []string{"name", "sku", "wattage", "category", "description"}
+	fixtureTypeSheetColumns = []string{"name", "sku", "wattage", "description", "imageUrl", "unitCost", "listPrice", "laborHours"}
+)
+
+type importRow struct {
+	Line  int               // 1-based sheet row; the header is line 1
+	Cells map[string]string // trimmed, for the sheet's columns only
+}
+
+type importResult struct {
+	Committed bool           `json:"committed"`
+	Created   int            `json:"created"`
+	Updated   int            `json:"updated"`
+	Unchanged int            `json:"unchanged"`
+	Changes   []importChange `json:"changes"`
+	Errors    []importError  `json:"errors"`
+}
+
+type importChange struct {
+	Line     int                    `json:"line"`
+	Action   string                 `json:"action"` // "create" or "update"
+	ID       string                 `json:"id,omitempty"`
+	Name     string                 `json:"name"`
+	Archived bool                   `json:"archived,omitempty"` // stays archived; restore it separately
+	Fields   map[string]importField `json:"fields"`
+}
+
+type importField struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+type importError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// catalogSheet adapts import and export to one catalog's row type.
+type catalogSheet[T any] struct {
+	columns []string
+	key     func(*T) (id, name string, sku *string, archived bool)
+	set     func(row *T, column, cell string) error
+	values  func(*T) []any // in columns order
+	// required lists columns a new item must have a cell for.
+	required []string
+}
+
+var productSheet = catalogSheet[store.ProductRow]{
+	columns: productSheetColumns,
+	key: func(p *store.ProductRow) (string, string, *string, bool) {
+		return p.ID, p.Name, p.SKU, p.ArchivedAt != nil
+	},
+	set: func(p *store.ProductRow, column, cell string) error {
+		switch column {
+		case "name":
+			p.Name = cell
+		case "sku":
+			p.SKU = optionalText(&cell)
+		case "wattage":
+			if cell == "" {
+				return errors.New("wattage is required")
+			}
+			w, err := parseWattage(cell)
+			if err != nil {
+				return err
+			}
+			p.Wattage = w
+		case "category":
+			p.Category = optionalText(&cell)
+		case "description":
+			p.Description = optionalText(&cell)
+		}
+		return nil
+	},
+	values: func(p *store.ProductRow) []any {
+		return []any{p.Name, p.SKU, p.Wattage, p.Category, p.Description}
+	},
+	required: []string{"wattage"},
+}
+
+var fixtureTypeSheet = catalogSheet[store.FixtureTypeRow]{
+	columns: fixtureTypeSheetColumns,
+	key: func(t *store.FixtureTypeRow) (string, string, *string, bool) {
+		return t.ID, t.Name, t.SKU, t.ArchivedAt != nil
+	},
+	set: func(t *store.FixtureTypeRow, column, cell string) error {
+		switch column {
+		case "name":
+			t.Name = cell
+		case "sku":
+			t.SKU = optionalText(&cell)
+		case "wattage":
+			t.Wattage = nil
+			if cell != "" {
+				w, err := parseWattage(cell)
+				if err != nil {
+					return err
+				}
+				t.Wattage = &w
+			}
+		case "description":
+			t.Description = optionalText(&cell)
+		case "imageUrl":
+			if cell != "" && !isExternalURL(cell) {
+				return errors.New("imageUrl must be an absolute http(s) URL")
+			}
+			t.ImageURL = optionalText(&cell)
+		case "unitCost":
+			return parseAmount(column, cell, &t.UnitCost)
+		case "listPrice":
+			return parseAmount(column, cell, &t.ListPrice)
+		case "laborHours":
+			return parseAmount(column, cell, &t.LaborHours)
+		}
+		return nil
+	},
+	values: func(t *store.FixtureTypeRow) []any {
+		return []any{t.Name, t.SKU, t.Wattage, t.Description, t.ImageURL, t.UnitCost, t.ListPrice, t.LaborHours}
+	},
+}
+
+// parseWattage accepts a non-negative number, optionally suffixed "W".
+func parseWattage(cell string) (float64, error) {
+	v := strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(cell, "W"), "w"))
+	w, err := strconv.ParseFloat(v, 64)
+	if err != nil || w < 0 || math.IsInf(w, 0) || math.IsNaN(w) {
+		return 0, fmt.Errorf("wattage %q is not a non-negative number", cell)
+	}
+	return w, nil
+}
+
+// parseAmount sets *dst from a non-negative price or hours cell, nil when
+// empty. Currency signs and thousands separators are ignored: "$1,250.00".
+func parseAmount(column, cell string, dst **float64) error {
+	*dst = nil
+	if cell == "" {
+		return nil
+	}
+	v := strings.ReplaceAll(strings.TrimPrefix(cell, "$"), ",", "")
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+		return fmt.Errorf("%s %q is not a non-negative number", column, cell)
+	}
+	*dst = &f
+	return nil
+}
+
+// diffValue turns a column value into what the diff shows: nil pointers are
+// null, others their value.
+func diffValue(v any) any {
+	switch v := v.(type) {
+	case *string:
+		if v == nil {
+			return nil
+		}
+		return *v
+	case *float64:
+		if v == nil {
+			return nil
+		}
+		return *v
+	}
+	return v
+}
+
+// plan matches rows to existing items and returns the diff with the rows to
+// create and update. Rows with errors are reported and left out; the caller
+// only commits when there are none.
+func (s catalogSheet[T]) plan(existing []T, rows []importRow) (importResult, []T, []T) {
+	res := importResult{Changes: []importChange{}, Errors: []importError{}}
+	byName := map[string]int{}
+	bySKU := map[string][]int{}
+	for i := range existing {
+		_, name, sku, _ := s.key(&existing[i])
+		byName[name] = i
+		if sku != nil {
+			bySKU[*sku] = append(bySKU[*sku], i)
+		}
+	}
+	matched := map[int]int{}  // existing index → line
+	names := map[string]int{} // resulting name → line
+	skus := map[string]int{}  // resulting SKU → line
+
+	var creates, updates []T
+rows:
+	for _, r := range rows {
+		fail := func(format string, args ...any) {
+			res.Errors = append(res.Errors, importError{Line: r.Line, Error: fmt.Sprintf(format, args...)})
+		}
+		name, sku := r.Cells["name"], r.Cells["sku"]
+		if name == "" {
+			fail("name is required")
+			continue
+		}
+		if len(name) > maxCatalogName {
+			fail("name must be 1-%d characters", maxCatalogName)
+			continue
+		}
+
+		idx := -1
+		if sku != "" {
+			switch m := bySKU[sku]; len(m) {
+			case 0:
+			case 1:
+				idx = m[0]
+			default:
+				fail("SKU %q matches %d items", sku, len(m))
+				continue
+			}
+		}
+		if i, ok := byName[name]; idx < 0 && ok {
+			idx = i
+		}
+		if line, ok := matched[idx]; idx >= 0 && ok {
+			fail("updates the same item as line %d", line)
+			continue
+		}
+
+		var row T
+		if idx >= 0 {
+			row = existing[idx]
+		} else {
+			for _, col := range s.required {
+				if _, ok := r.Cells[col]; !ok {
+					fail("%s is required for a new item", col)
+					continue rows
+				}
+			}
+		}
+		for _, col := range s.columns {
+			if cell, ok := r.Cells[col]; ok {
+				if err := s.set(&row, col, cell); err != nil {
+					fail("%v", err)
+					continue rows
+				}
+			}
+		}
+
+		// Existing items outside the sheet cannot clash: a row naming their
+		// SKU matches them. Two rows of the sheet still can.
+		id, name, rowSKU, archived := s.key(&row)
+		if i, ok := byName[name]; ok && i != idx {
+			fail("name %q is used by another item", name)
+			continue
+		}
+		if line, ok := names[name]; ok {
+			fail("name %q repeats line %d", name, line)
+			continue
+		}
+		if rowSKU != nil {
+			if line, ok := skus[*rowSKU]; ok {
+				fail("SKU %q repeats line %d", *rowSKU, line)
+				continue
+			}
+			skus[*rowSKU] = r.Line
+		}
+		names[name] = r.Line
+
+		after := s.values(&row)
+		if idx < 0 {
+			fields := map[string]importField{}
+			for i, col := range s.columns {
+				if v := diffValue(after[i]); v != nil {
+					fields[col] = importField{To: v}
+				}
+			}
+			res.Created++
+			res.Changes = append(res.Changes, importChange{Line: r.Line, Action: "create", Name: name, Fields: fields})
+			creates = append(creates, row)
+			continue
+		}
+		matched[idx] = r.Line
+		before := s.values(&existing[idx])
+		fields := map[string]importField{}
+		for i, col := range s.columns {
+			if from, to := diffValue(before[i]), diffValue(after[i]); from != to {
+				fields[col] = importField{From: from, To: to}
+			}
+		}
+		if len(fields) == 0 {
+			res.Unchanged++
+			continue
+		}
+		res.Updated++
+		res.Changes = append(res.Changes, importChange{
+			Line: r.Line, Action: "update", ID: id, Name: name, Archived: archived, Fields: fields,
+		})
+		updates = append(updates, row)
+	}
+	return res, creates, updates
+}
+
+// export renders rows with a header line.
+func (s catalogSheet[T]) export(rows []T) [][]any {
+	out := make([][]any, 0, len(rows)+1)
+	header := make([]any, len(s.columns))
+	for i, col := range s.columns {
+		header[i] = col
+	}
+	out = append(out, header)
+	for i := range rows {
+		out = append(out, s.values(&rows[i]))
+	}
+	return out
+}
+
+// readImportSheet reads the multipart "file" as CSV or XLSX and maps its
+// header onto columns. An unknown header is an error rather than a column
+// silently skipped. It writes the error response itself.
+func (h *Handlers) readImportSheet(c *gin.Context, columns []string) ([]importRow, bool) {
+	fh, ok := h.formFile(c)
+	if !ok {
+		return nil, false
+	}
+	f, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unreadable upload"})
+		return nil, false
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unreadable upload"})
+		return nil, false
+	}
+	table, err := sheet.Read(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	known := map[string]string{}
+	for _, col := range columns {
+		known[strings.ToLower(col)] = col
+	}
+	header := make([]string, len(table[0]))
+	seen := map[string]bool{}
+	for i, h := range table[0] {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+		col, ok := known[strings.ToLower(h)]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("unknown column %q; expected %s", h, strings.Join(columns, ", ")),
+			})
+			return nil, false
+		}
+		if seen[col] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("column %q appears twice", col)})
+			return nil, false
+		}
+		seen[col] = true
+		header[i] = col
+	}
+	if !seen["name"] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": `the header row must include a "name" column`})
+		return nil, false
+	}
+
+	var rows []importRow
+	for n, rec := range table[1:] {
+		r := importRow{Line: n + 2, Cells: map[string]string{}}
+		empty := true
+		for i, col := range header {
+			if col == "" {
+				continue
+			}
+			cell := ""
+			if i < len(rec) {
+				cell = strings.TrimSpace(rec[i])
+			}
+			r.Cells[col] = cell
+			empty = empty && cell == ""
+		}
+		if !empty {
+			rows = append(rows, r)
+		}
+	}
+	return rows, true
+}
+
+func respondImport(c *gin.Context, res importResult, commit bool, err error) {
+	switch {
+	case errors.Is(err, store.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "catalog changed during import; run it again"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "import failed"})
+	case commit && len(res.Errors) > 0:
+		c.JSON(http.StatusUnprocessableEntity, res)
+	default:
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+// writeSheet sends rows as a CSV (default) or XLSX download named base.
+func writeSheet(c *gin.Context, base string, rows [][]any) {
+	var (
+		buf         bytes.Buffer
+		err         error
+		contentType string
+		ext         string
+	)
+	switch c.DefaultQuery("format", "csv") {
+	case "csv":
+		contentType, ext = "text/csv; charset=utf-8", ".csv"
+		err = sheet.WriteCSV(&buf, rows)
+	case "xlsx":
+		contentType, ext = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", ".xlsx"
+		err = sheet.WriteXLSX(&buf, base, rows)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or xlsx"})
+		return
+	}
+	if err != nil {
+		log.Printf("export %s: %v", base, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "export failed"})
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+base+ext+`"`)
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// GET /api/products/export?format=csv|xlsx&includeArchived=true (ADMIN)
+func (h *Handlers) ExportProducts(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	archived, ok := queryBool(c, "includeArchived")
+	if !ok {
+		return
+	}
+	rows, err := h.store.Catalog.ExportProducts(c, archived)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "export failed"})
+		return
+	}
+	writeSheet(c, "products", productSheet.export(rows))
+}
+
+// POST /api/products/import?commit=true (ADMIN)
+// Multipart: file (CSV or XLSX).
+func (h *Handlers) ImportProducts(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	commit, ok := queryBool(c, "commit")
+	if !ok {
+		return
+	}
+	rows, ok := h.readImportSheet(c, productSheetColumns)
+	if !ok {
+		return
+	}
+	var res importResult
+	err := h.store.Catalog.ImportProducts(c, func(existing []store.ProductRow) ([]store.ProductRow, []store.ProductRow, bool) {
+		var creates, updates []store.ProductRow
+		res, creates, updates = productSheet.plan(existing, rows)
+		res.Committed = commit && len(res.Errors) == 0
+		return creates, updates, res.Committed
+	})
+	respondImport(c, res, commit, err)
+}
+
+// GET /api/lightfixturetypes/export?format=csv|xlsx&includeArchived=true (ADMIN)
+func (h *Handlers) ExportLightFixtureTypes(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	archived, ok := queryBool(c, "includeArchived")
+	if !ok {
+		return
+	}
+	rows, err := h.store.Catalog.ExportFixtureTypes(c, archived)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "export failed"})
+		return
+	}
+	writeSheet(c, "lightfixturetypes", fixtureTypeSheet.export(rows))
+}
+
+// POST /api/lightfixturetypes/import?commit=true (ADMIN)
+// Multipart: file (CSV or XLSX).
+func (h *Handlers) ImportLightFixtureTypes(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	commit, ok := queryBool(c, "commit")
+	if !ok {
+		return
+	}
+	rows, ok := h.readImportSheet(c, fixtureTypeSheetColumns)
+	if !ok {
+		return
+	}
+	var res importResult
+	err := h.store.Catalog.ImportFixtureTypes(c, func(existing []store.FixtureTypeRow) ([]store.FixtureTypeRow, []store.FixtureTypeRow, bool) {
+		var creates, updates []store.FixtureTypeRow
+		res, creates, updates = fixtureTypeSheet.plan(existing, rows)
+		res.Committed = commit && len(res.Errors) == 0
+		return creates, updates, res.Committed
+	})
+	respondImport(c, res, commit, err)
+}
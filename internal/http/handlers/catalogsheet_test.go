@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rick/go-neon-api/internal/store"
+)
+
+func TestPlanProducts(t *testing.T) {
+	sku := func(s string) *string { return &s }
+	existing := []store.ProductRow{
+		{ID: "p1", Name: "T8 4ft", Wattage: 32, SKU: sku("T8-4")},
+		{ID: "p2", Name: "T12 4ft", Wattage: 40},
+		{ID: "p3", Name: "CFL", Wattage: 13, SKU: sku("DUP")},
+		{ID: "p4", Name: "CFL 2", Wattage: 26, SKU: sku("DUP")},
+	}
+	row := func(line int, cells ...string) importRow {
+		r := importRow{Line: line, Cells: map[string]string{}}
+		for i := 0; i < len(cells); i += 2 {
+			r.Cells[cells[i]] = cells[i+1]
+		}
+		return r
+	}
+
+	for _, tc := range []struct {
+		name    string
+		rows    []importRow
+		created int
+		updated []string // IDs, in order
+		same    int
+		errors  []importError
+	}{
+		{
+			name: "SKU match wins over name",
+			// Renames p1 to p2's name: the SKU picks p1, then the name clashes.
+			rows:   []importRow{row(2, "name", "T12 4ft", "sku", "T8-4")},
+			errors: []importError{{2, `name "T12 4ft" is used by another item`}},
+		},
+		{
+			name:    "SKU match renames",
+			rows:    []importRow{row(2, "name", "T8 48in", "sku", "T8-4", "wattage", "32")},
+			updated: []string{"p1"},
+		},
+		{
+			name:    "name match when the SKU is new",
+			rows:    []importRow{row(2, "name", "T12 4ft", "sku", "T12-4")},
+			updated: []string{"p2"},
+		},
+		{
+			name: "unchanged and missing columns keep stored values",
+			rows: []importRow{row(2, "name", "T8 4ft"), row(3, "name", "T12 4ft", "wattage", "40W")},
+			same: 2,
+		},
+		{
+			name:   "SKU shared by two items",
+			rows:   []importRow{row(2, "name", "CFL", "sku", "DUP")},
+			errors: []importError{{2, `SKU "DUP" matches 2 items`}},
+		},
+		{
+			name:    "create needs the required columns",
+			rows:    []importRow{row(2, "name", "LED", "sku", "L1"), row(3, "name", "LED 2", "wattage", "9")},
+			created: 1,
+			errors:  []importError{{2, "wattage is required for a new item"}},
+		},
+		{
+			name:   "required column present but empty",
+			rows:   []importRow{row(2, "name", "LED", "wattage", "")},
+			errors: []importError{{2, "wattage is required"}},
+		},
+		{
+			name:   "name is required",
+			rows:   []importRow{row(2, "name", "", "sku", "T8-4")},
+			errors: []importError{{2, "name is required"}},
+		},
+		{
+			name:    "duplicate names among creates",
+			rows:    []importRow{row(2, "name", "LED", "wattage", "9"), row(3, "name", "LED", "wattage", "10")},
+			created: 1,
+			errors:  []importError{{3, `name "LED" repeats line 2`}},
+		},
+		{
+			name:    "two lines update the same item",
+			rows:    []importRow{row(2, "name", "T8 4ft", "wattage", "30"), row(3, "name", "Renamed", "sku", "T8-4")},
+			updated: []string{"p1"},
+			errors:  []importError{{3, "updates the same item as line 2"}},
+		},
+		{
+			name: "SKU repeated between an update and a create",
+			rows: []importRow{
+				row(2, "name", "T12 4ft", "sku", "NEW"),
+				row(3, "name", "LED", "sku", "NEW", "wattage", "9"),
+			},
+			updated: []string{"p2"},
+			errors:  []importError{{3, `SKU "NEW" repeats line 2`}},
+		},
+		{
+			name:    "SKU moved off an item and reused",
+			rows:    []importRow{row(2, "name", "T8 4ft", "sku", ""), row(3, "name", "LED", "sku", "T8-4", "wattage", "9")},
+			updated: []string{"p1"},
+			// Line 3 matches p1 by SKU, which line 2 already updates.
+			errors: []importError{{3, "updates the same item as line 2"}},
+		},
+		{
+			name:   "bad cell",
+			rows:   []importRow{row(2, "name", "T8 4ft", "wattage", "lots")},
+			errors: []importError{{2, `wattage "lots" is not a non-negative number`}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			res, creates, updates := productSheet.plan(existing, tc.rows)
+			var ids []string
+			for _, u := range updates {
+				ids = append(ids, u.ID)
+			}
+			if res.Created != tc.created || len(creates) != tc.created {
+				t.Errorf("created %d (%d rows), want %d", res.Created, len(creates), tc.created)
+			}
+			if res.Updated != len(tc.updated) || !reflect.DeepEqual(ids, tc.updated) {
+				t.Errorf("updated %d %v, want %v", res.Updated, ids, tc.updated)
+			}
+			if res.Unchanged != tc.same {
+				t.Errorf("unchanged %d, want %d", res.Unchanged, tc.same)
+			}
+			if tc.errors == nil {
+				tc.errors = []importError{}
+			}
+			if !reflect.DeepEqual(res.Errors, tc.errors) {
+				t.Errorf("errors %v, want %v", res.Errors, tc.errors)
+			}
+		})
+	}
+}
+
+func TestPlanDiff(t *testing.T) {
+	cost := 12.5
+	existing := []store.FixtureTypeRow{{ID: "f1", Name: "Panel", Pricing: store.Pricing{UnitCost: &cost}}}
+	res, creates, updates := fixtureTypeSheet.plan(existing, []importRow{
+		{Line: 2, Cells: map[string]string{"name": "Panel", "unitCost": "$1,250.00", "wattage": ""}},
+		{Line: 3, Cells: map[string]string{"name": "Troffer", "wattage": "30w"}},
+	})
+	if len(creates) != 1 || len(updates) != 1 || len(res.Errors) != 0 {
+		t.Fatalf("plan = %+v", res)
+	}
+	want := []importChange{
+		{Line: 2, Action: "update", ID: "f1", Name: "Panel", Fields: map[string]importField{
+			"unitCost": {From: 12.5, To: 1250.0},
+		}},
+		{Line: 3, Action: "create", Name: "Troffer", Fields: map[string]importField{
+			"name": {To: "Troffer"}, "wattage": {To: 30.0},
+		}},
+	}
+	if !reflect.DeepEqual(res.Changes, want) {
+		t.Errorf("changes = %+v, want %+v", res.Changes, want)
+	}
+}
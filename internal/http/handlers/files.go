@@ -1,50 +1,195 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/rick/go-neon-api/internal/db"
+	"github.com/rick/go-neon-api/internal/auth"
 	"github.com/rick/go-neon-api/internal/models"
+	"github.com/rick/go-neon-api/internal/store"
 )
 
 type AddFileReq struct {
 	URL         string  `json:"url" binding:"required"`
 	FileName    *string `json:"fileName"` // for Document
 	CustomName  *string `json:"customName"`
-	Comment     *string `json:"comment"` // for Photo
+	Comment     *string `json:"comment"`
 	UploadedVia *bool   `json:"uploadedViaLink"`
 }
 
+// Columns PUT may change on a case photo or document.
+var fileFields = map[string]bool{
+	"customName": true,
+	"comment":    true,
+}
+
+// bindFilePatch is bindPatch for fileFields. Values must be strings or null;
+// blank strings are stored as NULL.
+func bindFilePatch(c *gin.Context) (map[string]any, bool) {
+	set, ok := bindPatch(c, fileFields)
+	if !ok {
+		return nil, false
+	}
+	for k, v := range set {
+		switch s := v.(type) {
+		case nil:
+		case string:
+			if s = strings.TrimSpace(s); s == "" {
+				set[k] = nil
+			} else {
+				set[k] = s
+			}
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": k + " must be a string or null"})
+			return nil, false
+		}
+	}
+	return set, true
+}
+
+// fileError writes the response for a failed photo/document write.
+func fileError(c *gin.Context, err error, action string) {
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": action + " failed"})
+}
+
+// -------------------- Photos --------------------
+
+// GET /api/cases/:id/photos
+func (h *Handlers) ListPhotos(c *gin.Context) {
+	rows, err := h.store.Files.Photos(c, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load photos"})
+		return
+	}
+	h.signPhotos(c, rows)
+	c.JSON(http.StatusOK, gin.H{"items": rows})
+}
+
+type photoResp struct {
+	models.Photo
+	DownloadURL          string `json:"downloadUrl"`
+	ThumbnailDownloadURL string `json:"thumbnailDownloadUrl"`
+}
+
+// POST /api/cases/:id/photos
+// Either a multipart upload (file, customName, comment) or JSON naming an
+// external url.
 func (h *Handlers) AddPhoto(c *gin.Context) {
 	caseID := c.Param("id")
 	var req AddFileReq
-	if err := c.ShouldBindJSON(&req); err != nil {
+	var meta store.PhotoMeta
+	if isMultipart(c) {
+		up, ok := h.receiveUpload(c, "cases/"+caseID+"/photos", nil)
+		if !ok {
+			return
+		}
+		req = AddFileReq{URL: up.Key, CustomName: formString(c, "customName"), Comment: formString(c, "comment")}
+		meta = h.processPhoto(c, up)
+	} else if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
+	} else if !isExternalURL(req.URL) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url must be an absolute http(s) URL"})
+		return
 	}
 	item := models.Photo{
 		URL:             req.URL,
 		CaseID:          caseID,
 		UploadedViaLink: req.UploadedVia != nil && *req.UploadedVia,
 		CustomName:      req.CustomName,
+		ThumbnailURL:    meta.ThumbnailURL,
+		TakenAt:         meta.TakenAt,
+		Latitude:        meta.Latitude,
+		Longitude:       meta.Longitude,
 	}
 	if req.Comment != nil {
 		item.Comment = req.Comment
 	}
-	if err := db.DB.Create(&item).Error; err != nil {
+	if err := h.store.Files.AddPhoto(c, auth.CurrentUser(c).ID, &item); err != nil {
+		h.discardPhoto(c, item.URL)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "create failed"})
 		return
 	}
-	c.JSON(http.StatusCreated, item)
+	c.JSON(http.StatusCreated, photoResp{item, h.downloadURL(c, item.URL), h.thumbnailDownloadURL(c, meta)})
+}
+
+// PUT /api/cases/:id/photos/:photoId
+// Renames (customName) or captions (comment) a photo.
+func (h *Handlers) UpdatePhoto(c *gin.Context) {
+	set, ok := bindFilePatch(c)
+	if !ok {
+		return
+	}
+	err := h.store.Files.UpdatePhoto(c, auth.CurrentUser(c).ID, c.Param("id"), c.Param("photoId"), set)
+	if err != nil {
+		fileError(c, err, "update")
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// DELETE /api/cases/:id/photos/:photoId
+// Also removes the stored file, if the photo was uploaded.
+func (h *Handlers) DeletePhoto(c *gin.Context) {
+	url, err := h.store.Files.DeletePhoto(c, auth.CurrentUser(c).ID, c.Param("id"), c.Param("photoId"))
+	if err != nil {
+		fileError(c, err, "delete")
+		return
+	}
+	h.discardPhoto(c, url)
+	c.Status(http.StatusNoContent)
+}
+
+// -------------------- Documents --------------------
+
+// GET /api/cases/:id/documents
+func (h *Handlers) ListDocuments(c *gin.Context) {
+	rows, err := h.store.Files.Documents(c, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load documents"})
+		return
+	}
+	h.signDocuments(c, rows)
+	c.JSON(http.StatusOK, gin.H{"items": rows})
+}
+
+type documentResp struct {
+	models.Document
+	DownloadURL string `json:"downloadUrl"`
 }
 
+// POST /api/cases/:id/documents
+// Either a multipart upload (file, fileName, customName, comment) or JSON
+// naming an external url. fileName defaults to the uploaded file's name.
 func (h *Handlers) AddDocument(c *gin.Context) {
 	caseID := c.Param("id")
 	var req AddFileReq
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if isMultipart(c) {
+		up, ok := h.receiveUpload(c, "cases/"+caseID+"/documents", nil)
+		if !ok {
+			return
+		}
+		req = AddFileReq{
+			URL:        up.Key,
+			FileName:   formString(c, "fileName"),
+			CustomName: formString(c, "customName"),
+			Comment:    formString(c, "comment"),
+		}
+		if req.FileName == nil {
+			req.FileName = &up.FileName
+		}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
+	} else if !isExternalURL(req.URL) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url must be an absolute http(s) URL"})
+		return
 	}
 	if req.FileName == nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "fileName is required"})
@@ -56,10 +201,39 @@ func (h *Handlers) AddDocument(c *gin.Context) {
 		CaseID:          caseID,
 		UploadedViaLink: req.UploadedVia != nil && *req.UploadedVia,
 		CustomName:      req.CustomName,
+		Comment:         req.Comment,
 	}
-	if err := db.DB.Create(&item).Error; err != nil {
+	if err := h.store.Files.AddDocument(c, auth.CurrentUser(c).ID, &item); err != nil {
+		h.discardObject(c, item.URL)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "create failed"})
 		return
 	}
-	c.JSON(http.StatusCreated, item)
+	c.JSON(http.StatusCreated, documentResp{item, h.downloadURL(c, item.URL)})
+}
+
+// PUT /api/cases/:id/documents/:documentId
+// Renames (customName) or comments on a document.
+func (h *Handlers) UpdateDocument(c *gin.Context) {
+	set, ok := bindFilePatch(c)
+	if !ok {
+		return
+	}
+	err := h.store.Files.UpdateDocument(c, auth.CurrentUser(c).ID, c.Param("id"), c.Param("documentId"), set)
+	if err != nil {
+		fileError(c, err, "update")
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// DELETE /api/cases/:id/documents/:documentId
+// Also removes the stored file, if the document was uploaded.
+func (h *Handlers) DeleteDocument(c *gin.Context) {
+	url, err := h.store.Files.DeleteDocument(c, auth.CurrentUser(c).ID, c.Param("id"), c.Param("documentId"))
+	if err != nil {
+		fileError(c, err, "delete")
+		return
+	}
+	h.discardObject(c, url)
+	c.Status(http.StatusNoContent)
 }
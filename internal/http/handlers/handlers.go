@@ -1,5 +1,22 @@
 package handlers
 
-type Handlers struct{}
+import (
+	"github.com/rick/go-neon-api/internal/casestatus"
+	"github.com/rick/go-neon-api/internal/mail"
+	"github.com/rick/go-neon-api/internal/storage"
+	"github.com/rick/go-neon-api/internal/store"
+)
 
-func New() *Handlers { return &Handlers{} }
+type Handlers struct {
+	store       *store.Store
+	files       storage.Storage
+	uploads     storage.Limits
+	statusRules casestatus.Rules
+	energy      energyRates
+	proposal    proposalBrand
+	mailer      mail.Mailer
+}
+
+func New(st *store.Store, files storage.Storage, uploads storage.Limits, mailer mail.Mailer) *Handlers {
+	return &Handlers{store: st, files: files, uploads: uploads, statusRules: casestatus.MustLoad(), energy: mustLoadEnergyRates(), proposal: mustLoadProposalBrand(), mailer: mailer}
+}
@@ -0,0 +1,483 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rick/go-neon-api/internal/auth"
+	apphttp "github.com/rick/go-neon-api/internal/http"
+	"github.com/rick/go-neon-api/internal/http/handlers"
+	"github.com/rick/go-neon-api/internal/mail"
+	"github.com/rick/go-neon-api/internal/models"
+	"github.com/rick/go-neon-api/internal/storage"
+	"github.com/rick/go-neon-api/internal/store"
+	"github.com/rick/go-neon-api/internal/store/memory"
+)
+
+const password = "password1"
+
+// api is the full router over the memory store and local storage.
+type api struct {
+	t     *testing.T
+	db    *memory.DB
+	dir   string // local storage root
+	r     *gin.Engine
+	admin *models.User
+	user  *models.User
+	mail  *outbox
+}
+
+// outbox is a mail.Mailer that keeps what it was given.
+type outbox struct {
+	mu   sync.Mutex
+	sent []mail.Message
+}
+
+func (o *outbox) Send(_ context.Context, m mail.Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.sent = append(o.sent, m)
+	return nil
+}
+
+func newAPI(t *testing.T) *api {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	t.Setenv("AUTH_SECRET", "0123456789abcdef0123456789abcdef")
+	auth.Configure()
+
+	db := memory.New()
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	files, err := storage.NewLocal(dir, "", []byte("test signing key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mailer := &outbox{}
+	h := handlers.New(db.Store(), files, storage.Limits{MaxUploadBytes: 1 << 20, URLTTL: time.Minute}, mailer)
+	return &api{
+		mail:  mailer,
+		t:     t,
+		db:    db,
+		dir:   dir,
+		r:     apphttp.NewRouter(h),
+		admin: db.PutUser(models.User{Email: "admin@example.com", Password: &hash, Role: models.RoleAdmin}),
+		user:  db.PutUser(models.User{Email: "user@example.com", Password: &hash, Role: models.RoleUser}),
+	}
+}
+
+func (a *api) login(email string) string {
+	a.t.Helper()
+	var out struct {
+		Token string `json:"token"`
+	}
+	a.want(http.StatusOK, a.do("", http.MethodPost, "/api/auth/login", map[string]any{"email": email, "password": password}), &out)
+	return out.Token
+}
+
+func (a *api) send(token string, req *http.Request) *httptest.ResponseRecorder {
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	a.r.ServeHTTP(w, req)
+	return w
+}
+
+func (a *api) do(token, method, path string, body any) *httptest.ResponseRecorder {
+	a.t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			a.t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	return a.send(token, req)
+}
+
+func (a *api) upload(token, path, fileName string, content []byte) *httptest.ResponseRecorder {
+	a.t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, err := mw.CreateFormFile("file", fileName)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	fw.Write(content)
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, path, &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return a.send(token, req)
+}
+
+// want fails unless w has status code, then decodes the body into out.
+func (a *api) want(code int, w *httptest.ResponseRecorder, out any) {
+	a.t.Helper()
+	if w.Code != code {
+		a.t.Fatalf("status %d, want %d: %s", w.Code, code, w.Body.String())
+	}
+	if out != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			a.t.Fatalf("decode %s: %v", w.Body.String(), err)
+		}
+	}
+}
+
+func (a *api) createCase(token string, body map[string]any) string {
+	a.t.Helper()
+	var out struct {
+		ID string `json:"id"`
+	}
+	a.want(http.StatusCreated, a.do(token, http.MethodPost, "/api/cases", body), &out)
+	return out.ID
+}
+
+// room adds a room to the case's visit, creating the visit if needed, and
+// returns the room's id.
+func (a *api) room(token, caseID string, body map[string]any) string {
+	a.t.Helper()
+	var visit, room struct {
+		ID string `json:"id"`
+	}
+	a.want(http.StatusOK, a.do(token, http.MethodPost, "/api/cases/"+caseID+"/onsite", nil), &visit)
+	a.want(http.StatusCreated, a.do(token, http.MethodPost, "/api/onsite/"+visit.ID+"/rooms", body), &room)
+	return room.ID
+}
+
+// suggest adds a suggested fixture line to a room and returns its id.
+func (a *api) suggest(token, roomID, fixtureTypeID string, quantity int) string {
+	a.t.Helper()
+	var out struct {
+		ID string `json:"id"`
+	}
+	body := map[string]any{"productId": fixtureTypeID, "quantity": quantity}
+	a.want(http.StatusCreated, a.do(token, http.MethodPost, "/api/rooms/"+roomID+"/suggested", body), &out)
+	return out.ID
+}
+
+func ptr[T any](v T) *T { return &v }
+
+// stored reports whether the object at key is in local storage.
+func (a *api) stored(key string) bool {
+	_, err := os.Stat(filepath.Join(a.dir, filepath.FromSlash(key)))
+	return err == nil
+}
+
+func pngBytes(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 40, 30))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCaseCRUD(t *testing.T) {
+	a := newAPI(t)
+	token := a.login("admin@example.com")
+
+	id := a.createCase(token, map[string]any{"customerName": " Lincoln ", "schoolName": "Lincoln High"})
+	a.want(http.StatusOK, a.do(token, http.MethodPut, "/api/cases/"+id, map[string]any{"phoneNumber": "555-0100", "ignored": true}), nil)
+	a.want(http.StatusBadRequest, a.do(token, http.MethodPut, "/api/cases/"+id, map[string]any{"customerName": " "}), nil)
+	a.want(http.StatusBadRequest, a.do(token, http.MethodPut, "/api/cases/"+id, map[string]any{"userId": "missing"}), nil)
+
+	var got struct {
+		CustomerName string `json:"customerName"`
+		PhoneNumber  string `json:"phoneNumber"`
+		Status       string `json:"status"`
+	}
+	a.want(http.StatusOK, a.do(token, http.MethodGet, "/api/cases/"+id, nil), &got)
+	if got.CustomerName != "Lincoln" || got.PhoneNumber != "555-0100" || got.Status == "" {
+		t.Errorf("case = %+v", got)
+	}
+
+	a.want(http.StatusNoContent, a.do(token, http.MethodDelete, "/api/cases/"+id, nil), nil)
+	a.want(http.StatusNotFound, a.do(token, http.MethodGet, "/api/cases/"+id, nil), nil)
+	a.want(http.StatusNotFound, a.do(token, http.MethodDelete, "/api/cases/"+id, nil), nil)
+
+	var logged bool
+	for _, l := range a.db.Activity("") {
+		logged = logged || (l.CaseID == nil && l.UserID == a.admin.ID)
+	}
+	if !logged {
+		t.Error("case deletion was not logged")
+	}
+}
+
+func TestCaseOwnership(t *testing.T) {
+	a := newAPI(t)
+	admin, user := a.login("admin@example.com"), a.login("user@example.com")
+
+	mine := a.createCase(user, map[string]any{"customerName": "Mine"})
+	theirs := a.createCase(admin, map[string]any{"customerName": "Theirs"})
+	a.want(http.StatusForbidden, a.do(user, http.MethodPost, "/api/cases", map[string]any{"customerName": "X", "userId": a.admin.ID}), nil)
+
+	a.want(http.StatusOK, a.do(user, http.MethodGet, "/api/cases/"+mine, nil), nil)
+	a.want(http.StatusForbidden, a.do(user, http.MethodGet, "/api/cases/"+theirs, nil), nil)
+	a.want(http.StatusForbidden, a.do(user, http.MethodDelete, "/api/cases/"+theirs, nil), nil)
+	a.want(http.StatusNotFound, a.do(user, http.MethodGet, "/api/cases/missing", nil), nil)
+	a.want(http.StatusForbidden, a.do(user, http.MethodPut, "/api/cases/"+mine, map[string]any{"userId": a.admin.ID}), nil)
+	a.want(http.StatusForbidden, a.do(user, http.MethodGet, "/api/cases?userId="+a.admin.ID, nil), nil)
+
+	var page struct {
+		Items []struct {
+			ID string `json:"id"`
+		} `json:"items"`
+	}
+	a.want(http.StatusOK, a.do(user, http.MethodGet, "/api/cases", nil), &page)
+	if len(page.Items) != 1 || page.Items[0].ID != mine {
+		t.Errorf("user lists %+v, want only %s", page.Items, mine)
+	}
+	a.want(http.StatusOK, a.do(admin, http.MethodGet, "/api/cases", nil), &page)
+	if len(page.Items) != 2 {
+		t.Errorf("admin lists %d cases, want 2", len(page.Items))
+	}
+
+	a.want(http.StatusOK, a.do(admin, http.MethodPut, "/api/cases/"+theirs, map[string]any{"userId": a.user.ID}), nil)
+	a.want(http.StatusOK, a.do(user, http.MethodGet, "/api/cases/"+theirs, nil), nil)
+}
+
+// TestListCasesEverySort walks each sort one row at a time in both orders;
+// every row must come back exactly once.
+func TestListCasesEverySort(t *testing.T) {
+	a := newAPI(t)
+	token := a.login("admin@example.com")
+	want := map[string]bool{}
+	for _, c := range []map[string]any{
+		{"customerName": "Beta", "operationDaysPerYear": 200, "operationHoursPerDay": 8},
+		{"customerName": "Alpha", "schoolName": "Zed", "operationDaysPerYear": 200},
+		{"customerName": "Gamma", "emailAddress": "g@example.com", "userId": a.user.ID},
+	} {
+		want[a.createCase(token, c)] = true
+	}
+
+	for sort := range store.CaseSorts {
+		for _, order := range []string{"asc", "desc"} {
+			seen := map[string]bool{}
+			path := "/api/cases?limit=1&sort=" + sort + "&order=" + order
+			for next := ""; ; {
+				var page struct {
+					Items []struct {
+						ID string `json:"id"`
+					} `json:"items"`
+					NextCursor *string `json:"nextCursor"`
+				}
+				a.want(http.StatusOK, a.do(token, http.MethodGet, path+next, nil), &page)
+				for _, it := range page.Items {
+					if seen[it.ID] {
+						t.Fatalf("sort=%s order=%s: %s returned twice", sort, order, it.ID)
+					}
+					seen[it.ID] = true
+				}
+				if page.NextCursor == nil {
+					break
+				}
+				next = "&cursor=" + *page.NextCursor
+			}
+			if len(seen) != len(want) {
+				t.Errorf("sort=%s order=%s: got %d cases, want %d", sort, order, len(seen), len(want))
+			}
+		}
+	}
+	a.want(http.StatusBadRequest, a.do(token, http.MethodGet, "/api/cases?sort=password", nil), nil)
+}
+
+func TestUploadedFilesServedBySniffedType(t *testing.T) {
+	a := newAPI(t)
+	token := a.login("admin@example.com")
+	id := a.createCase(token, map[string]any{"customerName": "Lincoln"})
+
+	for _, tc := range []struct {
+		path, name string
+		content    []byte
+		typ        string
+		attachment bool
+	}{
+		{"/photos", "evil.html", pngBytes(t), "image/png", false},
+		{"/documents", "plan.pdf", []byte("%PDF-1.4 plan"), "application/pdf", false},
+		{"/documents", "page.html", []byte("<html><script>alert(1)</script></html>"), "application/octet-stream", true},
+	} {
+		var up struct {
+			URL         string `json:"url"`
+			DownloadURL string `json:"downloadUrl"`
+		}
+		a.want(http.StatusCreated, a.upload(token, "/api/cases/"+id+tc.path, tc.name, tc.content), &up)
+		if strings.HasSuffix(up.URL, ".html") {
+			t.Errorf("%s: key %s takes its extension from the file name", tc.name, up.URL)
+		}
+		w := a.send("", httptest.NewRequest(http.MethodGet, up.DownloadURL, nil))
+		a.want(http.StatusOK, w, nil)
+		if ct := w.Header().Get("Content-Type"); ct != tc.typ {
+			t.Errorf("%s: Content-Type %q, want %q", tc.name, ct, tc.typ)
+		}
+		if cd := w.Header().Get("Content-Disposition"); (cd == "attachment") != tc.attachment {
+			t.Errorf("%s: Content-Disposition %q", tc.name, cd)
+		}
+	}
+}
+
+func TestDeletesRemoveStoredObjects(t *testing.T) {
+	a := newAPI(t)
+	token := a.login("admin@example.com")
+	id := a.createCase(token, map[string]any{"customerName": "Lincoln"})
+
+	var visit, room, other struct {
+		ID string `json:"id"`
+	}
+	a.want(http.StatusOK, a.do(token, http.MethodPost, "/api/cases/"+id+"/onsite", nil), &visit)
+	a.want(http.StatusCreated, a.do(token, http.MethodPost, "/api/onsite/"+visit.ID+"/rooms", map[string]any{"location": "Gym"}), &room)
+	a.want(http.StatusCreated, a.do(token, http.MethodPost, "/api/onsite/"+visit.ID+"/rooms", map[string]any{"location": "Hall"}), &other)
+
+	keys := func(path string, content []byte) []string {
+		var up struct {
+			URL          string  `json:"url"`
+			ThumbnailURL *string `json:"thumbnailUrl"`
+		}
+		a.want(http.StatusCreated, a.upload(token, path, "file", content), &up)
+		out := []string{up.URL}
+		if up.ThumbnailURL != nil {
+			out = append(out, *up.ThumbnailURL)
+		}
+		for _, k := range out {
+			if !a.stored(k) {
+				t.Fatalf("%s not stored", k)
+			}
+		}
+		return out
+	}
+	roomKeys := keys("/api/rooms/"+room.ID+"/photos", pngBytes(t))
+	if len(roomKeys) != 2 {
+		t.Fatalf("room photo has no thumbnail: %v", roomKeys)
+	}
+	a.want(http.StatusNoContent, a.do(token, http.MethodDelete, "/api/rooms/"+room.ID, nil), nil)
+	for _, k := range roomKeys {
+		if a.stored(k) {
+			t.Errorf("%s survived its room", k)
+		}
+	}
+
+	var caseKeys []string
+	caseKeys = append(caseKeys, keys("/api/rooms/"+other.ID+"/photos", pngBytes(t))...)
+	caseKeys = append(caseKeys, keys("/api/cases/"+id+"/photos", pngBytes(t))...)
+	caseKeys = append(caseKeys, keys("/api/cases/"+id+"/documents", []byte("%PDF-1.4 plan"))...)
+	a.want(http.StatusNoContent, a.do(token, http.MethodDelete, "/api/cases/"+id, nil), nil)
+	for _, k := range caseKeys {
+		if a.stored(k) {
+			t.Errorf("%s survived its case", k)
+		}
+	}
+}
+
+func TestPasswordResetMailsTheLinkWithoutLoggingIt(t *testing.T) {
+	a := newAPI(t)
+	t.Setenv("PASSWORD_RESET_URL", "https://app.example.com/reset")
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	a.want(http.StatusAccepted, a.do("", http.MethodPost, "/api/auth/password-reset", map[string]any{"email": "nobody@example.com"}), nil)
+	a.want(http.StatusAccepted, a.do("", http.MethodPost, "/api/auth/password-reset", map[string]any{"email": "user@example.com"}), nil)
+	if len(a.mail.sent) != 1 || a.mail.sent[0].To != "user@example.com" {
+		t.Fatalf("sent %+v, want one message to user@example.com", a.mail.sent)
+	}
+	_, token, ok := strings.Cut(a.mail.sent[0].Body, "https://app.example.com/reset?token=")
+	if !ok {
+		t.Fatalf("no reset link in %q", a.mail.sent[0].Body)
+	}
+	token, _, _ = strings.Cut(token, "\n")
+	if strings.Contains(logs.String(), token) || strings.Contains(logs.String(), "user@example.com") {
+		t.Errorf("logs expose the reset: %s", logs.String())
+	}
+
+	a.want(http.StatusNoContent, a.do("", http.MethodPost, "/api/auth/password-reset/confirm", map[string]any{"token": token, "newPassword": "password2"}), nil)
+	a.want(http.StatusOK, a.do("", http.MethodPost, "/api/auth/login", map[string]any{"email": "user@example.com", "password": "password2"}), nil)
+}
+
+// portalBudget counts the portal requests from one peer, each claiming a new
+// X-Forwarded-For, that get through before the limiter answers 429.
+func portalBudget(a *api) int {
+	n := 0
+	for ; n < 100; n++ {
+		req := httptest.NewRequest(http.MethodGet, "/api/upload/missing", nil)
+		req.RemoteAddr = "192.0.2.1:40000"
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", n))
+		if a.send("", req).Code == http.StatusTooManyRequests {
+			break
+		}
+	}
+	return n
+}
+
+func TestPortalRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	if n := portalBudget(newAPI(t)); n != 60 {
+		t.Errorf("%d requests before 429, want 60", n)
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "192.0.2.1")
+	if n := portalBudget(newAPI(t)); n <= 60 {
+		t.Errorf("behind a trusted proxy, forwarded clients share one budget: 429 after %d", n)
+	}
+}
+
+func TestProductImport(t *testing.T) {
+	a := newAPI(t)
+	admin := a.login("admin@example.com")
+
+	for _, tc := range []struct {
+		sheet, want string
+	}{
+		{"sku,wattage\nA,1\n", `{"error":"the header row must include a \"name\" column"}`},
+		{"name,watts\nA,1\n", `{"error":"unknown column \"watts\"; expected name, sku, wattage, category, description"}`},
+		{"Name,NAME\nA,B\n", `{"error":"column \"name\" appears twice"}`},
+		{"\n\n", `{"error":"sheet: no rows"}`},
+	} {
+		w := a.upload(admin, "/api/products/import", "products.csv", []byte(tc.sheet))
+		if w.Code != http.StatusBadRequest || w.Body.String() != tc.want {
+			t.Errorf("import %q: %d %s, want 400 %s", tc.sheet, w.Code, w.Body.String(), tc.want)
+		}
+	}
+
+	sheet := []byte("\xef\xbb\xbfName, SKU ,Wattage\nT8 4ft,T8-4,32\n ,,\nLED, LED-1 ,9W\n")
+	var res struct {
+		Committed bool `json:"committed"`
+		Created   int  `json:"created"`
+	}
+	a.want(http.StatusOK, a.upload(admin, "/api/products/import", "products.csv", sheet), &res)
+	if res.Committed || res.Created != 2 {
+		t.Fatalf("dry run = %+v, want 2 uncommitted creates", res)
+	}
+	a.want(http.StatusOK, a.upload(admin, "/api/products/import?commit=true", "products.csv", sheet), &res)
+	if !res.Committed || res.Created != 2 {
+		t.Fatalf("commit = %+v, want 2 committed creates", res)
+	}
+
+	// The admin endpoints keep SKUs unique as the import does.
+	w := a.do(admin, http.MethodPost, "/api/products", map[string]any{"name": "LED 2", "wattage": 9, "sku": "LED-1"})
+	a.want(http.StatusConflict, w, nil)
+	var p struct {
+		ID string `json:"id"`
+	}
+	a.want(http.StatusCreated, a.do(admin, http.MethodPost, "/api/products", map[string]any{"name": "LED 2", "wattage": 9}), &p)
+	a.want(http.StatusConflict, a.do(admin, http.MethodPut, "/api/products/"+p.ID, map[string]any{"name": "LED 2", "wattage": 9, "sku": "T8-4"}), nil)
+	a.want(http.StatusOK, a.do(admin, http.MethodPut, "/api/products/"+p.ID, map[string]any{"name": "LED 2", "wattage": 9, "sku": "LED-2"}), nil)
+}
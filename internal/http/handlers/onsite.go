@@ -1,172 +1,137 @@
 package handlers
 
 import (
-	"fmt"
+	"errors"
 	"log"
 	"net/http"
+	"sort"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/lucsky/cuid"
-	"github.com/rick/go-neon-api/internal/db"
+	"github.com/rick/go-neon-api/internal/store"
 )
 
 // -------------------- EnsureOnSiteVisit --------------------
 // POST /api/cases/:id/onsite
 // Creates an OnSiteVisit if missing and returns the visit header.
 func (h *Handlers) EnsureOnSiteVisit(c *gin.Context) {
-	caseID := c.Param("id")
-
-	// Try find
-	var head struct {
-		ID        string    `json:"id"        gorm:"column:id"`
-		CaseID    string    `json:"caseId"    gorm:"column:caseId"`
-		CreatedAt time.Time `json:"createdAt" gorm:"column:createdAt"`
-	}
-	if err := db.DB.Raw(
-		`SELECT "id","caseId","createdAt" FROM "OnSiteVisit" WHERE "caseId" = ? LIMIT 1`,
-		caseID,
-	).Scan(&head).Error; err == nil && head.ID != "" {
-		c.JSON(http.StatusOK, head)
-		return
-	}
-
-	// Create new with CUID
-	newID := cuid.New()
-	if err := db.DB.Raw(
-		`INSERT INTO "OnSiteVisit" ("id","caseId","createdAt")
-		 VALUES (?, ?, now())
-		 RETURNING "id","caseId","createdAt"`,
-		newID, caseID,
-	).Scan(&head).Error; err != nil {
+	visit, err := h.store.OnSite.EnsureVisit(c, c.Param("id"))
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "create visit failed"})
 		return
 	}
-
-	c.JSON(http.StatusOK, head)
+	c.JSON(http.StatusOK, visit)
 }
 
 // -------------------- GetOnSiteVisit --------------------
-// GET /api/cases/:id/onsite
-// Returns visit header and rooms with existing/suggested products.
+// GET /api/cases/:id/onsite[?groupBy=locationTag]
+// Returns visit header and rooms with existing/suggested products. With
+// groupBy=locationTag, "rooms" is replaced by "groups": one per location tag
+// by name, untagged rooms last, each with fixture subtotals.
 func (h *Handlers) GetOnSiteVisit(c *gin.Context) {
-	caseID := c.Param("id")
+	groupBy := c.Query("groupBy")
+	if groupBy != "" && groupBy != "locationTag" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "groupBy must be locationTag"})
+		return
+	}
 
-	// Visit
-	var visit struct {
-		ID        string    `json:"id"        gorm:"column:id"`
-		CaseID    string    `json:"caseId"    gorm:"column:caseId"`
-		CreatedAt time.Time `json:"createdAt" gorm:"column:createdAt"`
-	}
-	if err := db.DB.Raw(
-		`SELECT "id","caseId","createdAt" FROM "OnSiteVisit" WHERE "caseId" = ? LIMIT 1`,
-		caseID,
-	).Scan(&visit).Error; err != nil || visit.ID == "" {
+	visit, err := h.store.OnSite.Visit(c, c.Param("id"))
+	if errors.Is(err, store.ErrNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load visit"})
+		return
+	}
 
-	// Rooms
-	type Room struct {
-		ID              string    `json:"id"              gorm:"column:id"`
-		OnSiteVisitID   string    `json:"onSiteVisitId"   gorm:"column:onSiteVisitId"`
-		Location        string    `json:"location"        gorm:"column:location"`
-		LocationTagID   *string   `json:"locationTagId"   gorm:"column:locationTagId"`
-		LightingIssue   string    `json:"lightingIssue"   gorm:"column:lightingIssue"`
-		CustomerRequest string    `json:"customerRequest" gorm:"column:customerRequest"`
-		MountingKitQty  string    `json:"mountingKitQty"  gorm:"column:mountingKitQty"`
-		MotionSensorQty int       `json:"motionSensorQty" gorm:"column:motionSensorQty"`
-		CreatedAt       time.Time `json:"createdAt"       gorm:"column:createdAt"`
-		CeilingHeight   *int      `json:"ceilingHeight"   gorm:"column:ceilingHeight"`
-		Existing        []any     `json:"existing"`  // fill below
-		Suggested       []any     `json:"suggested"` // fill below
-	}
-	var rooms []Room
-	if err := db.DB.Raw(
-		`SELECT "id","onSiteVisitId","location","locationTagId","lightingIssue","customerRequest",
-		        "mountingKitQty","motionSensorQty","createdAt","ceilingHeight"
-		   FROM "OnSiteVisitRoom"
-		  WHERE "onSiteVisitId" = ?
-		  ORDER BY "createdAt" DESC`,
-		visit.ID,
-	).Scan(&rooms).Error; err != nil {
+	rooms, err := h.store.OnSite.Rooms(c, visit.ID)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load rooms"})
 		return
 	}
 
-	// For each room, load existing + suggested
-	type ExistingRow struct {
-		ID            string  `json:"id"            gorm:"column:id"`
-		ProductID     string  `json:"productId"     gorm:"column:productId"`
-		ProductName   string  `json:"productName"   gorm:"column:productName"`
-		ProductWatt   float64 `json:"wattage"       gorm:"column:wattage"`
-		Quantity      int     `json:"quantity"      gorm:"column:quantity"`
-		BypassBallast bool    `json:"bypassBallast" gorm:"column:bypassBallast"`
-	}
-	type SuggestedRow struct {
-		ID        string   `json:"id"          gorm:"column:id"`
-		ProductID string   `json:"productId"   gorm:"column:productId"` // stores LightFixtureType.id
-		TypeName  string   `json:"typeName"    gorm:"column:typeName"`
-		SKU       *string  `json:"sku"         gorm:"column:SKU"`
-		ImageURL  *string  `json:"imageUrl"    gorm:"column:imageUrl"`
-		Wattage   *float64 `json:"wattage"     gorm:"column:wattage"`
-		Quantity  int      `json:"quantity"    gorm:"column:quantity"`
+	for i := range rooms {
+		h.signRoomPhotos(c, rooms[i].Photos)
 	}
 
-	for i := range rooms {
-		r := &rooms[i]
+	resp := gin.H{
+		"id":        visit.ID,
+		"caseId":    visit.CaseID,
+		"createdAt": visit.CreatedAt,
+	}
+	if groupBy == "" {
+		resp["rooms"] = rooms
+	} else {
+		resp["groups"] = groupByLocationTag(rooms)
+		resp["totals"] = fixtureTotalsOf(rooms)
+	}
+	c.JSON(http.StatusOK, resp)
+}
 
-		var ex []ExistingRow
-		if err := db.DB.Raw(
-			`SELECT e."id", e."productId", p."name" AS "productName", p."wattage",
-			        e."quantity", e."bypassBallast"
-			   FROM "OnSiteExistingProduct" e
-			   JOIN "Product" p ON p."id" = e."productId"
-			  WHERE e."roomId" = ?
-			  ORDER BY e."id"`,
-			r.ID,
-		).Scan(&ex).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load existing"})
-			return
-		}
+type fixtureTotals struct {
+	Rooms             int     `json:"rooms"`
+	ExistingQuantity  int     `json:"existingQuantity"`
+	ExistingWatts     float64 `json:"existingWatts"`
+	SuggestedQuantity int     `json:"suggestedQuantity"`
+	SuggestedWatts    float64 `json:"suggestedWatts"`
+}
 
-		var sg []SuggestedRow
-		if err := db.DB.Raw(
-			`SELECT s."id",
-					s."productId",                         -- this is LightFixtureType.id in your DB
-					l."name"        AS "typeName",
-					l."SKU",
-					l."imageUrl",
-					l."wattage",
-					s."quantity"
-			FROM "OnSiteSuggestedProduct" s
-			JOIN "LightFixtureType" l ON l."id" = s."productId"
-			WHERE s."roomId" = ?
-			ORDER BY s."id"`,
-			r.ID,
-		).Scan(&sg).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load suggested"})
-			return
+// fixtureTotalsOf sums fixture quantities and quantity × wattage over rooms.
+// Fixture types without a wattage count as 0 W.
+func fixtureTotalsOf(rooms []store.Room) fixtureTotals {
+	t := fixtureTotals{Rooms: len(rooms)}
+	for _, r := range rooms {
+		for _, e := range r.Existing {
+			t.ExistingQuantity += e.Quantity
+			t.ExistingWatts += float64(e.Quantity) * e.ProductWatt
+		}
+		for _, sg := range r.Suggested {
+			t.SuggestedQuantity += sg.Quantity
+			if sg.Wattage != nil {
+				t.SuggestedWatts += float64(sg.Quantity) * *sg.Wattage
+			}
 		}
+	}
+	return t
+}
+
+type roomGroup struct {
+	LocationTagID *string       `json:"locationTagId"`
+	LocationTag   *string       `json:"locationTag"`
+	Rooms         []store.Room  `json:"rooms"`
+	Subtotals     fixtureTotals `json:"subtotals"`
+}
 
-		// assign
-		r.Existing = make([]any, len(ex))
-		for i2 := range ex {
-			r.Existing[i2] = ex[i2]
+// groupByLocationTag buckets rooms by tag, keeping their order within each
+// group. Groups are sorted by tag name with untagged rooms last.
+func groupByLocationTag(rooms []store.Room) []roomGroup {
+	groups := []roomGroup{}
+	index := map[string]int{}
+	for _, r := range rooms {
+		key := ""
+		if r.LocationTagID != nil {
+			key = *r.LocationTagID
 		}
-		r.Suggested = make([]any, len(sg))
-		for i2 := range sg {
-			r.Suggested[i2] = sg[i2]
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, roomGroup{LocationTagID: r.LocationTagID, LocationTag: r.LocationTag})
 		}
+		groups[i].Rooms = append(groups[i].Rooms, r)
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"id":        visit.ID,
-		"caseId":    visit.CaseID,
-		"createdAt": visit.CreatedAt,
-		"rooms":     rooms,
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i].LocationTag, groups[j].LocationTag
+		if a == nil || b == nil {
+			return b == nil && a != nil
+		}
+		return strings.ToLower(*a) < strings.ToLower(*b)
 	})
+	for i := range groups {
+		groups[i].Subtotals = fixtureTotalsOf(groups[i].Rooms)
+	}
+	return groups
 }
 
 // -------------------- Rooms --------------------
@@ -182,70 +147,38 @@ type CreateRoomReq struct {
 }
 
 // POST /api/onsite/:visitId/rooms
+// Returns the complete room object with empty product arrays.
 func (h *Handlers) CreateRoom(c *gin.Context) {
-	visitID := c.Param("visitId")
 	var req CreateRoomReq
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Generate CUID
-	newID := cuid.New()
-
-	// Get full room data back
-	var row struct {
-		ID              string    `json:"id" gorm:"column:id"`
-		OnSiteVisitID   string    `json:"onSiteVisitId" gorm:"column:onSiteVisitId"`
-		Location        string    `json:"location" gorm:"column:location"`
-		LocationTagID   *string   `json:"locationTagId" gorm:"column:locationTagId"`
-		LightingIssue   string    `json:"lightingIssue" gorm:"column:lightingIssue"`
-		CustomerRequest string    `json:"customerRequest" gorm:"column:customerRequest"`
-		MountingKitQty  string    `json:"mountingKitQty" gorm:"column:mountingKitQty"`
-		MotionSensorQty int       `json:"motionSensorQty" gorm:"column:motionSensorQty"`
-		CreatedAt       time.Time `json:"createdAt" gorm:"column:createdAt"`
-		CeilingHeight   *int      `json:"ceilingHeight" gorm:"column:ceilingHeight"`
+	room, err := h.store.OnSite.CreateRoom(c, c.Param("visitId"), store.RoomInput{
+		Location:        req.Location,
+		LocationTagID:   req.LocationTagId,
+		LightingIssue:   req.LightingIssue,
+		CustomerRequest: req.CustomerRequest,
+		MountingKitQty:  req.MountingKitQty,
+		MotionSensorQty: req.MotionSensorQty,
+		CeilingHeight:   req.CeilingHeight,
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown locationTagId"})
+		return
 	}
-
-	err := db.DB.Raw(
-		`INSERT INTO "OnSiteVisitRoom"
-		 ("id","onSiteVisitId","location","locationTagId","lightingIssue","customerRequest",
-		  "mountingKitQty","motionSensorQty","createdAt","ceilingHeight")
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, now(), ?)
-		 RETURNING "id","onSiteVisitId","location","locationTagId","lightingIssue","customerRequest",
-		           "mountingKitQty","motionSensorQty","createdAt","ceilingHeight"`,
-		newID, visitID, req.Location, req.LocationTagId, req.LightingIssue, req.CustomerRequest,
-		req.MountingKitQty, req.MotionSensorQty, req.CeilingHeight,
-	).Scan(&row).Error
-
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "create failed"})
 		return
 	}
-
-	// Return complete room object with empty product arrays
-	c.JSON(http.StatusCreated, gin.H{
-		"id":              row.ID,
-		"onSiteVisitId":   row.OnSiteVisitID,
-		"location":        row.Location,
-		"locationTagId":   row.LocationTagID,
-		"lightingIssue":   row.LightingIssue,
-		"customerRequest": row.CustomerRequest,
-		"mountingKitQty":  row.MountingKitQty,
-		"motionSensorQty": row.MotionSensorQty,
-		"createdAt":       row.CreatedAt,
-		"ceilingHeight":   row.CeilingHeight,
-		"existing":        []any{},
-		"suggested":       []any{},
-	})
+	c.JSON(http.StatusCreated, room)
 }
 
-// PUT /api/rooms/:roomId
-func (h *Handlers) UpdateRoom(c *gin.Context) {
-	roomID := c.Param("roomId")
-
-	// Accept only known keys to avoid SQL injection
-	allowed := map[string]bool{
+// Columns PUT may change; other keys are ignored so request bodies never
+// name SQL identifiers.
+var (
+	roomFields = map[string]bool{
 		"location":        true,
 		"locationTagId":   true,
 		"lightingIssue":   true,
@@ -254,106 +187,69 @@ func (h *Handlers) UpdateRoom(c *gin.Context) {
 		"motionSensorQty": true,
 		"ceilingHeight":   true,
 	}
+	existingFields = map[string]bool{
+		"productId":     true,
+		"quantity":      true,
+		"bypassBallast": true,
+	}
+	suggestedFields = map[string]bool{
+		"productId": true,
+		"quantity":  true,
+	}
+)
 
+// bindPatch reads a JSON object and keeps only the allowed keys. It writes
+// the 400 response itself and returns ok=false when nothing usable remains.
+func bindPatch(c *gin.Context, allowed map[string]bool) (map[string]any, bool) {
 	var patch map[string]any
 	if err := c.ShouldBindJSON(&patch); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		return nil, false
 	}
-
-	set := []string{}
-	args := []any{}
+	set := map[string]any{}
 	for k, v := range patch {
 		if allowed[k] {
-			set = append(set, fmt.Sprintf(`"%s" = ?`, k))
-			args = append(args, v)
+			set[k] = v
 		}
 	}
 	if len(set) == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "no valid fields"})
-		return
+		return nil, false
 	}
-	args = append(args, roomID)
+	return set, true
+}
 
-	sql := `UPDATE "OnSiteVisitRoom" SET ` + strings.Join(set, ", ") + ` WHERE "id" = ?`
-	if err := db.DB.Exec(sql, args...).Error; err != nil {
+// PUT /api/rooms/:roomId
+func (h *Handlers) UpdateRoom(c *gin.Context) {
+	set, ok := bindPatch(c, roomFields)
+	if !ok {
+		return
+	}
+	err := h.store.OnSite.UpdateRoom(c, c.Param("roomId"), set)
+	if errors.Is(err, store.ErrNotFound) && set["locationTagId"] != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown locationTagId"})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
 		return
 	}
-
 	c.Status(http.StatusOK)
 }
 
 // DELETE /api/rooms/:roomId
-// DELETE /api/rooms/:roomId
-// DELETE /api/rooms/:roomId
+// Removes the room with its photos and fixtures in one transaction.
 func (h *Handlers) DeleteRoom(c *gin.Context) {
 	roomID := c.Param("roomId")
-
-	log.Printf("🗑️ Attempting to delete room: %s", roomID)
-
-	// Start a transaction
-	tx := db.DB.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-			log.Printf("❌ Panic during room deletion: %v", r)
-		}
-	}()
-
-	// 1. Delete photos
-	log.Printf("📸 Deleting photos for room: %s", roomID)
-	result := tx.Exec(`DELETE FROM "OnSiteVisitPhoto" WHERE "roomId" = ?`, roomID)
-	if result.Error != nil {
-		tx.Rollback()
-		log.Printf("❌ Failed to delete photos: %v", result.Error)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete photos: " + result.Error.Error()})
-		return
-	}
-	log.Printf("✅ Deleted %d photos", result.RowsAffected)
-
-	// 2. Delete suggested products
-	log.Printf("💡 Deleting suggested products for room: %s", roomID)
-	result = tx.Exec(`DELETE FROM "OnSiteSuggestedProduct" WHERE "roomId" = ?`, roomID)
-	if result.Error != nil {
-		tx.Rollback()
-		log.Printf("❌ Failed to delete suggested products: %v", result.Error)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete suggested products: " + result.Error.Error()})
-		return
-	}
-	log.Printf("✅ Deleted %d suggested products", result.RowsAffected)
-
-	// 3. Delete existing products
-	log.Printf("🔦 Deleting existing products for room: %s", roomID)
-	result = tx.Exec(`DELETE FROM "OnSiteExistingProduct" WHERE "roomId" = ?`, roomID)
-	if result.Error != nil {
-		tx.Rollback()
-		log.Printf("❌ Failed to delete existing products: %v", result.Error)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete existing products: " + result.Error.Error()})
-		return
-	}
-	log.Printf("✅ Deleted %d existing products", result.RowsAffected)
-
-	// 4. Delete the room
-	log.Printf("🏠 Deleting room: %s", roomID)
-	result = tx.Exec(`DELETE FROM "OnSiteVisitRoom" WHERE "id" = ?`, roomID)
-	if result.Error != nil {
-		tx.Rollback()
-		log.Printf("❌ Failed to delete room: %v", result.Error)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete room: " + result.Error.Error()})
+	urls, err := h.store.OnSite.DeleteRoom(c, roomID)
+	if err != nil {
+		log.Printf("delete room %s: %v", roomID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
 		return
 	}
-	log.Printf("✅ Deleted room (rows affected: %d)", result.RowsAffected)
-
-	// Commit the transaction
-	log.Printf("💾 Committing transaction...")
-	if err := tx.Commit().Error; err != nil {
-		log.Printf("❌ Failed to commit transaction: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction: " + err.Error()})
-		return
+	for _, url := range urls {
+		h.discardPhoto(c, url)
 	}
-
-	log.Printf("✅ Room %s deleted successfully", roomID)
 	c.Status(http.StatusNoContent)
 }
 
@@ -367,61 +263,28 @@ type AddProductReq struct {
 
 // POST /api/rooms/:roomId/existing
 func (h *Handlers) AddExistingProduct(c *gin.Context) {
-	roomID := c.Param("roomId")
-
 	var req AddProductReq
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	bypass := false
-	if req.BypassBallast != nil {
-		bypass = *req.BypassBallast
-	}
+	bypass := req.BypassBallast != nil && *req.BypassBallast
 
-	var row struct {
-		ID string `gorm:"column:id"`
-	}
-	if err := db.DB.Raw(
-		`INSERT INTO "OnSiteExistingProduct" ("id","roomId","productId","quantity","bypassBallast")
-		 VALUES (gen_random_uuid()::text, ?, ?, ?, ?)
-		 RETURNING "id"`,
-		roomID, req.ProductID, req.Quantity, bypass,
-	).Scan(&row).Error; err != nil {
+	id, err := h.store.OnSite.AddExisting(c, c.Param("roomId"), req.ProductID, req.Quantity, bypass)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "create failed"})
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{"id": row.ID})
+	c.JSON(http.StatusCreated, gin.H{"id": id})
 }
 
 // PUT /api/existing/:id
 func (h *Handlers) UpdateExistingProduct(c *gin.Context) {
-	id := c.Param("id")
-	allowed := map[string]bool{
-		"productId":     true,
-		"quantity":      true,
-		"bypassBallast": true,
-	}
-	var patch map[string]any
-	if err := c.ShouldBindJSON(&patch); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	set, ok := bindPatch(c, existingFields)
+	if !ok {
 		return
 	}
-	set := []string{}
-	args := []any{}
-	for k, v := range patch {
-		if allowed[k] {
-			set = append(set, fmt.Sprintf(`"%s" = ?`, k))
-			args = append(args, v)
-		}
-	}
-	if len(set) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "no valid fields"})
-		return
-	}
-	args = append(args, id)
-	sql := `UPDATE "OnSiteExistingProduct" SET ` + strings.Join(set, ", ") + ` WHERE "id" = ?`
-	if err := db.DB.Exec(sql, args...).Error; err != nil {
+	if err := h.store.OnSite.UpdateExisting(c, c.Param("id"), set); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
 		return
 	}
@@ -430,8 +293,7 @@ func (h *Handlers) UpdateExistingProduct(c *gin.Context) {
 
 // DELETE /api/existing/:id
 func (h *Handlers) DeleteExistingProduct(c *gin.Context) {
-	id := c.Param("id")
-	if err := db.DB.Exec(`DELETE FROM "OnSiteExistingProduct" WHERE "id" = ?`, id).Error; err != nil {
+	if err := h.store.OnSite.DeleteExisting(c, c.Param("id")); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
 		return
 	}
@@ -442,56 +304,27 @@ func (h *Handlers) DeleteExistingProduct(c *gin.Context) {
 
 // POST /api/rooms/:roomId/suggested
 func (h *Handlers) AddSuggestedProduct(c *gin.Context) {
-	roomID := c.Param("roomId")
-
 	var req AddProductReq
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	var row struct {
-		ID string `gorm:"column:id"`
-	}
-	if err := db.DB.Raw(
-		`INSERT INTO "OnSiteSuggestedProduct" ("id","roomId","productId","quantity")
-		 VALUES (gen_random_uuid()::text, ?, ?, ?)
-		 RETURNING "id"`,
-		roomID, req.ProductID, req.Quantity,
-	).Scan(&row).Error; err != nil {
+	id, err := h.store.OnSite.AddSuggested(c, c.Param("roomId"), req.ProductID, req.Quantity)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "create failed"})
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{"id": row.ID})
+	c.JSON(http.StatusCreated, gin.H{"id": id})
 }
 
 // PUT /api/suggested/:id
 func (h *Handlers) UpdateSuggestedProduct(c *gin.Context) {
-	id := c.Param("id")
-	allowed := map[string]bool{
-		"productId": true,
-		"quantity":  true,
-	}
-	var patch map[string]any
-	if err := c.ShouldBindJSON(&patch); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-	set := []string{}
-	args := []any{}
-	for k, v := range patch {
-		if allowed[k] {
-			set = append(set, fmt.Sprintf(`"%s" = ?`, k))
-			args = append(args, v)
-		}
-	}
-	if len(set) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "no valid fields"})
+	set, ok := bindPatch(c, suggestedFields)
+	if !ok {
 		return
 	}
-	args = append(args, id)
-	sql := `UPDATE "OnSiteSuggestedProduct" SET ` + strings.Join(set, ", ") + ` WHERE "id" = ?`
-	if err := db.DB.Exec(sql, args...).Error; err != nil {
+	if err := h.store.OnSite.UpdateSuggested(c, c.Param("id"), set); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
 		return
 	}
@@ -500,8 +333,7 @@ func (h *Handlers) UpdateSuggestedProduct(c *gin.Context) {
 
 // DELETE /api/suggested/:id
 func (h *Handlers) DeleteSuggestedProduct(c *gin.Context) {
-	id := c.Param("id")
-	if err := db.DB.Exec(`DELETE FROM "OnSiteSuggestedProduct" WHERE "id" = ?`, id).Error; err != nil {
+	if err := h.store.OnSite.DeleteSuggested(c, c.Param("id")); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
 		return
 	}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rick/go-neon-api/internal/auth"
+	"github.com/rick/go-neon-api/internal/models"
+	"github.com/rick/go-neon-api/internal/store"
+)
+
+// Customer upload portal: a school contact holding a case's upload token can
+// add photos and documents without an account. Files land on the case with
+// uploadedViaLink set and are logged under the case owner.
+
+// Sniffed content types the portal accepts.
+var (
+	portalPhotoTypes    = []string{"image/gif", "image/jpeg", "image/png", "image/webp"}
+	portalDocumentTypes = []string{"application/pdf", "image/gif", "image/jpeg", "image/png", "image/webp"}
+)
+
+// portalLink resolves the :token param. Unknown and expired tokens get the
+// same 404 so the response does not reveal which tokens once existed.
+func (h *Handlers) portalLink(c *gin.Context) (*store.UploadLink, bool) {
+	link, err := h.store.Cases.ByUploadToken(c, c.Param("token"))
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "invalid or expired upload link"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "lookup failed"})
+		return nil, false
+	}
+	return link, true
+}
+
+// GET /api/upload/:token
+// What the upload page needs to render: whose case it is and the limits.
+func (h *Handlers) GetUploadPortal(c *gin.Context) {
+	link, ok := h.portalLink(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"customerName":   link.CustomerName,
+		"schoolName":     link.SchoolName,
+		"expiresAt":      link.ExpiresAt,
+		"maxUploadBytes": h.uploads.MaxUploadBytes,
+		"photoTypes":     portalPhotoTypes,
+		"documentTypes":  portalDocumentTypes,
+	})
+}
+
+// POST /api/upload/:token/photos
+// Multipart: file, comment.
+func (h *Handlers) PortalUploadPhoto(c *gin.Context) {
+	link, ok := h.portalLink(c)
+	if !ok {
+		return
+	}
+	up, ok := h.receiveUpload(c, "cases/"+link.CaseID+"/photos", portalPhotoTypes)
+	if !ok {
+		return
+	}
+	meta := h.processPhoto(c, up)
+	item := models.Photo{
+		URL:             up.Key,
+		CaseID:          link.CaseID,
+		UploadedViaLink: true,
+		Comment:         formString(c, "comment"),
+		ThumbnailURL:    meta.ThumbnailURL,
+		TakenAt:         meta.TakenAt,
+		Latitude:        meta.Latitude,
+		Longitude:       meta.Longitude,
+	}
+	if err := h.store.Files.AddPhoto(c, link.OwnerID, &item); err != nil {
+		h.discardPhoto(c, item.URL)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "upload failed"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"id": item.ID, "createdAt": item.CreatedAt})
+}
+
+// POST /api/upload/:token/documents
+// Multipart: file, comment. The document is named after the uploaded file.
+func (h *Handlers) PortalUploadDocument(c *gin.Context) {
+	link, ok := h.portalLink(c)
+	if !ok {
+		return
+	}
+	up, ok := h.receiveUpload(c, "cases/"+link.CaseID+"/documents", portalDocumentTypes)
+	if !ok {
+		return
+	}
+	item := models.Document{
+		URL:             up.Key,
+		FileName:        up.FileName,
+		CaseID:          link.CaseID,
+		UploadedViaLink: true,
+		Comment:         formString(c, "comment"),
+	}
+	if err := h.store.Files.AddDocument(c, link.OwnerID, &item); err != nil {
+		h.discardObject(c, item.URL)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "upload failed"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"id": item.ID, "fileName": item.FileName, "createdAt": item.CreatedAt})
+}
+
+// -------------------- Staff: managing the link --------------------
+
+func uploadLinkResp(link *store.UploadLink) gin.H {
+	return gin.H{
+		"token":     link.Token,
+		"path":      "/api/upload/" + link.Token,
+		"expiresAt": link.ExpiresAt,
+		"expired":   link.Expired(time.Now()),
+	}
+}
+
+// GET /api/cases/:id/upload-link
+func (h *Handlers) GetUploadLink(c *gin.Context) {
+	link, err := h.store.Cases.UploadLink(c, c.Param("id"))
+	if err != nil {
+		fileError(c, err, "lookup")
+		return
+	}
+	c.JSON(http.StatusOK, uploadLinkResp(link))
+}
+
+type RotateUploadLinkReq struct {
+	ExpiresInDays *int `json:"expiresInDays" binding:"omitempty,gte=1,lte=365"` // omit for no expiry
+}
+
+// POST /api/cases/:id/upload-link
+// Issues a new token; the previous link stops working immediately.
+func (h *Handlers) RotateUploadLink(c *gin.Context) {
+	var req RotateUploadLinkReq
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	var expiresAt *time.Time
+	if req.ExpiresInDays != nil {
+		t := time.Now().Add(time.Duration(*req.ExpiresInDays) * 24 * time.Hour)
+		expiresAt = &t
+	}
+	link, err := h.store.Cases.RotateUploadToken(c, auth.CurrentUser(c).ID, c.Param("id"), expiresAt)
+	if err != nil {
+		fileError(c, err, "rotate")
+		return
+	}
+	c.JSON(http.StatusOK, uploadLinkResp(link))
+}
+
+// DELETE /api/cases/:id/upload-link
+// Expires the current token. Rotating issues a working one again.
+func (h *Handlers) ExpireUploadLink(c *gin.Context) {
+	if err := h.store.Cases.ExpireUploadToken(c, auth.CurrentUser(c).ID, c.Param("id")); err != nil {
+		fileError(c, err, "expire")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -1,115 +1,355 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/rick/go-neon-api/internal/db"
+	"github.com/rick/go-neon-api/internal/auth"
+	"github.com/rick/go-neon-api/internal/pagination"
+	"github.com/rick/go-neon-api/internal/store"
 )
 
 // ---------- GET /api/products ----------
 // For EXISTING lighting picker (joins use "Product")
+// Archived products are left out unless ?includeArchived=true.
 func (h *Handlers) ListProducts(c *gin.Context) {
-	q := strings.TrimSpace(c.Query("q"))
-	category := strings.TrimSpace(c.Query("category"))
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	if page < 1 {
-		page = 1
-	}
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
-	if limit < 1 || limit > 200 {
-		limit = 50
-	}
-	offset := (page - 1) * limit
-
-	type Row struct {
-		ID          string  `json:"id"          gorm:"column:id"`
-		Name        string  `json:"name"        gorm:"column:name"`
-		Wattage     float64 `json:"wattage"     gorm:"column:wattage"`
-		Category    *string `json:"category"    gorm:"column:category"`
-		Description *string `json:"description" gorm:"column:description2"`
-	}
-
-	where := []string{}
-	args := []any{}
-	if q != "" {
-		where = append(where, `("name" ILIKE ? OR "description2" ILIKE ?)`)
-		args = append(args, "%"+q+"%", "%"+q+"%")
-	}
-	if category != "" {
-		where = append(where, `"category" = ?`)
-		args = append(args, category)
-	}
-	whereSQL := ""
-	if len(where) > 0 {
-		whereSQL = "WHERE " + strings.Join(where, " AND ")
-	}
-
-	var rows []Row
-	sql := `
-		SELECT "id","name","wattage","category","description2"
-		FROM "Product"
-		` + whereSQL + `
-		ORDER BY "name" ASC
-		LIMIT ? OFFSET ?`
-	args = append(args, limit, offset)
-
-	if err := db.DB.Raw(sql, args...).Scan(&rows).Error; err != nil {
+	p, err := pagination.Parse(c, 50, 200)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	archived, ok := queryBool(c, "includeArchived")
+	if !ok {
+		return
+	}
+	page, err := h.store.Catalog.ListProducts(c, store.ProductFilter{
+		Q:               strings.TrimSpace(c.Query("q")),
+		Category:        strings.TrimSpace(c.Query("category")),
+		IncludeArchived: archived,
+	}, p)
+	if errors.Is(err, pagination.ErrBadCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "list failed"})
 		return
 	}
-	c.JSON(http.StatusOK, rows)
+	c.JSON(http.StatusOK, page)
 }
 
 // ---------- GET /api/lightfixturetypes ----------
 // For SUGGESTED lighting picker (joins use "LightFixtureType")
+// Archived types are left out unless ?includeArchived=true.
 func (h *Handlers) ListLightFixtureTypes(c *gin.Context) {
-	q := strings.TrimSpace(c.Query("q"))
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	if page < 1 {
-		page = 1
-	}
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
-	if limit < 1 || limit > 200 {
-		limit = 50
-	}
-	offset := (page - 1) * limit
-
-	type Row struct {
-		ID          string   `json:"id"          gorm:"column:id"`
-		Name        string   `json:"name"        gorm:"column:name"`
-		SKU         *string  `json:"sku"         gorm:"column:SKU"`
-		Wattage     *float64 `json:"wattage"     gorm:"column:wattage"`
-		ImageURL    *string  `json:"imageUrl"    gorm:"column:imageUrl"`
-		Description *string  `json:"description" gorm:"column:description"`
-		// If you later add categories/tags to fixture types, you can filter here too.
-	}
-
-	where := []string{}
-	args := []any{}
-	if q != "" {
-		where = append(where, `("name" ILIKE ? OR "description" ILIKE ? OR "SKU" ILIKE ?)`)
-		args = append(args, "%"+q+"%", "%"+q+"%", "%"+q+"%")
-	}
-	whereSQL := ""
-	if len(where) > 0 {
-		whereSQL = "WHERE " + strings.Join(where, " AND ")
-	}
-
-	var rows []Row
-	sql := `
-		SELECT "id","name","SKU","wattage","imageUrl","description"
-		FROM "LightFixtureType"
-		` + whereSQL + `
-		ORDER BY "name" ASC
-		LIMIT ? OFFSET ?`
-	args = append(args, limit, offset)
-
-	if err := db.DB.Raw(sql, args...).Scan(&rows).Error; err != nil {
+	p, err := pagination.Parse(c, 50, 200)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	archived, ok := queryBool(c, "includeArchived")
+	if !ok {
+		return
+	}
+	page, err := h.store.Catalog.ListFixtureTypes(c, store.FixtureTypeFilter{
+		Q:               strings.TrimSpace(c.Query("q")),
+		IncludeArchived: archived,
+	}, p)
+	if errors.Is(err, pagination.ErrBadCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list failed"})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// queryBool reads an optional boolean query param, false when absent. It
+// writes the 400 response itself.
+func queryBool(c *gin.Context, key string) (bool, bool) {
+	v := c.Query(key)
+	if v == "" {
+		return false, true
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": key + " must be true or false"})
+		return false, false
+	}
+	return b, true
+}
+
+// -------------------- Catalog administration (ADMIN) --------------------
+//
+// Create and PUT take the full row; PUT replaces every editable field.
+// Catalog rows are referenced by past visits, so DELETE of a row still in use
+// archives it instead and answers 200 {"archived": true}; a row nothing uses
+// is removed with 204.
+
+const maxCatalogName = 128
+
+type ProductReq struct {
+	Name        string   `json:"name" binding:"required"`
+	Wattage     *float64 `json:"wattage" binding:"required,gte=0"`
+	Category    *string  `json:"category"`
+	Description *string  `json:"description"`
+	SKU         *string  `json:"sku"`
+}
+
+type FixtureTypeReq struct {
+	Name        string   `json:"name" binding:"required"`
+	Wattage     *float64 `json:"wattage" binding:"omitempty,gte=0"`
+	Description *string  `json:"description"`
+	SKU         *string  `json:"sku"`
+	ImageURL    *string  `json:"imageUrl"`
+	UnitCost    *float64 `json:"unitCost" binding:"omitempty,gte=0"`
+	ListPrice   *float64 `json:"listPrice" binding:"omitempty,gte=0"`
+	LaborHours  *float64 `json:"laborHours" binding:"omitempty,gte=0"`
+}
+
+// catalogName trims name and checks its length, writing the 400 itself.
+func catalogName(c *gin.Context, name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxCatalogName {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must be 1-128 characters"})
+		return "", false
+	}
+	return name, true
+}
+
+// optionalText trims s; blank is stored as NULL.
+func optionalText(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	t := strings.TrimSpace(*s)
+	if t == "" {
+		return nil
+	}
+	return &t
+}
+
+func bindProduct(c *gin.Context) (store.ProductInput, bool) {
+	var req ProductReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return store.ProductInput{}, false
+	}
+	name, ok := catalogName(c, req.Name)
+	if !ok {
+		return store.ProductInput{}, false
+	}
+	return store.ProductInput{
+		Name:        name,
+		Wattage:     *req.Wattage,
+		Category:    optionalText(req.Category),
+		Description: optionalText(req.Description),
+		SKU:         optionalText(req.SKU),
+	}, true
+}
+
+func bindFixtureType(c *gin.Context) (store.FixtureTypeInput, bool) {
+	var req FixtureTypeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return store.FixtureTypeInput{}, false
+	}
+	name, ok := catalogName(c, req.Name)
+	if !ok {
+		return store.FixtureTypeInput{}, false
+	}
+	in := store.FixtureTypeInput{
+		Name:        name,
+		Wattage:     req.Wattage,
+		Description: optionalText(req.Description),
+		SKU:         optionalText(req.SKU),
+		ImageURL:    optionalText(req.ImageURL),
+		Pricing:     store.Pricing{UnitCost: req.UnitCost, ListPrice: req.ListPrice, LaborHours: req.LaborHours},
+	}
+	if in.ImageURL != nil && !isExternalURL(*in.ImageURL) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "imageUrl must be an absolute http(s) URL"})
+		return store.FixtureTypeInput{}, false
+	}
+	return in, true
+}
+
+// catalogWrite answers a create, update or archive with the row.
+func catalogWrite[T any](c *gin.Context, status int, row *T, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, store.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "name or SKU already in use"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "save failed"})
+	default:
+		c.JSON(status, row)
+	}
+}
+
+func catalogDelete(c *gin.Context, archived bool, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, store.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "in use"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+	case archived:
+		c.JSON(http.StatusOK, gin.H{"archived": true})
+	default:
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func requireAdmin(c *gin.Context) bool {
+	if !auth.IsAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return false
+	}
+	return true
+}
+
+// POST /api/products (ADMIN)
+func (h *Handlers) CreateProduct(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	in, ok := bindProduct(c)
+	if !ok {
+		return
+	}
+	row, err := h.store.Catalog.CreateProduct(c, in)
+	catalogWrite(c, http.StatusCreated, row, err)
+}
+
+// PUT /api/products/:id (ADMIN)
+func (h *Handlers) UpdateProduct(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	in, ok := bindProduct(c)
+	if !ok {
+		return
+	}
+	row, err := h.store.Catalog.UpdateProduct(c, c.Param("id"), in)
+	catalogWrite(c, http.StatusOK, row, err)
+}
+
+// POST /api/products/:id/archive and /restore (ADMIN)
+func (h *Handlers) ArchiveProduct(archived bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+		row, err := h.store.Catalog.ArchiveProduct(c, c.Param("id"), archived)
+		catalogWrite(c, http.StatusOK, row, err)
+	}
+}
+
+// DELETE /api/products/:id (ADMIN)
+// Archives instead while an existing-lighting line uses the product.
+func (h *Handlers) DeleteProduct(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	archived, err := h.store.Catalog.DeleteProduct(c, c.Param("id"))
+	catalogDelete(c, archived, err)
+}
+
+// POST /api/lightfixturetypes (ADMIN)
+func (h *Handlers) CreateLightFixtureType(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	in, ok := bindFixtureType(c)
+	if !ok {
+		return
+	}
+	row, err := h.store.Catalog.CreateFixtureType(c, in)
+	catalogWrite(c, http.StatusCreated, row, err)
+}
+
+// PUT /api/lightfixturetypes/:id (ADMIN)
+func (h *Handlers) UpdateLightFixtureType(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	in, ok := bindFixtureType(c)
+	if !ok {
+		return
+	}
+	row, err := h.store.Catalog.UpdateFixtureType(c, c.Param("id"), in)
+	catalogWrite(c, http.StatusOK, row, err)
+}
+
+// POST /api/lightfixturetypes/:id/archive and /restore (ADMIN)
+func (h *Handlers) ArchiveLightFixtureType(archived bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+		row, err := h.store.Catalog.ArchiveFixtureType(c, c.Param("id"), archived)
+		catalogWrite(c, http.StatusOK, row, err)
+	}
+}
+
+// DELETE /api/lightfixturetypes/:id (ADMIN)
+// Archives instead while a suggested-lighting line or case fixture count
+// uses the type.
+func (h *Handlers) DeleteLightFixtureType(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	archived, err := h.store.Catalog.DeleteFixtureType(c, c.Param("id"))
+	catalogDelete(c, archived, err)
+}
+
+// -------------------- Accessories --------------------
+
+// GET /api/accessories
+// The priced items behind each room's mounting kit and motion sensor counts.
+func (h *Handlers) ListAccessories(c *gin.Context) {
+	rows, err := h.store.Catalog.Accessories(c)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "list failed"})
 		return
 	}
-	c.JSON(http.StatusOK, rows)
+	c.JSON(http.StatusOK, gin.H{"items": rows})
+}
+
+type AccessoryReq struct {
+	Name       string   `json:"name" binding:"required"`
+	SKU        *string  `json:"sku"`
+	UnitCost   *float64 `json:"unitCost" binding:"omitempty,gte=0"`
+	ListPrice  *float64 `json:"listPrice" binding:"omitempty,gte=0"`
+	LaborHours *float64 `json:"laborHours" binding:"omitempty,gte=0"`
+}
+
+// PUT /api/accessories/:kind (ADMIN)
+// Replaces the name, SKU and prices; kind is mountingKit or motionSensor.
+func (h *Handlers) UpdateAccessory(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	var req AccessoryReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name, ok := catalogName(c, req.Name)
+	if !ok {
+		return
+	}
+	row, err := h.store.Catalog.UpdateAccessory(c, c.Param("kind"), store.AccessoryInput{
+		Name:    name,
+		SKU:     optionalText(req.SKU),
+		Pricing: store.Pricing{UnitCost: req.UnitCost, ListPrice: req.ListPrice, LaborHours: req.LaborHours},
+	})
+	catalogWrite(c, http.StatusOK, row, err)
 }
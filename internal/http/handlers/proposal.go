@@ -0,0 +1,583 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rick/go-neon-api/internal/auth"
+	"github.com/rick/go-neon-api/internal/imaging"
+	"github.com/rick/go-neon-api/internal/models"
+	"github.com/rick/go-neon-api/internal/pdf"
+	"github.com/rick/go-neon-api/internal/storage"
+	"github.com/rick/go-neon-api/internal/store"
+)
+
+// Proposals are PDFs rendered from the case, its rooms, the bill of
+// materials and the savings report, and kept as one of the case's
+// documents. Each POST renders a new one; earlier proposals stay.
+
+// proposalBrand is how proposals are branded, read from the environment:
+//
+//	PROPOSAL_COMPANY     name in the header, default "Lighting Upgrade Proposal"
+//	PROPOSAL_CONTACT     one line under it and in the footer, e.g. phone and web
+//	PROPOSAL_COLOR       header colour as #rrggbb, default #1f4e79
+//	PROPOSAL_TERMS_FILE  plain-text terms replacing the defaults; blank lines
+//	                     separate paragraphs
+type proposalBrand struct {
+	Company string
+	Contact string
+	Color   pdf.Color
+	Terms   []string
+}
+
+var defaultProposalTerms = []string{
+	"This proposal is valid for 30 days from the date above.",
+	"Prices are in US dollars and exclude applicable taxes unless stated otherwise.",
+	"Energy and cost savings are estimates based on the operating hours and electricity rate shown. Actual savings depend on use and on utility tariffs.",
+	"Installation is scheduled on acceptance, subject to site access and product availability.",
+	"Fixtures and lamps removed during installation are recycled or disposed of according to local regulations.",
+}
+
+func mustLoadProposalBrand() proposalBrand {
+	b := proposalBrand{
+		Company: "Lighting Upgrade Proposal",
+		Contact: os.Getenv("PROPOSAL_CONTACT"),
+		Color:   pdf.Color{R: 0x1f, G: 0x4e, B: 0x79},
+		Terms:   defaultProposalTerms,
+	}
+	if v := os.Getenv("PROPOSAL_COMPANY"); v != "" {
+		b.Company = v
+	}
+	if v := os.Getenv("PROPOSAL_COLOR"); v != "" {
+		c, err := pdf.ParseColor(v)
+		if err != nil {
+			log.Fatalf("invalid PROPOSAL_COLOR %q", v)
+		}
+		b.Color = c
+	}
+	if path := os.Getenv("PROPOSAL_TERMS_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatalf("read PROPOSAL_TERMS_FILE: %v", err)
+		}
+		b.Terms = nil
+		for _, para := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n\n") {
+			if para = strings.TrimSpace(para); para != "" {
+				b.Terms = append(b.Terms, strings.Join(strings.Fields(para), " "))
+			}
+		}
+	}
+	return b
+}
+
+// POST /api/cases/:id/proposal
+// Renders the proposal and stores it as a case document, returned like
+// POST /documents returns one.
+func (h *Handlers) CreateProposal(c *gin.Context) {
+	id := c.Param("id")
+	head, err := h.store.Cases.Get(c, id)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load case"})
+		return
+	}
+	rooms, ok := h.caseRooms(c, id)
+	if !ok {
+		return
+	}
+	savings, ok := h.caseSavings(c, id, rooms)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	p := &proposalWriter{doc: pdf.New(), brand: h.proposal}
+	p.render(head, rooms, savings, h.fixtureImages(c, rooms), now)
+	var buf bytes.Buffer
+	if _, err := p.doc.WriteTo(&buf); err != nil {
+		log.Printf("case %s proposal: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render proposal"})
+		return
+	}
+
+	key := storage.NewKey("cases/"+id+"/documents", "application/pdf")
+	if err := h.files.Put(c, key, &buf, int64(buf.Len()), "application/pdf"); err != nil {
+		log.Printf("storage put %s: %v", key, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store proposal"})
+		return
+	}
+	name := head.SchoolName
+	if name == "" {
+		name = head.CustomerName
+	}
+	item := models.Document{
+		URL:      key,
+		FileName: archiveSegment(fmt.Sprintf("Proposal %s %s.pdf", name, now.Format("2006-01-02")), "Proposal.pdf"),
+		CaseID:   id,
+	}
+	if err := h.store.Files.AddDocument(c, auth.CurrentUser(c).ID, &item); err != nil {
+		h.discardObject(c, key)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create failed"})
+		return
+	}
+	c.JSON(http.StatusCreated, documentResp{item, h.downloadURL(c, item.URL)})
+}
+
+// maxFixtureImage bounds a fixture image fetched for a proposal.
+const maxFixtureImage = 5 << 20
+
+// fixtureImages loads the image of each suggested fixture type, keyed by
+// type id. Images that cannot be loaded are logged and left out; the
+// proposal is still useful without them.
+func (h *Handlers) fixtureImages(c *gin.Context, rooms []store.Room) map[string]*pdf.Image {
+	out := map[string]*pdf.Image{}
+	tried := map[string]bool{}
+	for _, r := range rooms {
+		for _, s := range r.Suggested {
+			if s.ImageURL == nil || tried[s.ProductID] {
+				continue
+			}
+			tried[s.ProductID] = true
+			data, err := h.readFixtureImage(c, *s.ImageURL)
+			if err == nil {
+				data, err = imaging.Thumbnail(data, 300, 0)
+			}
+			var img *pdf.Image
+			if err == nil {
+				img, err = pdf.NewImage(data)
+			}
+			if err != nil {
+				log.Printf("proposal image %s: %v", *s.ImageURL, err)
+				continue
+			}
+			out[s.ProductID] = img
+		}
+	}
+	return out
+}
+
+func (h *Handlers) readFixtureImage(c *gin.Context, stored string) ([]byte, error) {
+	var body io.ReadCloser
+	if storage.IsKey(stored) {
+		rc, err := h.files.Open(c, stored)
+		if err != nil {
+			return nil, err
+		}
+		body = rc
+	} else {
+		req, err := http.NewRequestWithContext(c, http.MethodGet, stored, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := imageClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("status %s", resp.Status)
+		}
+		body = resp.Body
+	}
+	defer body.Close()
+	data, err := io.ReadAll(io.LimitReader(body, maxFixtureImage+1))
+	if err == nil && len(data) > maxFixtureImage {
+		err = fmt.Errorf("larger than %d MB", maxFixtureImage>>20)
+	}
+	return data, err
+}
+
+// imageClient fetches catalog image URLs. Only admins set them, but the
+// dialer still refuses loopback, private and link-local addresses so a URL
+// cannot point the server at internal services.
+var imageClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{Timeout: 5 * time.Second, Control: publicAddressOnly}).DialContext,
+	},
+}
+
+func publicAddressOnly(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("address %s not allowed", host)
+	}
+	return nil
+}
+
+// ---------- Layout ----------
+
+const (
+	propMargin   = 48.0
+	propWidth    = pdf.PageWidth - 2*propMargin
+	propTop      = 104.0 // first line below the header band
+	propBottom   = pdf.PageHeight - 60
+	propBodySize = 10.0
+	propLine     = 14.0
+)
+
+var (
+	propMuted  = pdf.Color{R: 0x60, G: 0x60, B: 0x60}
+	propRule   = pdf.Color{R: 0xc8, G: 0xc8, B: 0xc8}
+	propShade  = pdf.Color{R: 0xee, G: 0xf1, B: 0xf5}
+	propStripe = pdf.Color{R: 0xf8, G: 0xf9, B: 0xfa}
+)
+
+// proposalWriter lays content out top to bottom, starting a new page when
+// the next block does not fit.
+type proposalWriter struct {
+	doc   *pdf.Document
+	brand proposalBrand
+	y     float64
+}
+
+func (p *proposalWriter) render(head *store.CaseDetail, rooms []store.Room, s *savingsReport, images map[string]*pdf.Image, now time.Time) {
+	school := head.SchoolName
+	if school == "" {
+		school = head.CustomerName
+	}
+	p.doc.Title = "Lighting upgrade proposal - " + school
+	p.newPage()
+
+	p.doc.Text(propMargin, p.y, pdf.HelveticaBold, 20, pdf.Black, "Lighting upgrade proposal")
+	p.y += 18
+	p.doc.Text(propMargin, p.y, pdf.Helvetica, 12, propMuted, school)
+	p.y += 8
+
+	p.heading("Prepared for")
+	prepared := [][2]string{
+		{"School", head.SchoolName},
+		{"Customer", head.CustomerName},
+		{"Contact", head.ContactPerson},
+		{"Email", head.EmailAddress},
+		{"Phone", head.PhoneNumber},
+		{"Address", head.SchoolAddress},
+	}
+	by := head.UserEmail
+	if head.UserName != nil && *head.UserName != "" {
+		by = *head.UserName + " (" + head.UserEmail + ")"
+	}
+	prepared = append(prepared, [2]string{"Prepared by", by}, [2]string{"Date", now.Format("January 2, 2006")}, [2]string{"Reference", head.ID})
+	p.fields(prepared)
+
+	p.heading("Room by room")
+	p.roomTable(rooms, s)
+
+	p.heading("Proposed fixtures")
+	p.fixtures(rooms, images)
+
+	p.heading("Savings and payback")
+	p.savings(s)
+
+	p.heading("Terms")
+	for i, t := range p.brand.Terms {
+		p.paragraph(fmt.Sprintf("%d. %s", i+1, t), propMuted)
+		p.y += 4
+	}
+
+	p.footers(now)
+}
+
+func (p *proposalWriter) newPage() {
+	p.doc.AddPage()
+	p.doc.Rect(0, 0, pdf.PageWidth, 64, p.brand.Color)
+	p.doc.Text(propMargin, 34, pdf.HelveticaBold, 18, pdf.White, p.brand.Company)
+	if p.brand.Contact != "" {
+		p.doc.Text(propMargin, 50, pdf.Helvetica, 9, pdf.White, p.brand.Contact)
+	}
+	p.y = propTop
+}
+
+// need starts a new page unless h more points fit on this one.
+func (p *proposalWriter) need(h float64) {
+	if p.y+h > propBottom {
+		p.newPage()
+	}
+}
+
+func (p *proposalWriter) heading(s string) {
+	p.need(60) // keep a heading with the start of its section
+	p.y += 24
+	p.doc.Text(propMargin, p.y, pdf.HelveticaBold, 13, p.brand.Color, s)
+	p.y += 6
+	p.doc.Line(propMargin, p.y, propMargin+propWidth, p.y, 0.75, p.brand.Color)
+	p.y += 16
+}
+
+func (p *proposalWriter) paragraph(s string, c pdf.Color) {
+	for _, line := range pdf.Wrap(pdf.Helvetica, propBodySize, propWidth, s) {
+		p.need(propLine)
+		p.doc.Text(propMargin, p.y, pdf.Helvetica, propBodySize, c, line)
+		p.y += propLine
+	}
+}
+
+// fields lists label/value pairs, skipping blank values.
+func (p *proposalWriter) fields(pairs [][2]string) {
+	const labelWidth = 110
+	for _, kv := range pairs {
+		if strings.TrimSpace(kv[1]) == "" {
+			continue
+		}
+		lines := pdf.Wrap(pdf.Helvetica, propBodySize, propWidth-labelWidth, kv[1])
+		p.need(float64(len(lines)) * propLine)
+		p.doc.Text(propMargin, p.y, pdf.HelveticaBold, propBodySize, propMuted, kv[0])
+		for _, l := range lines {
+			p.doc.Text(propMargin+labelWidth, p.y, pdf.Helvetica, propBodySize, pdf.Black, l)
+			p.y += propLine
+		}
+	}
+}
+
+type propColumn struct {
+	title   string
+	width   float64
+	numeric bool // right-aligned
+}
+
+// table draws rows under a shaded header that is repeated on each page the
+// table continues onto. Cells wrap; a row is never split across pages.
+// The last row is set in bold when bold is true.
+func (p *proposalWriter) table(cols []propColumn, rows [][]string, bold bool) {
+	const pad, size, line = 4.0, 9.0, 12.0
+	header := func() {
+		p.doc.Rect(propMargin, p.y, propWidth, line+2*pad, propShade)
+		x := propMargin
+		for _, col := range cols {
+			if col.numeric {
+				p.doc.TextRight(x+col.width-pad, p.y+pad+size, pdf.HelveticaBold, size, pdf.Black, col.title)
+			} else {
+				p.doc.Text(x+pad, p.y+pad+size, pdf.HelveticaBold, size, pdf.Black, col.title)
+			}
+			x += col.width
+		}
+		p.y += line + 2*pad
+	}
+	p.need(2 * (line + 2*pad))
+	header()
+	for i, row := range rows {
+		font := pdf.Helvetica
+		if bold && i == len(rows)-1 {
+			font = pdf.HelveticaBold
+		}
+		cells := make([][]string, len(cols))
+		height := 0.0
+		for j, col := range cols {
+			cells[j] = pdf.Wrap(font, size, col.width-2*pad, row[j])
+			height = max(height, float64(len(cells[j]))*line+2*pad)
+		}
+		if p.y+height > propBottom {
+			p.newPage()
+			header()
+		}
+		if i%2 == 1 {
+			p.doc.Rect(propMargin, p.y, propWidth, height, propStripe)
+		}
+		x := propMargin
+		for j, col := range cols {
+			for k, l := range cells[j] {
+				y := p.y + pad + size + float64(k)*line
+				if col.numeric {
+					p.doc.TextRight(x+col.width-pad, y, font, size, pdf.Black, l)
+				} else {
+					p.doc.Text(x+pad, y, font, size, pdf.Black, l)
+				}
+			}
+			x += col.width
+		}
+		p.y += height
+		p.doc.Line(propMargin, p.y, propMargin+propWidth, p.y, 0.5, propRule)
+	}
+}
+
+func (p *proposalWriter) roomTable(rooms []store.Room, s *savingsReport) {
+	if len(rooms) == 0 {
+		p.paragraph("No rooms have been surveyed yet.", propMuted)
+		return
+	}
+	cols := []propColumn{
+		{"Room", 96, false},
+		{"Existing", 140, false},
+		{"Proposed", 140, false},
+		{"Existing W", 46, true},
+		{"Proposed W", 46, true},
+		{"kWh saved / yr", 48, true},
+	}
+	rows := make([][]string, 0, len(rooms)+1)
+	for i, r := range rooms {
+		var existing, proposed []string
+		for _, l := range r.Existing {
+			existing = append(existing, fmt.Sprintf("%d × %s (%s W)", l.Quantity, l.ProductName, formatNumber(l.ProductWatt, -1)))
+		}
+		for _, l := range r.Suggested {
+			item := fmt.Sprintf("%d × %s", l.Quantity, l.TypeName)
+			if l.Wattage != nil {
+				item += fmt.Sprintf(" (%s W)", formatNumber(*l.Wattage, -1))
+			}
+			proposed = append(proposed, item)
+		}
+		f := s.Rooms[i]
+		rows = append(rows, []string{
+			r.Location, orDash(strings.Join(existing, "\n")), orDash(strings.Join(proposed, "\n")),
+			formatNumber(f.ExistingWatts, -1), formatNumber(f.ProposedWatts, -1), formatNumber(f.AnnualKWhSaved, 0),
+		})
+	}
+	t := s.Totals
+	rows = append(rows, []string{"Total", "", "",
+		formatNumber(t.ExistingWatts, -1), formatNumber(t.ProposedWatts, -1), formatNumber(t.AnnualKWhSaved, 0)})
+	p.table(cols, rows, true)
+}
+
+// fixtures lists each proposed fixture type once, with its image.
+func (p *proposalWriter) fixtures(rooms []store.Room, images map[string]*pdf.Image) {
+	const box = 72.0
+	var order []string
+	lines := map[string]*store.SuggestedLine{}
+	qty := map[string]int{}
+	for _, r := range rooms {
+		for _, s := range r.Suggested {
+			if _, ok := lines[s.ProductID]; !ok {
+				order = append(order, s.ProductID)
+				lines[s.ProductID] = &s
+			}
+			qty[s.ProductID] += s.Quantity
+		}
+	}
+	if len(order) == 0 {
+		p.paragraph("No replacement fixtures have been selected yet.", propMuted)
+		return
+	}
+	for _, id := range order {
+		l := lines[id]
+		p.need(box + 12)
+		top := p.y
+		textX := propMargin
+		if img := images[id]; img != nil {
+			// Fit the image inside the box, keeping its aspect ratio.
+			scale := box / float64(max(img.Width, img.Height))
+			w, h := float64(img.Width)*scale, float64(img.Height)*scale
+			p.doc.Image(img, propMargin+(box-w)/2, top+(box-h)/2, w, h)
+		}
+		textX += box + 16
+		y := top + 14
+		p.doc.Text(textX, y, pdf.HelveticaBold, 11, pdf.Black, l.TypeName)
+		details := []string{fmt.Sprintf("Quantity: %d", qty[id])}
+		if l.SKU != nil {
+			details = append(details, "SKU: "+*l.SKU)
+		}
+		if l.Wattage != nil {
+			details = append(details, "Wattage: "+formatNumber(*l.Wattage, -1)+" W")
+		}
+		if l.ListPrice != nil {
+			details = append(details, "Unit price: "+formatMoney(*l.ListPrice))
+		}
+		for _, d := range details {
+			y += propLine
+			p.doc.Text(textX, y, pdf.Helvetica, propBodySize, propMuted, d)
+		}
+		p.y = max(top+box, y) + 12
+	}
+}
+
+func (p *proposalWriter) savings(s *savingsReport) {
+	t := s.Totals
+	hours := "Not provided"
+	if s.AnnualHours > 0 {
+		hours = fmt.Sprintf("%s hours a year (%d days × %d hours)", formatNumber(float64(s.AnnualHours), 0), s.DaysPerYear, s.HoursPerDay)
+	}
+	price, payback := "To be confirmed", "-"
+	if s.ProjectPrice != nil {
+		price = formatMoney(*s.ProjectPrice)
+	}
+	if s.PaybackYears != nil {
+		payback = formatNumber(*s.PaybackYears, 1) + " years"
+	}
+	p.fields([][2]string{
+		{"Operating hours", hours},
+		{"Electricity rate", "$" + formatNumber(s.ElectricityRate, -1) + " per kWh"},
+		{"Connected load", fmt.Sprintf("%s W today, %s W proposed (%s W less)",
+			formatNumber(t.ExistingWatts, -1), formatNumber(t.ProposedWatts, -1), formatNumber(t.WattsSaved, -1))},
+		{"Energy saved", formatNumber(t.AnnualKWhSaved, 0) + " kWh a year"},
+		{"Cost saved", formatMoney(t.AnnualSavings) + " a year"},
+		{"CO2 avoided", formatNumber(t.CO2KgAvoided, 0) + " kg a year"},
+		{"Project price", price},
+		{"Simple payback", payback},
+	})
+}
+
+func (p *proposalWriter) footers(now time.Time) {
+	n := p.doc.Pages()
+	for i := range n {
+		p.doc.SetPage(i)
+		y := pdf.PageHeight - 30
+		p.doc.Line(propMargin, y-12, propMargin+propWidth, y-12, 0.5, propRule)
+		left := p.brand.Company
+		if p.brand.Contact != "" {
+			left += " · " + p.brand.Contact
+		}
+		p.doc.Text(propMargin, y, pdf.Helvetica, 8, propMuted, left)
+		p.doc.TextRight(propMargin+propWidth, y, pdf.Helvetica, 8, propMuted,
+			fmt.Sprintf("%s · Page %d of %d", now.Format("2006-01-02"), i+1, n))
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// formatNumber groups thousands with commas. decimals < 0 prints as many
+// as needed, up to two.
+func formatNumber(v float64, decimals int) string {
+	var s string
+	if decimals < 0 {
+		s = strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+	} else {
+		s = strconv.FormatFloat(v, 'f', decimals, 64)
+	}
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	var b strings.Builder
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	if hasFrac {
+		return sign + b.String() + "." + frac
+	}
+	return sign + b.String()
+}
+
+func formatMoney(v float64) string {
+	if v < 0 {
+		return "-$" + formatNumber(-v, 2)
+	}
+	return "$" + formatNumber(v, 2)
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rick/go-neon-api/internal/auth"
+	"github.com/rick/go-neon-api/internal/models"
+	"github.com/rick/go-neon-api/internal/store"
+)
+
+// Quotes freeze the case's bill of materials (see bom.go) as a numbered
+// revision. The snapshot is the bomReport as it stood, so later catalog price
+// changes and room edits never alter an issued quote.
+
+type CreateQuoteReq struct {
+	Note *string `json:"note" binding:"omitempty,max=2000"`
+}
+
+// POST /api/cases/:id/quotes
+// Every line must be priced; a quote with blanks is not something a customer
+// can accept, so those are listed back instead.
+func (h *Handlers) CreateQuote(c *gin.Context) {
+	var req CreateQuoteReq
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	id := c.Param("id")
+	rooms, ok := h.caseRooms(c, id)
+	if !ok {
+		return
+	}
+	bom, ok := h.caseBOM(c, id, rooms)
+	if !ok {
+		return
+	}
+	if len(bom.Lines) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to quote: the case has no suggested fixtures or accessories"})
+		return
+	}
+	if bom.Totals.UnpricedLines > 0 {
+		unpriced := []string{}
+		for _, l := range bom.Lines {
+			if l.TotalCost == nil || l.TotalPrice == nil {
+				unpriced = append(unpriced, l.Name)
+			}
+		}
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "some items have no cost or list price", "unpriced": unpriced})
+		return
+	}
+	snapshot, err := json.Marshal(bom)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encode quote"})
+		return
+	}
+	q := models.Quote{
+		CaseID:     bom.CaseID,
+		Note:       optionalText(req.Note),
+		TotalCost:  bom.Totals.Cost,
+		TotalPrice: bom.Totals.Price,
+		LaborHours: bom.Totals.LaborHours,
+		Snapshot:   snapshot,
+	}
+	if err := h.store.Quotes.Create(c, auth.CurrentUser(c).ID, &q); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create failed"})
+		return
+	}
+	c.JSON(http.StatusCreated, q)
+}
+
+// GET /api/cases/:id/quotes
+func (h *Handlers) ListQuotes(c *gin.Context) {
+	rows, err := h.store.Quotes.List(c, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load quotes"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": rows})
+}
+
+// GET /api/cases/:id/quotes/:revision
+func (h *Handlers) GetQuote(c *gin.Context) {
+	rev, err := strconv.Atoi(c.Param("revision"))
+	if err != nil || rev < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "revision must be a positive integer"})
+		return
+	}
+	if q, ok := h.loadQuote(c, rev); ok {
+		c.JSON(http.StatusOK, q)
+	}
+}
+
+func (h *Handlers) loadQuote(c *gin.Context, rev int) (*models.Quote, bool) {
+	q, err := h.store.Quotes.Get(c, c.Param("id"), rev)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "quote revision " + strconv.Itoa(rev) + " not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load quote"})
+		return nil, false
+	}
+	return q, true
+}
+
+type quoteRef struct {
+	Number   string `json:"number"`
+	Revision int    `json:"revision"`
+}
+
+// quoteLineChange is one line that differs between two revisions. From is
+// nil for an added line and To for a removed one.
+type quoteLineChange struct {
+	Change string   `json:"change"` // "added", "removed" or "changed"
+	Kind   string   `json:"kind"`
+	ItemID string   `json:"itemId"`
+	Name   string   `json:"name"`
+	Fields []string `json:"fields,omitempty"` // for "changed": what differs
+	From   *bomLine `json:"from"`
+	To     *bomLine `json:"to"`
+}
+
+type quoteTotalsDelta struct {
+	Cost       float64 `json:"cost"`
+	Price      float64 `json:"price"`
+	LaborHours float64 `json:"laborHours"`
+}
+
+// GET /api/cases/:id/quotes/compare?from=1&to=2
+// Lines are matched by kind and item; unchanged lines are left out.
+func (h *Handlers) CompareQuotes(c *gin.Context) {
+	var revs [2]int
+	for i, key := range []string{"from", "to"} {
+		n, err := strconv.Atoi(c.Query(key))
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": key + " must be a revision number"})
+			return
+		}
+		revs[i] = n
+	}
+	var quotes [2]*models.Quote
+	var boms [2]bomReport
+	for i, rev := range revs {
+		q, ok := h.loadQuote(c, rev)
+		if !ok {
+			return
+		}
+		if err := json.Unmarshal(q.Snapshot, &boms[i]); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read quote"})
+			return
+		}
+		quotes[i] = q
+	}
+	from, to := boms[0], boms[1]
+
+	type key struct{ kind, item string }
+	before := map[key]*bomLine{}
+	for i := range from.Lines {
+		l := &from.Lines[i]
+		before[key{l.Kind, l.ItemID}] = l
+	}
+	changes := []quoteLineChange{}
+	for i := range to.Lines {
+		l := &to.Lines[i]
+		k := key{l.Kind, l.ItemID}
+		old, ok := before[k]
+		delete(before, k)
+		if !ok {
+			changes = append(changes, quoteLineChange{Change: "added", Kind: l.Kind, ItemID: l.ItemID, Name: l.Name, To: l})
+		} else if fields := lineDiff(old, l); len(fields) > 0 {
+			changes = append(changes, quoteLineChange{Change: "changed", Kind: l.Kind, ItemID: l.ItemID, Name: l.Name, Fields: fields, From: old, To: l})
+		}
+	}
+	for i := range from.Lines {
+		l := &from.Lines[i]
+		if _, ok := before[key{l.Kind, l.ItemID}]; ok {
+			changes = append(changes, quoteLineChange{Change: "removed", Kind: l.Kind, ItemID: l.ItemID, Name: l.Name, From: l})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":    quoteRef{quotes[0].Number, quotes[0].Revision},
+		"to":      quoteRef{quotes[1].Number, quotes[1].Revision},
+		"changes": changes,
+		"totals": gin.H{
+			"from": from.Totals,
+			"to":   to.Totals,
+			"delta": quoteTotalsDelta{
+				Cost:       round2(to.Totals.Cost - from.Totals.Cost),
+				Price:      round2(to.Totals.Price - from.Totals.Price),
+				LaborHours: round2(to.Totals.LaborHours - from.Totals.LaborHours),
+			},
+		},
+	})
+}
+
+// lineDiff names the fields that differ between two revisions of a line.
+func lineDiff(a, b *bomLine) []string {
+	var fields []string
+	if a.Name != b.Name {
+		fields = append(fields, "name")
+	}
+	if !equalPtr(a.SKU, b.SKU) {
+		fields = append(fields, "sku")
+	}
+	if a.Quantity != b.Quantity {
+		fields = append(fields, "quantity")
+	}
+	if !equalPtr(a.UnitCost, b.UnitCost) {
+		fields = append(fields, "unitCost")
+	}
+	if !equalPtr(a.ListPrice, b.ListPrice) {
+		fields = append(fields, "listPrice")
+	}
+	if !equalPtr(a.LaborHours, b.LaborHours) {
+		fields = append(fields, "laborHours")
+	}
+	return fields
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
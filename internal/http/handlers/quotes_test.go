@@ -0,0 +1,98 @@
+package handlers_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/rick/go-neon-api/internal/models"
+)
+
+func TestCompareQuotes(t *testing.T) {
+	a := newAPI(t)
+	token := a.login("admin@example.com")
+	id := a.createCase(token, map[string]any{"customerName": "Lincoln"})
+
+	panel := a.db.PutFixtureType(models.LightFixtureType{Name: "Panel", UnitCost: ptr(100.0), ListPrice: ptr(150.0), LaborHours: ptr(0.5)})
+	troffer := a.db.PutFixtureType(models.LightFixtureType{Name: "Troffer", UnitCost: ptr(80.0), ListPrice: ptr(120.0), LaborHours: ptr(1.0)})
+	kit := map[string]any{"name": "Mounting kit", "unitCost": 10, "listPrice": 20}
+	a.want(http.StatusOK, a.do(token, http.MethodPut, "/api/accessories/mountingKit", kit), nil)
+
+	room := a.room(token, id, map[string]any{"location": "Gym", "mountingKitQty": "2"})
+	panels := a.suggest(token, room, panel.ID, 4)
+	var q struct {
+		Number   string `json:"number"`
+		Revision int    `json:"revision"`
+	}
+	a.want(http.StatusCreated, a.do(token, http.MethodPost, "/api/cases/"+id+"/quotes", map[string]any{"note": "first"}), &q)
+	if q.Revision != 1 {
+		t.Fatalf("first quote is revision %d", q.Revision)
+	}
+
+	// Revision 2: more panels at a new price, a troffer, no mounting kits.
+	a.want(http.StatusOK, a.do(token, http.MethodPut, "/api/suggested/"+panels, map[string]any{"quantity": 6}), nil)
+	panel.ListPrice = ptr(160.0)
+	a.db.PutFixtureType(*panel)
+	a.suggest(token, room, troffer.ID, 2)
+	a.want(http.StatusOK, a.do(token, http.MethodPut, "/api/rooms/"+room, map[string]any{"mountingKitQty": ""}), nil)
+	a.want(http.StatusCreated, a.do(token, http.MethodPost, "/api/cases/"+id+"/quotes", nil), &q)
+	if q.Revision != 2 {
+		t.Fatalf("second quote is revision %d", q.Revision)
+	}
+
+	type line struct {
+		Quantity int `json:"quantity"`
+	}
+	var cmp struct {
+		From    struct{ Revision int } `json:"from"`
+		To      struct{ Revision int } `json:"to"`
+		Changes []struct {
+			Change string   `json:"change"`
+			Kind   string   `json:"kind"`
+			ItemID string   `json:"itemId"`
+			Fields []string `json:"fields"`
+			From   *line    `json:"from"`
+			To     *line    `json:"to"`
+		} `json:"changes"`
+		Totals struct {
+			From, To, Delta struct {
+				Cost       float64 `json:"cost"`
+				Price      float64 `json:"price"`
+				LaborHours float64 `json:"laborHours"`
+			}
+		} `json:"totals"`
+	}
+	a.want(http.StatusOK, a.do(token, http.MethodGet, "/api/cases/"+id+"/quotes/compare?from=1&to=2", nil), &cmp)
+	if cmp.From.Revision != 1 || cmp.To.Revision != 2 {
+		t.Errorf("compared %d to %d", cmp.From.Revision, cmp.To.Revision)
+	}
+	if len(cmp.Changes) != 3 {
+		t.Fatalf("changes = %+v, want 3", cmp.Changes)
+	}
+	changed, added, removed := cmp.Changes[0], cmp.Changes[1], cmp.Changes[2]
+	if changed.Change != "changed" || changed.ItemID != panel.ID ||
+		!reflect.DeepEqual(changed.Fields, []string{"quantity", "listPrice"}) ||
+		changed.From.Quantity != 4 || changed.To.Quantity != 6 {
+		t.Errorf("changed = %+v", changed)
+	}
+	if added.Change != "added" || added.Kind != "fixture" || added.ItemID != troffer.ID || added.From != nil || added.To.Quantity != 2 {
+		t.Errorf("added = %+v", added)
+	}
+	if removed.Change != "removed" || removed.Kind != models.AccessoryMountingKit || removed.From.Quantity != 2 || removed.To != nil {
+		t.Errorf("removed = %+v", removed)
+	}
+	totals := cmp.Totals
+	if totals.From.Cost != 420 || totals.From.Price != 640 || totals.From.LaborHours != 2 ||
+		totals.To.Cost != 760 || totals.To.Price != 1200 || totals.To.LaborHours != 5 ||
+		totals.Delta.Cost != 340 || totals.Delta.Price != 560 || totals.Delta.LaborHours != 3 {
+		t.Errorf("totals = %+v", totals)
+	}
+
+	// Comparing a revision with itself shows nothing.
+	a.want(http.StatusOK, a.do(token, http.MethodGet, "/api/cases/"+id+"/quotes/compare?from=2&to=2", nil), &cmp)
+	if len(cmp.Changes) != 0 {
+		t.Errorf("self-compare changes = %+v", cmp.Changes)
+	}
+	a.want(http.StatusBadRequest, a.do(token, http.MethodGet, "/api/cases/"+id+"/quotes/compare?from=0&to=2", nil), nil)
+	a.want(http.StatusNotFound, a.do(token, http.MethodGet, "/api/cases/"+id+"/quotes/compare?from=1&to=3", nil), nil)
+}
@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rick/go-neon-api/internal/store"
+)
+
+// Fixture recommendations turn a room's existing lighting into suggested
+// lighting. Each rule maps a Product, or every product of a category, to a
+// LightFixtureType, optionally only in rooms within a ceiling height range or
+// carrying a location tag. The auto endpoints add one suggested line per
+// recommended fixture type and room, with the quantities of the existing
+// lines it replaces added up.
+
+// ---------- Rules (ADMIN) ----------
+
+// GET /api/fixture-recommendations
+func (h *Handlers) ListFixtureRecommendations(c *gin.Context) {
+	rows, err := h.store.Catalog.Recommendations(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": rows})
+}
+
+type FixtureRecommendationReq struct {
+	ProductID        *string `json:"productId"`
+	Category         *string `json:"category" binding:"omitempty,max=128"`
+	MinCeilingHeight *int    `json:"minCeilingHeight" binding:"omitempty,gte=0"`
+	MaxCeilingHeight *int    `json:"maxCeilingHeight" binding:"omitempty,gte=0"`
+	LocationTagID    *string `json:"locationTagId"`
+	FixtureTypeID    string  `json:"fixtureTypeId" binding:"required"`
+	Priority         int     `json:"priority"`
+}
+
+func bindRecommendation(c *gin.Context) (store.FixtureRecommendationInput, bool) {
+	var req FixtureRecommendationReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return store.FixtureRecommendationInput{}, false
+	}
+	in := store.FixtureRecommendationInput{
+		ProductID:        optionalText(req.ProductID),
+		Category:         optionalText(req.Category),
+		MinCeilingHeight: req.MinCeilingHeight,
+		MaxCeilingHeight: req.MaxCeilingHeight,
+		LocationTagID:    optionalText(req.LocationTagID),
+		FixtureTypeID:    strings.TrimSpace(req.FixtureTypeID),
+		Priority:         req.Priority,
+	}
+	if (in.ProductID == nil) == (in.Category == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "set exactly one of productId and category"})
+		return store.FixtureRecommendationInput{}, false
+	}
+	if in.MinCeilingHeight != nil && in.MaxCeilingHeight != nil && *in.MinCeilingHeight > *in.MaxCeilingHeight {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "minCeilingHeight is above maxCeilingHeight"})
+		return store.FixtureRecommendationInput{}, false
+	}
+	return in, true
+}
+
+// recommendationWrite answers a create or update with the rule.
+func recommendationWrite(c *gin.Context, status int, row *store.FixtureRecommendation, err error) {
+	if errors.Is(err, store.ErrInvalidReference) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "productId, locationTagId or fixtureTypeId does not exist"})
+		return
+	}
+	catalogWrite(c, status, row, err)
+}
+
+// POST /api/fixture-recommendations (ADMIN)
+func (h *Handlers) CreateFixtureRecommendation(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	in, ok := bindRecommendation(c)
+	if !ok {
+		return
+	}
+	row, err := h.store.Catalog.CreateRecommendation(c, in)
+	recommendationWrite(c, http.StatusCreated, row, err)
+}
+
+// PUT /api/fixture-recommendations/:id (ADMIN)
+// Replaces the whole rule.
+func (h *Handlers) UpdateFixtureRecommendation(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	in, ok := bindRecommendation(c)
+	if !ok {
+		return
+	}
+	row, err := h.store.Catalog.UpdateRecommendation(c, c.Param("id"), in)
+	recommendationWrite(c, http.StatusOK, row, err)
+}
+
+// DELETE /api/fixture-recommendations/:id (ADMIN)
+func (h *Handlers) DeleteFixtureRecommendation(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	err := h.store.Catalog.DeleteRecommendation(c, c.Param("id"))
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+	default:
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// ---------- Automatic suggestions ----------
+
+// ruleApplies reports whether r covers the existing line in room.
+func ruleApplies(r *store.FixtureRecommendation, room *store.Room, line *store.ExistingLine) bool {
+	if r.ProductID != nil {
+		if *r.ProductID != line.ProductID {
+			return false
+		}
+	} else if line.Category == nil || !strings.EqualFold(strings.TrimSpace(*line.Category), deref(r.Category)) {
+		return false
+	}
+	if r.MinCeilingHeight != nil || r.MaxCeilingHeight != nil {
+		if room.CeilingHeight == nil {
+			return false
+		}
+		height := *room.CeilingHeight
+		if (r.MinCeilingHeight != nil && height < *r.MinCeilingHeight) || (r.MaxCeilingHeight != nil && height > *r.MaxCeilingHeight) {
+			return false
+		}
+	}
+	if r.LocationTagID != nil && deref(room.LocationTagID) != *r.LocationTagID {
+		return false
+	}
+	return true
+}
+
+// ruleRank orders matching rules: higher priority first, then product rules
+// before category rules, then rules with more room conditions.
+func ruleRank(r *store.FixtureRecommendation) [3]int {
+	rank := [3]int{r.Priority, 0, 0}
+	if r.ProductID != nil {
+		rank[1] = 1
+	}
+	if r.MinCeilingHeight != nil || r.MaxCeilingHeight != nil {
+		rank[2]++
+	}
+	if r.LocationTagID != nil {
+		rank[2]++
+	}
+	return rank
+}
+
+// recommend picks the rule for an existing line, nil if none applies. Ties
+// go to the older rule; rules for archived fixture types are ignored.
+func recommend(rules []store.FixtureRecommendation, room *store.Room, line *store.ExistingLine) *store.FixtureRecommendation {
+	var best *store.FixtureRecommendation
+	var bestRank [3]int
+	for i := range rules {
+		r := &rules[i]
+		if r.FixtureTypeArchived || !ruleApplies(r, room, line) {
+			continue
+		}
+		rank := ruleRank(r)
+		if best == nil || rank[0] > bestRank[0] ||
+			(rank[0] == bestRank[0] && (rank[1] > bestRank[1] || (rank[1] == bestRank[1] && rank[2] > bestRank[2]))) {
+			best, bestRank = r, rank
+		}
+	}
+	return best
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// autoMatch is an existing line and the rule that matched it.
+type autoMatch struct {
+	ExistingID  string `json:"existingId"`
+	ProductName string `json:"productName"`
+	Quantity    int    `json:"quantity"`
+	RuleID      string `json:"ruleId"`
+}
+
+type autoSuggestion struct {
+	ID            string      `json:"id"` // the new suggested line
+	RoomID        string      `json:"roomId"`
+	Location      string      `json:"location"`
+	FixtureTypeID string      `json:"fixtureTypeId"`
+	TypeName      string      `json:"typeName"`
+	Quantity      int         `json:"quantity"`
+	Existing      []autoMatch `json:"existing"`
+}
+
+type autoSkip struct {
+	RoomID      string `json:"roomId"`
+	Location    string `json:"location"`
+	ExistingID  string `json:"existingId"`
+	ProductName string `json:"productName"`
+	TypeName    string `json:"typeName,omitempty"` // the recommended type, if any
+	Reason      string `json:"reason"`             // "no matching rule" or "already suggested"
+}
+
+// POST /api/rooms/:roomId/suggested/auto
+func (h *Handlers) AutoSuggestRoom(c *gin.Context) {
+	roomID := c.Param("roomId")
+	visitID, err := h.store.OnSite.RoomVisit(c, roomID)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load room"})
+		return
+	}
+	rooms, err := h.store.OnSite.Rooms(c, visitID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load room"})
+		return
+	}
+	var room []store.Room
+	for _, r := range rooms {
+		if r.ID == roomID {
+			room = append(room, r)
+		}
+	}
+	h.autoSuggest(c, room)
+}
+
+// POST /api/onsite/:visitId/suggested/auto
+func (h *Handlers) AutoSuggestVisit(c *gin.Context) {
+	rooms, err := h.store.OnSite.Rooms(c, c.Param("visitId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load rooms"})
+		return
+	}
+	h.autoSuggest(c, rooms)
+}
+
+// autoSuggest adds the recommended fixtures the rooms do not suggest yet and
+// reports what was added and which existing lines were left alone.
+func (h *Handlers) autoSuggest(c *gin.Context, rooms []store.Room) {
+	rules, err := h.store.Catalog.Recommendations(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load recommendations"})
+		return
+	}
+
+	var planned []*autoSuggestion
+	skipped := []autoSkip{}
+	for i := range rooms {
+		room := &rooms[i]
+		suggested := map[string]bool{}
+		for _, s := range room.Suggested {
+			suggested[s.ProductID] = true
+		}
+		byType := map[string]*autoSuggestion{}
+		for j := range room.Existing {
+			line := &room.Existing[j]
+			skip := autoSkip{RoomID: room.ID, Location: room.Location, ExistingID: line.ID, ProductName: line.ProductName}
+			r := recommend(rules, room, line)
+			if r == nil {
+				skip.Reason = "no matching rule"
+				skipped = append(skipped, skip)
+				continue
+			}
+			if suggested[r.FixtureTypeID] {
+				skip.TypeName, skip.Reason = r.FixtureTypeName, "already suggested"
+				skipped = append(skipped, skip)
+				continue
+			}
+			s, ok := byType[r.FixtureTypeID]
+			if !ok {
+				s = &autoSuggestion{RoomID: room.ID, Location: room.Location, FixtureTypeID: r.FixtureTypeID, TypeName: r.FixtureTypeName}
+				byType[r.FixtureTypeID] = s
+				planned = append(planned, s)
+			}
+			s.Quantity += line.Quantity
+			s.Existing = append(s.Existing, autoMatch{ExistingID: line.ID, ProductName: line.ProductName, Quantity: line.Quantity, RuleID: r.ID})
+		}
+	}
+
+	lines := make([]store.NewSuggested, len(planned))
+	for i, s := range planned {
+		lines[i] = store.NewSuggested{RoomID: s.RoomID, FixtureTypeID: s.FixtureTypeID, Quantity: s.Quantity}
+	}
+	added, err := h.store.OnSite.AddSuggestedMissing(c, lines)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create failed"})
+		return
+	}
+	type key struct{ room, fixtureType string }
+	ids := map[key]string{}
+	for _, l := range added {
+		ids[key{l.RoomID, l.FixtureTypeID}] = l.ID
+	}
+
+	// A line missing from added was suggested by someone else meanwhile.
+	out := []autoSuggestion{}
+	for _, s := range planned {
+		if id, ok := ids[key{s.RoomID, s.FixtureTypeID}]; ok {
+			s.ID = id
+			out = append(out, *s)
+			continue
+		}
+		for _, m := range s.Existing {
+			skipped = append(skipped, autoSkip{
+				RoomID: s.RoomID, Location: s.Location, ExistingID: m.ExistingID, ProductName: m.ProductName,
+				TypeName: s.TypeName, Reason: "already suggested",
+			})
+		}
+	}
+
+	status := http.StatusOK
+	if len(out) > 0 {
+		status = http.StatusCreated
+	}
+	c.JSON(status, gin.H{"added": out, "skipped": skipped})
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rick/go-neon-api/internal/store"
+)
+
+// -------------------- Room Photos --------------------
+
+type AddRoomPhotoReq struct {
+	URL     string `json:"url" binding:"required"`
+	Comment string `json:"comment"`
+}
+
+// GET /api/rooms/:roomId/photos
+// Oldest first, each with its tags.
+func (h *Handlers) ListRoomPhotos(c *gin.Context) {
+	photos, err := h.store.OnSite.RoomPhotos(c, c.Param("roomId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load photos"})
+		return
+	}
+	h.signRoomPhotos(c, photos)
+	c.JSON(http.StatusOK, gin.H{"items": photos})
+}
+
+// POST /api/rooms/:roomId/photos
+// Either a multipart upload (file, comment) or JSON naming an external url.
+func (h *Handlers) AddRoomPhoto(c *gin.Context) {
+	roomID := c.Param("roomId")
+	var req AddRoomPhotoReq
+	var meta store.PhotoMeta
+	if isMultipart(c) {
+		up, ok := h.receiveUpload(c, "rooms/"+roomID+"/photos", nil)
+		if !ok {
+			return
+		}
+		req = AddRoomPhotoReq{URL: up.Key, Comment: strings.TrimSpace(c.PostForm("comment"))}
+		meta = h.processPhoto(c, up)
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if !isExternalURL(req.URL) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url must be an absolute http(s) URL"})
+		return
+	}
+	photo, err := h.store.OnSite.AddRoomPhoto(c, roomID, req.URL, req.Comment, meta)
+	if err != nil {
+		h.discardPhoto(c, req.URL)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create failed"})
+		return
+	}
+	photo.DownloadURL = h.downloadURL(c, photo.URL)
+	photo.ThumbnailDownloadURL = h.thumbnailDownloadURL(c, photo.PhotoMeta)
+	c.JSON(http.StatusCreated, photo)
+}
+
+type CaptionRoomPhotoReq struct {
+	Comment *string `json:"comment" binding:"required"`
+}
+
+// PUT /api/room-photos/:id
+// Sets the caption; an empty string clears it.
+func (h *Handlers) CaptionRoomPhoto(c *gin.Context) {
+	var req CaptionRoomPhotoReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.store.OnSite.SetRoomPhotoComment(c, c.Param("id"), *req.Comment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// DELETE /api/room-photos/:id
+// Also removes the stored file, if the photo was uploaded.
+func (h *Handlers) DeleteRoomPhoto(c *gin.Context) {
+	url, err := h.store.OnSite.DeleteRoomPhoto(c, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+	h.discardPhoto(c, url)
+	c.Status(http.StatusNoContent)
+}
+
+type TagRoomPhotoReq struct {
+	TagID string `json:"tagId" binding:"required"`
+}
+
+// POST /api/room-photos/:id/tags
+// Tagging twice is a no-op.
+func (h *Handlers) TagRoomPhoto(c *gin.Context) {
+	var req TagRoomPhotoReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	err := h.store.OnSite.TagRoomPhoto(c, c.Param("id"), req.TagID)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown tagId"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "tag failed"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// DELETE /api/room-photos/:id/tags/:tagId
+func (h *Handlers) UntagRoomPhoto(c *gin.Context) {
+	err := h.store.OnSite.UntagRoomPhoto(c, c.Param("id"), c.Param("tagId"))
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "photo does not have this tag"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "untag failed"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
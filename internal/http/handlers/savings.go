@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rick/go-neon-api/internal/store"
+)
+
+// Energy savings compare each room's existing fixtures (Product.wattage ×
+// quantity) with its suggested ones (LightFixtureType.wattage × quantity)
+// over the case's operating hours. A case's PaybackSetting value, when set,
+// is its electricity rate in $/kWh and replaces the default.
+
+// energyRates are the savings calculator's defaults.
+type energyRates struct {
+	Electricity float64 // $/kWh
+	CO2         float64 // kg CO2 per kWh
+}
+
+// mustLoadEnergyRates reads ELECTRICITY_RATE ($/kWh, default 0.15) and
+// CO2_KG_PER_KWH (default 0.4).
+func mustLoadEnergyRates() energyRates {
+	r := energyRates{Electricity: 0.15, CO2: 0.4}
+	for _, v := range []struct {
+		name string
+		dst  *float64
+	}{{"ELECTRICITY_RATE", &r.Electricity}, {"CO2_KG_PER_KWH", &r.CO2}} {
+		s := os.Getenv(v.name)
+		if s == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || f < 0 {
+			log.Fatalf("invalid %s %q", v.name, s)
+		}
+		*v.dst = f
+	}
+	return r
+}
+
+type savingsFigures struct {
+	ExistingWatts  float64 `json:"existingWatts"`
+	ProposedWatts  float64 `json:"proposedWatts"`
+	WattsSaved     float64 `json:"wattsSaved"`
+	AnnualKWhSaved float64 `json:"annualKwhSaved"`
+	AnnualSavings  float64 `json:"annualSavings"` // dollars
+	CO2KgAvoided   float64 `json:"co2KgAvoided"`  // per year
+}
+
+type savingsRoom struct {
+	RoomID   string `json:"roomId"`
+	Location string `json:"location"`
+	savingsFigures
+}
+
+type savingsReport struct {
+	CaseID          string  `json:"caseId"`
+	DaysPerYear     int     `json:"operationDaysPerYear"`
+	HoursPerDay     int     `json:"operationHoursPerDay"`
+	AnnualHours     int     `json:"annualHours"`
+	ElectricityRate float64 `json:"electricityRate"`
+	RateSource      string  `json:"rateSource"` // "case" (PaybackSetting) or "default"
+	CO2KgPerKWh     float64 `json:"co2KgPerKwh"`
+
+	Rooms        []savingsRoom  `json:"rooms"`
+	Totals       savingsFigures `json:"totals"`
+	ProjectPrice *float64       `json:"projectPrice"` // bill of materials list price
+	PaybackYears *float64       `json:"paybackYears"` // null without a price or savings
+	Warnings     []string       `json:"warnings"`
+}
+
+// GET /api/cases/:id/savings
+func (h *Handlers) GetCaseSavings(c *gin.Context) {
+	id := c.Param("id")
+	rooms, ok := h.caseRooms(c, id)
+	if !ok {
+		return
+	}
+	if r, ok := h.caseSavings(c, id, rooms); ok {
+		c.JSON(http.StatusOK, r)
+	}
+}
+
+// caseSavings works out the savings of the given rooms, and the payback on
+// the case's bill of materials. On failure it writes the error response and
+// returns false.
+func (h *Handlers) caseSavings(c *gin.Context, id string, rooms []store.Room) (*savingsReport, bool) {
+	op, err := h.store.Cases.Operation(c, id)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load case"})
+		return nil, false
+	}
+	bom, ok := h.caseBOM(c, id, rooms)
+	if !ok {
+		return nil, false
+	}
+
+	r := &savingsReport{
+		CaseID: id, DaysPerYear: op.DaysPerYear, HoursPerDay: op.HoursPerDay,
+		AnnualHours:     op.DaysPerYear * op.HoursPerDay,
+		ElectricityRate: h.energy.Electricity, RateSource: "default",
+		CO2KgPerKWh: h.energy.CO2,
+		Rooms:       []savingsRoom{}, Warnings: []string{},
+	}
+	if op.PaybackValue != nil && *op.PaybackValue > 0 {
+		r.ElectricityRate, r.RateSource = *op.PaybackValue, "case"
+	}
+	if r.AnnualHours == 0 {
+		r.Warnings = append(r.Warnings, "operating days or hours are not set; savings are zero")
+	}
+
+	figures := func(existing, proposed float64) savingsFigures {
+		kwh := (existing - proposed) * float64(r.AnnualHours) / 1000
+		return savingsFigures{
+			ExistingWatts:  round2(existing),
+			ProposedWatts:  round2(proposed),
+			WattsSaved:     round2(existing - proposed),
+			AnnualKWhSaved: round2(kwh),
+			AnnualSavings:  round2(kwh * r.ElectricityRate),
+			CO2KgAvoided:   round2(kwh * r.CO2KgPerKWh),
+		}
+	}
+	var existing, proposed float64
+	for _, room := range rooms {
+		var e, p float64
+		for _, l := range room.Existing {
+			e += l.ProductWatt * float64(l.Quantity)
+		}
+		for _, l := range room.Suggested {
+			if l.Wattage == nil {
+				r.Warnings = append(r.Warnings, fmt.Sprintf("room %q: %s has no wattage; counted as 0 W", room.Location, l.TypeName))
+				continue
+			}
+			p += *l.Wattage * float64(l.Quantity)
+		}
+		existing, proposed = existing+e, proposed+p
+		r.Rooms = append(r.Rooms, savingsRoom{RoomID: room.ID, Location: room.Location, savingsFigures: figures(e, p)})
+	}
+	r.Totals = figures(existing, proposed)
+
+	switch {
+	case bom.Totals.UnpricedLines > 0:
+		r.Warnings = append(r.Warnings, fmt.Sprintf("bill of materials has %d unpriced line(s); no payback", bom.Totals.UnpricedLines))
+	case len(bom.Lines) > 0:
+		price := bom.Totals.Price
+		r.ProjectPrice = &price
+		if r.Totals.AnnualSavings > 0 {
+			years := round2(price / r.Totals.AnnualSavings)
+			r.PaybackYears = &years
+		}
+	}
+	return r, true
+}
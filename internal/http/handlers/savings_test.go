@@ -0,0 +1,139 @@
+package handlers_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/rick/go-neon-api/internal/models"
+)
+
+type savingsFigures struct {
+	ExistingWatts  float64 `json:"existingWatts"`
+	ProposedWatts  float64 `json:"proposedWatts"`
+	WattsSaved     float64 `json:"wattsSaved"`
+	AnnualKWhSaved float64 `json:"annualKwhSaved"`
+	AnnualSavings  float64 `json:"annualSavings"`
+	CO2KgAvoided   float64 `json:"co2KgAvoided"`
+}
+
+func TestCaseSavings(t *testing.T) {
+	// Gym swaps 10 × 32 W for 10 × 15 W and Hall 4 × 32 W for 4 × 15 W: 238 W
+	// saved in all. At 250 days × 10 hours that is 595 kWh a year.
+	gym := savingsFigures{320, 150, 170, 425, 63.75, 170}
+	hall := savingsFigures{128, 60, 68, 170, 25.5, 68}
+	none := func(f savingsFigures) savingsFigures {
+		f.AnnualKWhSaved, f.AnnualSavings, f.CO2KgAvoided = 0, 0, 0
+		return f
+	}
+
+	for _, tc := range []struct {
+		name        string
+		days, hours int
+		rate        float64 // PaybackSetting; 0 for none
+		listPrice   *float64
+		wantRate    float64
+		wantSource  string
+		wantRooms   []savingsFigures // Hall then Gym, newest first
+		wantTotals  savingsFigures
+		wantPrice   *float64
+		wantPayback *float64
+		wantWarning []string
+	}{
+		{
+			name: "default rate", days: 250, hours: 10, listPrice: ptr(45.0),
+			wantRate: 0.15, wantSource: "default",
+			wantRooms:  []savingsFigures{hall, gym},
+			wantTotals: savingsFigures{448, 210, 238, 595, 89.25, 238},
+			// 14 fixtures at $45 = $630; 630 / 89.25 = 7.0588.
+			wantPrice: ptr(630.0), wantPayback: ptr(7.06),
+			wantWarning: []string{},
+		},
+		{
+			name: "case rate", days: 250, hours: 10, rate: 0.2, listPrice: ptr(45.0),
+			wantRate: 0.2, wantSource: "case",
+			wantRooms:  []savingsFigures{{128, 60, 68, 170, 34, 68}, {320, 150, 170, 425, 85, 170}},
+			wantTotals: savingsFigures{448, 210, 238, 595, 119, 238},
+			// 630 / 119 = 5.294.
+			wantPrice: ptr(630.0), wantPayback: ptr(5.29),
+			wantWarning: []string{},
+		},
+		{
+			name: "zero hours", days: 250, hours: 0, listPrice: ptr(45.0),
+			wantRate: 0.15, wantSource: "default",
+			wantRooms:   []savingsFigures{none(hall), none(gym)},
+			wantTotals:  savingsFigures{448, 210, 238, 0, 0, 0},
+			wantPrice:   ptr(630.0),
+			wantWarning: []string{"operating days or hours are not set; savings are zero"},
+		},
+		{
+			name: "unpriced bill of materials", days: 250, hours: 10,
+			wantRate: 0.15, wantSource: "default",
+			wantRooms:   []savingsFigures{hall, gym},
+			wantTotals:  savingsFigures{448, 210, 238, 595, 89.25, 238},
+			wantWarning: []string{"bill of materials has 1 unpriced line(s); no payback"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("ELECTRICITY_RATE", "0.15")
+			t.Setenv("CO2_KG_PER_KWH", "0.4")
+			a := newAPI(t)
+			token := a.login("admin@example.com")
+			id := a.createCase(token, map[string]any{
+				"customerName": "Lincoln", "operationDaysPerYear": tc.days, "operationHoursPerDay": tc.hours,
+			})
+			if tc.rate > 0 {
+				a.db.PutPaybackSetting(id, tc.rate)
+			}
+			t8 := a.db.PutProduct(models.Product{Name: "T8", Wattage: 32})
+			led := a.db.PutFixtureType(models.LightFixtureType{Name: "LED tube", Wattage: ptr(15.0), UnitCost: ptr(20.0), ListPrice: tc.listPrice})
+			for _, r := range []struct {
+				location string
+				quantity int
+			}{{"Gym", 10}, {"Hall", 4}} {
+				room := a.room(token, id, map[string]any{"location": r.location})
+				existing := map[string]any{"productId": t8.ID, "quantity": r.quantity}
+				a.want(http.StatusCreated, a.do(token, http.MethodPost, "/api/rooms/"+room+"/existing", existing), nil)
+				a.suggest(token, room, led.ID, r.quantity)
+			}
+
+			var got struct {
+				AnnualHours     int     `json:"annualHours"`
+				ElectricityRate float64 `json:"electricityRate"`
+				RateSource      string  `json:"rateSource"`
+				CO2KgPerKWh     float64 `json:"co2KgPerKwh"`
+				Rooms           []struct {
+					Location string `json:"location"`
+					savingsFigures
+				} `json:"rooms"`
+				Totals       savingsFigures `json:"totals"`
+				ProjectPrice *float64       `json:"projectPrice"`
+				PaybackYears *float64       `json:"paybackYears"`
+				Warnings     []string       `json:"warnings"`
+			}
+			a.want(http.StatusOK, a.do(token, http.MethodGet, "/api/cases/"+id+"/savings", nil), &got)
+
+			if got.AnnualHours != tc.days*tc.hours || got.ElectricityRate != tc.wantRate ||
+				got.RateSource != tc.wantSource || got.CO2KgPerKWh != 0.4 {
+				t.Errorf("%d hours at $%v/kWh (%s), %v kg/kWh", got.AnnualHours, got.ElectricityRate, got.RateSource, got.CO2KgPerKWh)
+			}
+			var rooms []savingsFigures
+			for _, r := range got.Rooms {
+				rooms = append(rooms, r.savingsFigures)
+			}
+			if !reflect.DeepEqual(rooms, tc.wantRooms) {
+				t.Errorf("rooms = %+v, want %+v", rooms, tc.wantRooms)
+			}
+			if got.Totals != tc.wantTotals {
+				t.Errorf("totals = %+v, want %+v", got.Totals, tc.wantTotals)
+			}
+			if !reflect.DeepEqual(got.ProjectPrice, tc.wantPrice) || !reflect.DeepEqual(got.PaybackYears, tc.wantPayback) {
+				t.Errorf("price %v, payback %v; want %v, %v",
+					deref(got.ProjectPrice), deref(got.PaybackYears), deref(tc.wantPrice), deref(tc.wantPayback))
+			}
+			if !reflect.DeepEqual(got.Warnings, tc.wantWarning) {
+				t.Errorf("warnings = %q, want %q", got.Warnings, tc.wantWarning)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rick/go-neon-api/internal/auth"
+	"github.com/rick/go-neon-api/internal/casestatus"
+	"github.com/rick/go-neon-api/internal/store"
+)
+
+// -------------------- Case status --------------------
+
+// GET /api/cases/:id/status
+// Current status, the transitions open to the caller, and the change history.
+func (h *Handlers) GetCaseStatus(c *gin.Context) {
+	caseID := c.Param("id")
+
+	status, history, err := h.store.Cases.StatusHistory(c, caseID)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load status history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  status,
+		"allowed": h.statusRules.Next(auth.CurrentRole(c), status),
+		"history": history,
+	})
+}
+
+type TransitionStatusReq struct {
+	Status string  `json:"status" binding:"required"`
+	Note   *string `json:"note"`
+}
+
+// POST /api/cases/:id/status
+// Moves the case to a new status if the caller's role allows it from the
+// current one; 409 otherwise.
+func (h *Handlers) TransitionCaseStatus(c *gin.Context) {
+	caseID := c.Param("id")
+	user := auth.CurrentUser(c)
+	role := auth.CurrentRole(c)
+
+	var req TransitionStatusReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	to := strings.TrimSpace(req.Status)
+	if !casestatus.Valid(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown status: " + to})
+		return
+	}
+
+	row, err := h.store.Cases.Transition(c, user.ID, caseID, to, req.Note, func(from string) bool {
+		return h.statusRules.Allowed(role, from, to)
+	})
+	var refused *store.TransitionError
+	switch {
+	case errors.As(err, &refused):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   refused.Error(),
+			"status":  refused.From,
+			"allowed": h.statusRules.Next(role, refused.From),
+		})
+		return
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "transition failed"})
+		return
+	}
+
+	row.UserName = user.Name
+	c.JSON(http.StatusOK, row)
+}
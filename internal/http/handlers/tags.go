@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rick/go-neon-api/internal/auth"
+	"github.com/rick/go-neon-api/internal/store"
+)
+
+// Tag vocabularies for room photos and room locations. Any user may list and
+// add tags; renaming and deleting affect every photo or room that carries the
+// tag, so those are ADMIN only.
+
+const maxTagName = 64
+
+type TagReq struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// bindTagName reads {"name"} and returns it trimmed. It writes the 400
+// response itself.
+func bindTagName(c *gin.Context) (string, bool) {
+	var req TagReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return "", false
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > maxTagName {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must be 1-64 characters"})
+		return "", false
+	}
+	return name, true
+}
+
+// -------------------- Photo Tags --------------------
+
+// GET /api/photo-tags
+func (h *Handlers) ListPhotoTags(c *gin.Context) {
+	tags, err := h.store.OnSite.PhotoTags(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load tags"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": tags})
+}
+
+// POST /api/photo-tags
+func (h *Handlers) CreatePhotoTag(c *gin.Context) {
+	name, ok := bindTagName(c)
+	if !ok {
+		return
+	}
+	tag, err := h.store.OnSite.CreatePhotoTag(c, name)
+	if errors.Is(err, store.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "tag already exists"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create failed"})
+		return
+	}
+	c.JSON(http.StatusCreated, tag)
+}
+
+// PUT /api/photo-tags/:tagId (ADMIN)
+func (h *Handlers) RenamePhotoTag(c *gin.Context) {
+	if !auth.IsAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+	name, ok := bindTagName(c)
+	if !ok {
+		return
+	}
+	switch err := h.store.OnSite.RenamePhotoTag(c, c.Param("tagId"), name); {
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, store.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "tag already exists"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+	default:
+		c.Status(http.StatusOK)
+	}
+}
+
+// DELETE /api/photo-tags/:tagId (ADMIN)
+// Refused with 409 while any photo carries the tag.
+func (h *Handlers) DeletePhotoTag(c *gin.Context) {
+	if !auth.IsAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+	switch err := h.store.OnSite.DeletePhotoTag(c, c.Param("tagId")); {
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, store.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "tag is in use"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+	default:
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// -------------------- Location Tags --------------------
+
+// GET /api/location-tags
+func (h *Handlers) ListLocationTags(c *gin.Context) {
+	tags, err := h.store.OnSite.LocationTags(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load tags"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": tags})
+}
+
+// POST /api/location-tags
+func (h *Handlers) CreateLocationTag(c *gin.Context) {
+	name, ok := bindTagName(c)
+	if !ok {
+		return
+	}
+	tag, err := h.store.OnSite.CreateLocationTag(c, name)
+	if errors.Is(err, store.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "tag already exists"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "create failed"})
+		return
+	}
+	c.JSON(http.StatusCreated, tag)
+}
+
+// PUT /api/location-tags/:tagId (ADMIN)
+func (h *Handlers) RenameLocationTag(c *gin.Context) {
+	if !auth.IsAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+	name, ok := bindTagName(c)
+	if !ok {
+		return
+	}
+	switch err := h.store.OnSite.RenameLocationTag(c, c.Param("tagId"), name); {
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, store.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "tag already exists"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+	default:
+		c.Status(http.StatusOK)
+	}
+}
+
+// DELETE /api/location-tags/:tagId (ADMIN)
+// Rooms that carried the tag become untagged.
+func (h *Handlers) DeleteLocationTag(c *gin.Context) {
+	if !auth.IsAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+	switch err := h.store.OnSite.DeleteLocationTag(c, c.Param("tagId")); {
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+	default:
+		c.Status(http.StatusNoContent)
+	}
+}
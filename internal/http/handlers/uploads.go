@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rick/go-neon-api/internal/imaging"
+	"github.com/rick/go-neon-api/internal/storage"
+	"github.com/rick/go-neon-api/internal/store"
+)
+
+// isMultipart reports whether the request is a multipart/form-data upload
+// rather than a JSON body naming an external URL.
+func isMultipart(c *gin.Context) bool {
+	return c.ContentType() == "multipart/form-data"
+}
+
+// isExternalURL reports whether a url given in a JSON body is an absolute
+// http(s) URL. Anything else could name another case's storage key.
+func isExternalURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// formString returns a trimmed form value, or nil if it is blank.
+func formString(c *gin.Context, name string) *string {
+	v := strings.TrimSpace(c.PostForm(name))
+	if v == "" {
+		return nil
+	}
+	return &v
+}
+
+// upload is a file received by receiveUpload and already in storage.
+type upload struct {
+	Key         string
+	FileName    string // as sent by the client
+	Size        int64
+	ContentType string // sniffed from the bytes, not taken from the client
+
+	file *multipart.FileHeader
+}
+
+// receiveUpload stores the multipart "file" field under a new key below
+// prefix, named for the sniffed content type (see storage.NewKey). If accept
+// is non-nil it lists the sniffed media types allowed. It writes the error
+// response itself and returns ok=false on failure.
+func (h *Handlers) receiveUpload(c *gin.Context, prefix string, accept []string) (upload, bool) {
+	fh, ok := h.formFile(c)
+	if !ok {
+		return upload{}, false
+	}
+	f, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unreadable upload"})
+		return upload{}, false
+	}
+	defer f.Close()
+
+	br := bufio.NewReaderSize(f, 512)
+	head, _ := br.Peek(512)
+	u := upload{
+		FileName:    path.Base(strings.ReplaceAll(fh.Filename, `\`, "/")),
+		Size:        fh.Size,
+		ContentType: http.DetectContentType(head),
+		file:        fh,
+	}
+	if mediaType, _, _ := strings.Cut(u.ContentType, ";"); accept != nil && !slices.Contains(accept, mediaType) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "file type not accepted: " + mediaType})
+		return upload{}, false
+	}
+	u.Key = storage.NewKey(prefix, u.ContentType)
+	if err := h.files.Put(c, u.Key, br, u.Size, storage.ContentType(u.Key)); err != nil {
+		log.Printf("storage put %s: %v", u.Key, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "upload failed"})
+		return upload{}, false
+	}
+	return u, true
+}
+
+// formFile returns the multipart "file" field, enforcing MaxUploadBytes. It
+// writes the error response itself.
+func (h *Handlers) formFile(c *gin.Context) (*multipart.FileHeader, bool) {
+	tooLarge := func() {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": fmt.Sprintf("file exceeds %d MB", h.uploads.MaxUploadBytes>>20),
+		})
+	}
+
+	// Allow some room for the multipart framing and the other form fields.
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.uploads.MaxUploadBytes+1<<20)
+	fh, err := c.FormFile("file")
+	if err != nil {
+		var tooBig *http.MaxBytesError
+		if errors.As(err, &tooBig) {
+			tooLarge()
+			return nil, false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": `multipart field "file" is required`})
+		return nil, false
+	}
+	if fh.Size > h.uploads.MaxUploadBytes {
+		tooLarge()
+		return nil, false
+	}
+	return fh, true
+}
+
+// thumbnailSize is the longest side of generated thumbnails, in pixels.
+const thumbnailSize = 400
+
+// thumbnailTypes are the sniffed types the standard library can decode.
+var thumbnailTypes = []string{"image/jpeg", "image/png", "image/gif"}
+
+// thumbnailSlots bounds how many photos are decoded at once across the
+// process; each decode may hold up to imaging.MaxPixels×4 bytes.
+var thumbnailSlots = make(chan struct{}, 2)
+
+// processPhoto reads EXIF capture time and position from an uploaded image
+// and stores an upright thumbnail next to it. The original is kept as
+// uploaded; viewers apply its EXIF orientation themselves. Failures are
+// logged and leave the fields nil, since the photo itself is already stored.
+func (h *Handlers) processPhoto(c *gin.Context, up upload) store.PhotoMeta {
+	var meta store.PhotoMeta
+	if !slices.Contains(thumbnailTypes, up.ContentType) {
+		return meta
+	}
+	select {
+	case thumbnailSlots <- struct{}{}:
+		defer func() { <-thumbnailSlots }()
+	case <-c.Request.Context().Done():
+		return meta
+	}
+	f, err := up.file.Open()
+	if err != nil {
+		log.Printf("thumbnail %s: %v", up.Key, err)
+		return meta
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		log.Printf("thumbnail %s: %v", up.Key, err)
+		return meta
+	}
+
+	exif := imaging.ReadMeta(data)
+	meta.TakenAt, meta.Latitude, meta.Longitude = exif.TakenAt, exif.Latitude, exif.Longitude
+
+	thumb, err := imaging.Thumbnail(data, thumbnailSize, exif.Orientation)
+	if err != nil {
+		log.Printf("thumbnail %s: %v", up.Key, err)
+		return meta
+	}
+	key := storage.ThumbnailKey(up.Key)
+	if err := h.files.Put(c, key, bytes.NewReader(thumb), int64(len(thumb)), "image/jpeg"); err != nil {
+		log.Printf("storage put %s: %v", key, err)
+		return meta
+	}
+	meta.ThumbnailURL = &key
+	return meta
+}
+
+// discardPhoto is discardObject for a photo and its thumbnail.
+func (h *Handlers) discardPhoto(c *gin.Context, stored string) {
+	if storage.IsKey(stored) {
+		h.discardObject(c, storage.ThumbnailKey(stored))
+	}
+	h.discardObject(c, stored)
+}
+
+// discardObject removes a stored object whose row is gone or was never
+// written. Failures only leave an orphan, so they are logged, not returned.
+func (h *Handlers) discardObject(c *gin.Context, stored string) {
+	if !storage.IsKey(stored) {
+		return
+	}
+	if err := h.files.Delete(c, stored); err != nil {
+		log.Printf("storage delete %s: %v", stored, err)
+	}
+}
+
+// downloadURL returns a signed link for a stored key, or the url itself for
+// rows that point at an external URL.
+func (h *Handlers) downloadURL(c *gin.Context, stored string) string {
+	if !storage.IsKey(stored) {
+		return stored
+	}
+	signed, err := h.files.SignedURL(c, stored, h.uploads.URLTTL)
+	if err != nil {
+		log.Printf("storage sign %s: %v", stored, err)
+		return ""
+	}
+	return signed
+}
+
+// thumbnailDownloadURL is downloadURL for a photo's thumbnail, if it has one.
+func (h *Handlers) thumbnailDownloadURL(c *gin.Context, meta store.PhotoMeta) string {
+	if meta.ThumbnailURL == nil {
+		return ""
+	}
+	return h.downloadURL(c, *meta.ThumbnailURL)
+}
+
+func (h *Handlers) signPhotos(c *gin.Context, rows []store.PhotoRow) {
+	for i := range rows {
+		rows[i].DownloadURL = h.downloadURL(c, rows[i].URL)
+		rows[i].ThumbnailDownloadURL = h.thumbnailDownloadURL(c, rows[i].PhotoMeta)
+	}
+}
+
+func (h *Handlers) signDocuments(c *gin.Context, rows []store.DocumentRow) {
+	for i := range rows {
+		rows[i].DownloadURL = h.downloadURL(c, rows[i].URL)
+	}
+}
+
+func (h *Handlers) signRoomPhotos(c *gin.Context, rows []store.RoomPhoto) {
+	for i := range rows {
+		rows[i].DownloadURL = h.downloadURL(c, rows[i].URL)
+		rows[i].ThumbnailDownloadURL = h.thumbnailDownloadURL(c, rows[i].PhotoMeta)
+	}
+}
+
+// GET /files/*key?expires=...&sig=...
+// Serves local-storage objects for links issued by storage.Local.SignedURL.
+// The signature is the credential, so the route sits outside /api and takes
+// no bearer token. With the S3 backend links go to the bucket and this 404s.
+// The type comes from the key, which records what was sniffed at upload;
+// anything but an image or PDF is sent as an attachment.
+func (h *Handlers) ServeFile(c *gin.Context) {
+	local, ok := h.files.(*storage.Local)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if err := local.Verify(key, c.Query("expires"), c.Query("sig")); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "invalid or expired link"})
+		return
+	}
+	rc, err := local.Open(c, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "read failed"})
+		return
+	}
+	defer rc.Close()
+
+	ct := storage.ContentType(key)
+	if !storage.Inline(ct) {
+		c.Header("Content-Disposition", "attachment")
+	}
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, -1, ct, rc, nil)
+}
@@ -1,47 +1,179 @@
 package http
 
 import (
+	"log"
+	"os"
+	"strings"
+	"time"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/rick/go-neon-api/internal/http/handlers"
+	"github.com/rick/go-neon-api/internal/ratelimit"
+	"github.com/rick/go-neon-api/internal/store"
 )
 
 func NewRouter(h *handlers.Handlers) *gin.Engine {
 	r := gin.Default()
+	if err := r.SetTrustedProxies(trustedProxies()); err != nil {
+		log.Fatalf("invalid TRUSTED_PROXIES: %v", err)
+	}
 	r.Use(cors.New(cors.Config{
 		AllowOrigins: []string{"*"}, // or limit to specific origins later
 		AllowMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders: []string{"Content-Type", "Authorization", "X-User-Id", "X-User-Role"},
+		AllowHeaders: []string{"Content-Type", "Authorization"},
 	}))
+	// Signed download links for local storage; the signature is the credential.
+	r.GET("/files/*key", h.ServeFile)
+
+	public := r.Group("/api")
+	{
+		// ----- Auth (no token required) -----
+		public.POST("/auth/login", h.Login)
+		public.POST("/auth/password-reset", h.RequestPasswordReset)         // email a reset link
+		public.POST("/auth/password-reset/confirm", h.ConfirmPasswordReset) // set new password with token
+	}
+
+	// ----- Customer upload portal (upload token instead of a login) -----
+	portal := r.Group("/api/upload/:token")
+	portal.Use(ratelimit.PerIP(ratelimit.New(60, 10*time.Minute)))
+	{
+		portal.GET("", h.GetUploadPortal)                 // case name and upload limits
+		portal.POST("/photos", h.PortalUploadPhoto)       // multipart file
+		portal.POST("/documents", h.PortalUploadDocument) // multipart file
+	}
+
 	api := r.Group("/api")
+	api.Use(h.Authenticate()) // resolves the caller from the bearer token
 	{
-		// ----- Cases (READ-ONLY) -----
-		api.GET("/cases", h.ListCases)   // admin: all, user: own (based on headers/middleware)
-		api.GET("/cases/:id", h.GetCase) // details for a single case
+		// ----- Session -----
+		api.GET("/auth/me", h.Me)
+		api.POST("/auth/logout", h.Logout)                  // revoke current token
+		api.POST("/auth/change-password", h.ChangePassword) // returns a fresh token
+
+		// Ownership guards: 404 if the id is unknown, 403 unless owner or ADMIN.
+		ownCase := h.RequireOwner(store.CaseResource, "id")
+		ownVisit := h.RequireOwner(store.VisitResource, "visitId")
+		ownRoom := h.RequireOwner(store.RoomResource, "roomId")
+		ownExisting := h.RequireOwner(store.ExistingProductResource, "id")
+		ownSuggested := h.RequireOwner(store.SuggestedProductResource, "id")
+		ownRoomPhoto := h.RequireOwner(store.RoomPhotoResource, "id")
+
+		// ----- Cases -----
+		api.GET("/cases", h.ListCases)                                 // admin: all, user: own (identity from auth middleware)
+		api.POST("/cases", h.CreateCase)                               // create (owner = caller unless admin assigns)
+		api.GET("/cases/:id", ownCase, h.GetCase)                      // details for a single case
+		api.PUT("/cases/:id", ownCase, h.UpdateCase)                   // partial update of case fields
+		api.DELETE("/cases/:id", ownCase, h.DeleteCase)                // delete case and all dependents
+		api.GET("/cases/:id/status", ownCase, h.GetCaseStatus)         // current status, allowed moves, history
+		api.POST("/cases/:id/status", ownCase, h.TransitionCaseStatus) // move to a new status
+
+		// Case photos and documents
+		api.GET("/cases/:id/photos", ownCase, h.ListPhotos)
+		api.POST("/cases/:id/photos", ownCase, h.AddPhoto)
+		api.PUT("/cases/:id/photos/:photoId", ownCase, h.UpdatePhoto) // customName / comment
+		api.DELETE("/cases/:id/photos/:photoId", ownCase, h.DeletePhoto)
+		api.GET("/cases/:id/documents", ownCase, h.ListDocuments)
+		api.POST("/cases/:id/documents", ownCase, h.AddDocument)
+		api.PUT("/cases/:id/documents/:documentId", ownCase, h.UpdateDocument) // customName / comment
+		api.DELETE("/cases/:id/documents/:documentId", ownCase, h.DeleteDocument)
+		api.GET("/cases/:id/archive", ownCase, h.DownloadCaseArchive) // ZIP of every file plus manifest.json
+		api.GET("/cases/:id/bom", ownCase, h.GetCaseBOM)              // priced bill of materials across all rooms
+		api.GET("/cases/:id/savings", ownCase, h.GetCaseSavings)      // annual kWh, dollars and CO2 saved, and payback
+		api.POST("/cases/:id/proposal", ownCase, h.CreateProposal)    // render a PDF proposal and keep it as a document
+		api.GET("/cases/:id/quotes", ownCase, h.ListQuotes)
+		api.POST("/cases/:id/quotes", ownCase, h.CreateQuote)          // freeze the bill of materials as the next revision
+		api.GET("/cases/:id/quotes/compare", ownCase, h.CompareQuotes) // ?from=&to= revisions
+		api.GET("/cases/:id/quotes/:revision", ownCase, h.GetQuote)
+
+		// Customer upload link
+		api.GET("/cases/:id/upload-link", ownCase, h.GetUploadLink)
+		api.POST("/cases/:id/upload-link", ownCase, h.RotateUploadLink)   // new token; optional expiresInDays
+		api.DELETE("/cases/:id/upload-link", ownCase, h.ExpireUploadLink) // disable now
 
 		// ----- On-Site Visit (READ + MUTATIONS on subresources) -----
-		api.GET("/cases/:id/onsite", h.GetOnSiteVisit)     // fetch visit + rooms tree (read)
-		api.POST("/cases/:id/onsite", h.EnsureOnSiteVisit) // ensure visit exists for a case (optional but handy)
+		api.GET("/cases/:id/onsite", ownCase, h.GetOnSiteVisit)     // fetch visit + rooms tree (read); ?groupBy=locationTag
+		api.POST("/cases/:id/onsite", ownCase, h.EnsureOnSiteVisit) // ensure visit exists for a case (optional but handy)
 
 		// Rooms within an On-Site Visit
-		api.POST("/onsite/:visitId/rooms", h.CreateRoom) // create a room for this visit
-		api.PUT("/rooms/:roomId", h.UpdateRoom)          // update room fields
-		api.DELETE("/rooms/:roomId", h.DeleteRoom)       // remove a room
+		api.POST("/onsite/:visitId/rooms", ownVisit, h.CreateRoom) // create a room for this visit
+		api.PUT("/rooms/:roomId", ownRoom, h.UpdateRoom)           // update room fields
+		api.DELETE("/rooms/:roomId", ownRoom, h.DeleteRoom)        // remove a room
 
-		// Pickers
+		// Pickers; ?includeArchived=true to show archived rows
 		api.GET("/products", h.ListProducts)
 		api.GET("/lightfixturetypes", h.ListLightFixtureTypes)
 
+		// Catalog administration (ADMIN); DELETE archives rows still in use
+		api.GET("/products/export", h.ExportProducts)  // ?format=csv|xlsx
+		api.POST("/products/import", h.ImportProducts) // multipart file; dry run unless ?commit=true
+		api.POST("/products", h.CreateProduct)
+		api.PUT("/products/:id", h.UpdateProduct)
+		api.POST("/products/:id/archive", h.ArchiveProduct(true))
+		api.POST("/products/:id/restore", h.ArchiveProduct(false))
+		api.DELETE("/products/:id", h.DeleteProduct)
+		api.GET("/lightfixturetypes/export", h.ExportLightFixtureTypes)
+		api.POST("/lightfixturetypes/import", h.ImportLightFixtureTypes)
+		api.POST("/lightfixturetypes", h.CreateLightFixtureType)
+		api.PUT("/lightfixturetypes/:id", h.UpdateLightFixtureType)
+		api.POST("/lightfixturetypes/:id/archive", h.ArchiveLightFixtureType(true))
+		api.POST("/lightfixturetypes/:id/restore", h.ArchiveLightFixtureType(false))
+		api.DELETE("/lightfixturetypes/:id", h.DeleteLightFixtureType)
+		api.GET("/accessories", h.ListAccessories)
+		api.PUT("/accessories/:kind", h.UpdateAccessory) // ADMIN; mountingKit or motionSensor
+		api.GET("/fixture-recommendations", h.ListFixtureRecommendations)
+		api.POST("/fixture-recommendations", h.CreateFixtureRecommendation)
+		api.PUT("/fixture-recommendations/:id", h.UpdateFixtureRecommendation)
+		api.DELETE("/fixture-recommendations/:id", h.DeleteFixtureRecommendation)
+
 		// Existing lighting in a room (CRUD)
-		api.POST("/rooms/:roomId/existing", h.AddExistingProduct) // add existing fixture row
-		api.PUT("/existing/:id", h.UpdateExistingProduct)         // update qty/flags/etc.
-		api.DELETE("/existing/:id", h.DeleteExistingProduct)      // delete existing fixture row
+		api.POST("/rooms/:roomId/existing", ownRoom, h.AddExistingProduct) // add existing fixture row
+		api.PUT("/existing/:id", ownExisting, h.UpdateExistingProduct)     // update qty/flags/etc.
+		api.DELETE("/existing/:id", ownExisting, h.DeleteExistingProduct)  // delete existing fixture row
 
 		// Suggested lighting in a room (CRUD)
-		api.POST("/rooms/:roomId/suggested", h.AddSuggestedProduct) // add suggested fixture row
-		api.PUT("/suggested/:id", h.UpdateSuggestedProduct)         // update suggestion
-		api.DELETE("/suggested/:id", h.DeleteSuggestedProduct)      // delete suggestion
+		api.POST("/rooms/:roomId/suggested", ownRoom, h.AddSuggestedProduct) // add suggested fixture row
+		api.PUT("/suggested/:id", ownSuggested, h.UpdateSuggestedProduct)    // update suggestion
+		api.DELETE("/suggested/:id", ownSuggested, h.DeleteSuggestedProduct) // delete suggestion
+
+		// Suggested lighting filled in from the fixture recommendation rules
+		api.POST("/rooms/:roomId/suggested/auto", ownRoom, h.AutoSuggestRoom)
+		api.POST("/onsite/:visitId/suggested/auto", ownVisit, h.AutoSuggestVisit)
+
+		// Room photos and their tags
+		api.GET("/rooms/:roomId/photos", ownRoom, h.ListRoomPhotos)                // photos with tags
+		api.POST("/rooms/:roomId/photos", ownRoom, h.AddRoomPhoto)                 // add photo by url
+		api.PUT("/room-photos/:id", ownRoomPhoto, h.CaptionRoomPhoto)              // set caption
+		api.DELETE("/room-photos/:id", ownRoomPhoto, h.DeleteRoomPhoto)            // delete photo
+		api.POST("/room-photos/:id/tags", ownRoomPhoto, h.TagRoomPhoto)            // attach tag
+		api.DELETE("/room-photos/:id/tags/:tagId", ownRoomPhoto, h.UntagRoomPhoto) // detach tag
+
+		// Photo tag vocabulary
+		api.GET("/photo-tags", h.ListPhotoTags)
+		api.POST("/photo-tags", h.CreatePhotoTag)
+		api.PUT("/photo-tags/:tagId", h.RenamePhotoTag)    // admin
+		api.DELETE("/photo-tags/:tagId", h.DeletePhotoTag) // admin; 409 while in use
+
+		// Room location vocabulary ("Gym", "Classroom", ...)
+		api.GET("/location-tags", h.ListLocationTags)
+		api.POST("/location-tags", h.CreateLocationTag)
+		api.PUT("/location-tags/:tagId", h.RenameLocationTag)    // admin
+		api.DELETE("/location-tags/:tagId", h.DeleteLocationTag) // admin; rooms become untagged
 	}
 
 	return r
 }
+
+// trustedProxies reads TRUSTED_PROXIES, the comma-separated IPs or CIDRs of
+// the load balancers in front of the API. None are trusted by default, so
+// ClientIP is the peer address and X-Forwarded-For cannot choose a caller's
+// rate-limit bucket.
+func trustedProxies() []string {
+	var out []string
+	for _, p := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,253 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Meta is what ReadMeta extracts from a photo's EXIF block. Zero values mean
+// the camera did not record it.
+type Meta struct {
+	Orientation int // 1–8 as in the EXIF spec; 0 if absent
+	TakenAt     *time.Time
+	Latitude    *float64
+	Longitude   *float64
+}
+
+var errNoEXIF = errors.New("imaging: no EXIF data")
+
+// ReadMeta reads orientation, capture time and GPS position from a JPEG's
+// APP1 EXIF segment. Other formats and JPEGs without EXIF return a zero Meta
+// and no error; a malformed EXIF block returns what was read before the
+// damage.
+func ReadMeta(data []byte) Meta {
+	var m Meta
+	tiff, err := exifSegment(data)
+	if err != nil {
+		return m
+	}
+	t, err := newTIFF(tiff)
+	if err != nil {
+		return m
+	}
+
+	ifd0 := t.ifd(t.u32(4))
+	if v, ok := ifd0[tagOrientation]; ok {
+		if o := int(t.uint(v)); o >= 1 && o <= 8 {
+			m.Orientation = o
+		}
+	}
+
+	var taken, offset string
+	if v, ok := ifd0[tagDateTime]; ok {
+		taken = t.ascii(v)
+	}
+	if v, ok := ifd0[tagExifIFD]; ok {
+		exif := t.ifd(t.uint(v))
+		if v, ok := exif[tagDateTimeOriginal]; ok {
+			taken = t.ascii(v)
+		}
+		if v, ok := exif[tagOffsetTimeOriginal]; ok {
+			offset = t.ascii(v)
+		}
+	}
+	m.TakenAt = parseEXIFTime(taken, offset)
+
+	if v, ok := ifd0[tagGPSIFD]; ok {
+		gps := t.ifd(t.uint(v))
+		m.Latitude = t.coordinate(gps[gpsLatitude], gps[gpsLatitudeRef], "S")
+		m.Longitude = t.coordinate(gps[gpsLongitude], gps[gpsLongitudeRef], "W")
+	}
+	return m
+}
+
+const (
+	tagOrientation        = 0x0112
+	tagDateTime           = 0x0132
+	tagExifIFD            = 0x8769
+	tagGPSIFD             = 0x8825
+	tagDateTimeOriginal   = 0x9003
+	tagOffsetTimeOriginal = 0x9011
+
+	gpsLatitudeRef  = 0x0001
+	gpsLatitude     = 0x0002
+	gpsLongitudeRef = 0x0003
+	gpsLongitude    = 0x0004
+)
+
+// exifSegment returns the TIFF payload of the first APP1 "Exif" segment.
+func exifSegment(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, errNoEXIF
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return nil, errNoEXIF
+		}
+		marker := data[i+1]
+		if marker == 0xD8 || (marker >= 0xD0 && marker <= 0xD7) || marker == 0x01 || marker == 0xFF {
+			i++ // standalone marker or fill byte
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 { // start of scan / end of image
+			return nil, errNoEXIF
+		}
+		n := int(binary.BigEndian.Uint16(data[i+2:]))
+		end := i + 2 + n
+		if n < 2 || end > len(data) {
+			return nil, errNoEXIF
+		}
+		seg := data[i+4 : end]
+		if marker == 0xE1 && bytes.HasPrefix(seg, []byte("Exif\x00\x00")) {
+			return seg[6:], nil
+		}
+		i = end
+	}
+	return nil, errNoEXIF
+}
+
+// tiff reads IFD entries from an EXIF TIFF block, bounds-checking every
+// offset since the block comes from the uploader.
+type tiff struct {
+	b     []byte
+	order binary.ByteOrder
+}
+
+// entry is one IFD entry: field type, value count and where the value is.
+type entry struct {
+	typ, count, at uint32
+}
+
+func newTIFF(b []byte) (*tiff, error) {
+	if len(b) < 8 {
+		return nil, errNoEXIF
+	}
+	t := &tiff{b: b}
+	switch string(b[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return nil, errNoEXIF
+	}
+	if t.order.Uint16(b[2:]) != 42 {
+		return nil, errNoEXIF
+	}
+	return t, nil
+}
+
+func (t *tiff) u32(at uint32) uint32 {
+	if uint64(at)+4 > uint64(len(t.b)) {
+		return 0
+	}
+	return t.order.Uint32(t.b[at:])
+}
+
+func (t *tiff) u16(at uint32) uint32 {
+	if uint64(at)+2 > uint64(len(t.b)) {
+		return 0
+	}
+	return uint32(t.order.Uint16(t.b[at:]))
+}
+
+// typeSize is the byte size of one value of each TIFF field type.
+var typeSize = map[uint32]uint32{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 7: 1, 9: 4, 10: 8}
+
+// ifd reads the directory at off. Values of four bytes or less sit in the
+// entry itself; larger ones at the offset it holds.
+func (t *tiff) ifd(off uint32) map[uint16]entry {
+	out := map[uint16]entry{}
+	if off == 0 {
+		return out
+	}
+	n := t.u16(off)
+	for i := uint32(0); i < n; i++ {
+		at := off + 2 + i*12
+		if uint64(at)+12 > uint64(len(t.b)) {
+			break
+		}
+		e := entry{typ: t.u16(at + 2), count: t.u32(at + 4), at: at + 8}
+		size, ok := typeSize[e.typ]
+		if !ok {
+			continue
+		}
+		if uint64(size)*uint64(e.count) > 4 {
+			e.at = t.u32(at + 8)
+		}
+		if uint64(e.at)+uint64(size)*uint64(e.count) > uint64(len(t.b)) {
+			continue
+		}
+		out[uint16(t.u16(at))] = e
+	}
+	return out
+}
+
+// uint reads a SHORT or LONG value.
+func (t *tiff) uint(e entry) uint32 {
+	switch e.typ {
+	case 3:
+		return t.u16(e.at)
+	case 4:
+		return t.u32(e.at)
+	}
+	return 0
+}
+
+func (t *tiff) ascii(e entry) string {
+	if e.typ != 2 {
+		return ""
+	}
+	s := string(t.b[e.at : e.at+e.count])
+	return strings.TrimSpace(strings.TrimRight(s, "\x00"))
+}
+
+func (t *tiff) rational(at uint32) (float64, bool) {
+	num, den := t.u32(at), t.u32(at+4)
+	if den == 0 {
+		return 0, false
+	}
+	return float64(num) / float64(den), true
+}
+
+// coordinate converts a degrees/minutes/seconds triple to signed decimal
+// degrees, negative when ref is neg ("S" or "W").
+func (t *tiff) coordinate(dms, ref entry, neg string) *float64 {
+	if dms.typ != 5 || dms.count < 3 || ref.typ != 2 {
+		return nil
+	}
+	d, ok1 := t.rational(dms.at)
+	m, ok2 := t.rational(dms.at + 8)
+	s, ok3 := t.rational(dms.at + 16)
+	if !ok1 || !ok2 || !ok3 {
+		return nil
+	}
+	v := d + m/60 + s/3600
+	if strings.EqualFold(t.ascii(ref), neg) {
+		v = -v
+	}
+	return &v
+}
+
+// parseEXIFTime parses "2006:01:02 15:04:05". Cameras record local time and
+// only newer ones add an offset ("+02:00"); without one the time is taken
+// as UTC.
+func parseEXIFTime(s, offset string) *time.Time {
+	if s == "" {
+		return nil
+	}
+	layout, value := "2006:01:02 15:04:05", s
+	if offset != "" {
+		layout, value = layout+"-07:00", s+offset
+	}
+	tm, err := time.Parse(layout, value)
+	if err != nil {
+		if tm, err = time.Parse("2006:01:02 15:04:05", s); err != nil {
+			return nil
+		}
+	}
+	return &tm
+}
@@ -0,0 +1,182 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+// field is one IFD entry for buildTIFF. value is already in the block's byte
+// order; a field with ifd > 0 instead points at that directory.
+type field struct {
+	tag, typ uint16
+	count    uint32
+	value    []byte
+	ifd      int
+}
+
+// buildTIFF lays out the directories one after another from offset 8, the
+// first being IFD0, followed by the values too large for their entries.
+func buildTIFF(order byteOrder, ifds ...[]field) []byte {
+	offsets := make([]uint32, len(ifds))
+	data := uint32(8)
+	for i, fs := range ifds {
+		offsets[i] = data
+		data += 2 + 12*uint32(len(fs)) + 4
+	}
+
+	b := make([]byte, data)
+	if order == binary.LittleEndian {
+		copy(b, "II")
+	} else {
+		copy(b, "MM")
+	}
+	order.PutUint16(b[2:], 42)
+	order.PutUint32(b[4:], offsets[0])
+	for i, fs := range ifds {
+		at := offsets[i]
+		order.PutUint16(b[at:], uint16(len(fs)))
+		for j, f := range fs {
+			e := at + 2 + 12*uint32(j)
+			order.PutUint16(b[e:], f.tag)
+			order.PutUint16(b[e+2:], f.typ)
+			order.PutUint32(b[e+4:], f.count)
+			switch {
+			case f.ifd > 0:
+				order.PutUint32(b[e+8:], offsets[f.ifd])
+			case len(f.value) <= 4:
+				copy(b[e+8:], f.value)
+			default:
+				order.PutUint32(b[e+8:], uint32(len(b)))
+				b = append(b, f.value...)
+			}
+		}
+	}
+	return b
+}
+
+// jpegWithEXIF wraps a TIFF block in the APP1 segment of an otherwise empty
+// JPEG stream.
+func jpegWithEXIF(tiff []byte) []byte {
+	seg := append([]byte("Exif\x00\x00"), tiff...)
+	out := []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x04, 0x00, 0x00, 0xFF, 0xE1}
+	out = binary.BigEndian.AppendUint16(out, uint16(len(seg)+2))
+	out = append(out, seg...)
+	return append(out, 0xFF, 0xD9)
+}
+
+func short(order byteOrder, v uint16) field {
+	return field{typ: 3, count: 1, value: order.AppendUint16(nil, v)}
+}
+
+func ascii(s string) field {
+	return field{typ: 2, count: uint32(len(s) + 1), value: append([]byte(s), 0)}
+}
+
+func rationals(order byteOrder, v ...uint32) field {
+	var b []byte
+	for _, x := range v {
+		b = order.AppendUint32(b, x)
+	}
+	return field{typ: 5, count: uint32(len(v) / 2), value: b}
+}
+
+func tagged(tag uint16, f field) field {
+	f.tag = tag
+	return f
+}
+
+// samplePhoto is a photo taken in Sydney and marked for a 90° turn, with the
+// west longitude of Sydney's antipode on the other side to exercise both
+// negative refs.
+func samplePhoto(order byteOrder) []byte {
+	return jpegWithEXIF(buildTIFF(order,
+		[]field{
+			tagged(tagOrientation, short(order, 6)),
+			tagged(tagDateTime, ascii("2020:01:01 00:00:00")),
+			{tag: tagExifIFD, typ: 4, count: 1, ifd: 1},
+			{tag: tagGPSIFD, typ: 4, count: 1, ifd: 2},
+		},
+		[]field{
+			tagged(tagDateTimeOriginal, ascii("2023:06:01 14:30:00")),
+			tagged(tagOffsetTimeOriginal, ascii("+10:00")),
+		},
+		[]field{
+			tagged(gpsLatitudeRef, ascii("S")),
+			tagged(gpsLatitude, rationals(order, 33, 1, 51, 1, 2448, 100)),
+			tagged(gpsLongitudeRef, ascii("W")),
+			tagged(gpsLongitude, rationals(order, 28, 1, 47, 1, 42, 1)),
+		},
+	))
+}
+
+func TestReadMeta(t *testing.T) {
+	for _, order := range []byteOrder{binary.LittleEndian, binary.BigEndian} {
+		t.Run(order.String(), func(t *testing.T) {
+			m := ReadMeta(samplePhoto(order))
+			if m.Orientation != 6 {
+				t.Errorf("Orientation = %d, want 6", m.Orientation)
+			}
+			want := time.Date(2023, 6, 1, 4, 30, 0, 0, time.UTC)
+			if m.TakenAt == nil || !m.TakenAt.Equal(want) {
+				t.Errorf("TakenAt = %v, want %v", m.TakenAt, want)
+			} else if _, off := m.TakenAt.Zone(); off != 10*3600 {
+				t.Errorf("TakenAt offset = %ds, want +10:00", off)
+			}
+			if m.Latitude == nil || math.Abs(*m.Latitude-(-33.8568)) > 1e-9 {
+				t.Errorf("Latitude = %v, want -33.8568", m.Latitude)
+			}
+			if m.Longitude == nil || math.Abs(*m.Longitude-(-28.795)) > 1e-9 {
+				t.Errorf("Longitude = %v, want -28.795", m.Longitude)
+			}
+		})
+	}
+}
+
+func TestReadMetaFallbacks(t *testing.T) {
+	order := binary.BigEndian
+	noOffset := jpegWithEXIF(buildTIFF(order, []field{
+		tagged(tagOrientation, short(order, 9)), // out of range
+		tagged(tagDateTime, ascii("2021:03:04 05:06:07")),
+		tagged(gpsLatitude, rationals(order, 1, 1, 2, 1, 3, 1)), // not in a GPS IFD
+	}))
+	m := ReadMeta(noOffset)
+	if m.Orientation != 0 || m.Latitude != nil || m.Longitude != nil {
+		t.Errorf("ReadMeta = %+v, want only TakenAt", m)
+	}
+	if want := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC); m.TakenAt == nil || !m.TakenAt.Equal(want) {
+		t.Errorf("TakenAt = %v, want %v as UTC", m.TakenAt, want)
+	}
+
+	full := samplePhoto(binary.LittleEndian)
+	for name, data := range map[string][]byte{
+		"empty":     nil,
+		"png":       []byte("\x89PNG\r\n\x1a\n"),
+		"no exif":   {0xFF, 0xD8, 0xFF, 0xD9},
+		"truncated": full[:len(full)/2],
+		"bad order": bytes.Replace(full, []byte("II*\x00"), []byte("XX*\x00"), 1),
+	} {
+		if m := ReadMeta(data); m != (Meta{}) {
+			t.Errorf("%s: ReadMeta = %+v, want zero", name, m)
+		}
+	}
+}
+
+func FuzzReadMeta(f *testing.F) {
+	f.Add(samplePhoto(binary.LittleEndian))
+	f.Add(samplePhoto(binary.BigEndian))
+	f.Add([]byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x10, 'E', 'x', 'i', 'f', 0, 0, 'M', 'M', 0, 42, 0xFF, 0xFF, 0xFF, 0xF0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		m := ReadMeta(data)
+		if m.Orientation < 0 || m.Orientation > 8 {
+			t.Errorf("Orientation = %d", m.Orientation)
+		}
+	})
+}
@@ -0,0 +1,134 @@
+// Package imaging builds photo thumbnails and reads photo metadata using only
+// the standard library decoders (JPEG, PNG, GIF).
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+
+	_ "image/gif" // register decoders for image.Decode
+	_ "image/png"
+)
+
+// MaxPixels bounds the images Thumbnail will decode, so a small file that
+// declares huge dimensions cannot exhaust memory. The decoded image takes up
+// to 4 bytes a pixel; nothing else full-size is allocated.
+const MaxPixels = 25_000_000
+
+var ErrTooLarge = errors.New("imaging: image dimensions too large")
+
+// Thumbnail decodes data, scales it to fit within size×size and applies the
+// EXIF orientation (1–8; 0 means as stored), returning a JPEG. Images already
+// smaller than size are not enlarged.
+func Thumbnail(data []byte, size, orientation int) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("imaging: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("imaging: %w", err)
+	}
+
+	b := src.Bounds()
+	w, h := fit(b.Dx(), b.Dy(), size)
+	thumb := orient(downscale(src, w, h), orientation)
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, thumb, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, fmt.Errorf("imaging: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// fit returns w×h scaled down to fit within size×size, keeping aspect ratio.
+func fit(w, h, size int) (int, int) {
+	if w <= size && h <= size {
+		return w, h
+	}
+	if w >= h {
+		return size, max(1, h*size/w)
+	}
+	return max(1, w*size/h), size
+}
+
+// downscale shrinks src to w×h by averaging the block of source pixels under
+// each target pixel (a box filter), which is enough for thumbnails, and
+// flattens the result onto white since JPEG has no alpha. Source rows are
+// converted to RGBA one at a time, so memory stays at one row plus the
+// thumbnail however large src is.
+func downscale(src image.Image, w, h int) *image.RGBA {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	row := image.NewRGBA(image.Rect(0, 0, sw, 1))
+	sums := make([]uint64, w*4)
+	for y := 0; y < h; y++ {
+		y0, y1 := y*sh/h, max((y+1)*sh/h, y*sh/h+1)
+		clear(sums)
+		for sy := y0; sy < y1; sy++ {
+			draw.Draw(row, row.Bounds(), src, image.Pt(b.Min.X, b.Min.Y+sy), draw.Src)
+			for x := 0; x < w; x++ {
+				x0, x1 := x*sw/w, max((x+1)*sw/w, x*sw/w+1)
+				s := sums[x*4 : x*4+4]
+				for sx := x0; sx < x1; sx++ {
+					p := row.Pix[sx*4 : sx*4+4]
+					s[0], s[1], s[2], s[3] = s[0]+uint64(p[0]), s[1]+uint64(p[1]), s[2]+uint64(p[2]), s[3]+uint64(p[3])
+				}
+			}
+		}
+		for x := 0; x < w; x++ {
+			x0, x1 := x*sw/w, max((x+1)*sw/w, x*sw/w+1)
+			n := uint64((x1 - x0) * (y1 - y0))
+			s := sums[x*4 : x*4+4]
+			// Premultiplied colour over white: c + (1 - alpha) × white.
+			white := 255 - s[3]/n
+			d := dst.Pix[y*dst.Stride+x*4:]
+			d[0], d[1], d[2], d[3] = uint8(s[0]/n+white), uint8(s[1]/n+white), uint8(s[2]/n+white), 255
+		}
+	}
+	return dst
+}
+
+// orient returns src turned upright for an EXIF orientation value.
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirrored
+				sx, sy = w-1-x, y
+			case 3: // upside down
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirrored, upside down
+				sx, sy = x, h-1-y
+			case 5: // mirrored, rotated 90° CCW
+				sx, sy = y, x
+			case 6: // rotated 90° CCW; turn it 90° CW
+				sx, sy = y, h-1-x
+			case 7: // mirrored, rotated 90° CW
+				sx, sy = w-1-y, h-1-x
+			case 8: // rotated 90° CW; turn it 90° CCW
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[y*dst.Stride+x*4:y*dst.Stride+x*4+4], src.Pix[sy*src.Stride+sx*4:])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,114 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// marked is a 3×2 image whose pixels encode their own upright position.
+func marked() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 80), uint8(y * 80), 7, 255})
+		}
+	}
+	return img
+}
+
+func TestOrient(t *testing.T) {
+	upright := marked()
+	w, h := 3, 2
+	// stored maps a pixel of the image as the camera saved it, for each EXIF
+	// orientation, to the upright pixel it shows.
+	for o, stored := range map[int]func(x, y int) (int, int){
+		1: func(x, y int) (int, int) { return x, y },
+		2: func(x, y int) (int, int) { return w - 1 - x, y },
+		3: func(x, y int) (int, int) { return w - 1 - x, h - 1 - y },
+		4: func(x, y int) (int, int) { return x, h - 1 - y },
+		5: func(x, y int) (int, int) { return y, x },
+		6: func(x, y int) (int, int) { return w - 1 - y, x },
+		7: func(x, y int) (int, int) { return w - 1 - y, h - 1 - x },
+		8: func(x, y int) (int, int) { return y, h - 1 - x },
+	} {
+		sw, sh := w, h
+		if o >= 5 {
+			sw, sh = h, w
+		}
+		src := image.NewRGBA(image.Rect(0, 0, sw, sh))
+		for y := 0; y < sh; y++ {
+			for x := 0; x < sw; x++ {
+				src.Set(x, y, upright.At(stored(x, y)))
+			}
+		}
+		got := orient(src, o)
+		if got.Bounds() != upright.Bounds() || !bytes.Equal(got.Pix, upright.Pix) {
+			t.Errorf("orientation %d: not turned upright", o)
+		}
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	// Left half opaque red, right half transparent: the thumbnail keeps the
+	// aspect ratio and shows the transparent half as white.
+	src := image.NewNRGBA(image.Rect(0, 0, 800, 400))
+	for y := 0; y < 400; y++ {
+		for x := 0; x < 400; x++ {
+			src.Set(x, y, color.NRGBA{255, 0, 0, 255})
+		}
+	}
+	var in bytes.Buffer
+	if err := png.Encode(&in, src); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := Thumbnail(in.Bytes(), 100, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	thumb, err := jpeg.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := thumb.Bounds(); b.Dx() != 50 || b.Dy() != 100 {
+		t.Fatalf("thumbnail is %dx%d, want 50x100 after turning", b.Dx(), b.Dy())
+	}
+	// Turned 90° clockwise, the red half is on top.
+	for _, tc := range []struct {
+		x, y    int
+		r, g, b uint32
+	}{{25, 20, 255, 0, 0}, {25, 80, 255, 255, 255}} {
+		r, g, b, _ := thumb.At(tc.x, tc.y).RGBA()
+		if diff(r>>8, tc.r) > 8 || diff(g>>8, tc.g) > 8 || diff(b>>8, tc.b) > 8 {
+			t.Errorf("pixel (%d,%d) = %d,%d,%d, want %d,%d,%d", tc.x, tc.y, r>>8, g>>8, b>>8, tc.r, tc.g, tc.b)
+		}
+	}
+}
+
+func TestThumbnailTooLarge(t *testing.T) {
+	// A PNG header alone declares the dimensions; nothing is decoded.
+	var in bytes.Buffer
+	if err := png.Encode(&in, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	data := in.Bytes()
+	copy(data[16:24], []byte{0, 0, 0x27, 0x10, 0, 0, 0x27, 0x10}) // IHDR: 10000×10000
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	if _, err := Thumbnail(data, 100, 0); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("err = %v, want ErrTooLarge", err)
+	}
+}
+
+func diff(a, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
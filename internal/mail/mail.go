@@ -0,0 +1,23 @@
+// Package mail sends the service's outgoing email.
+package mail
+
+import "context"
+
+// Message is one plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages. Bodies may carry credentials such as reset
+// links, so implementations must not log them.
+type Mailer interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// Discard drops every message. It stands in until a real transport is
+// configured; password resets cannot be completed while it is in use.
+type Discard struct{}
+
+func (Discard) Send(context.Context, Message) error { return nil }
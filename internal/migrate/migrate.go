@@ -0,0 +1,272 @@
+// Package migrate applies the versioned SQL migrations embedded in the binary
+// and records them in the "SchemaMigration" table.
+//
+// Files live in migrations/ as NNNN_name.up.sql / NNNN_name.down.sql. Each
+// migration runs in its own transaction while holding a Postgres advisory
+// lock, so concurrent deploys cannot apply the same version twice.
+package migrate
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed migrations/*.sql
+var files embed.FS
+
+// lockKey is an arbitrary constant for pg_advisory_lock.
+const lockKey = 7_341_906_211
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes one known migration and whether it is applied.
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+}
+
+// All returns the embedded migrations ordered by version.
+func All() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		name := e.Name()
+		var dir string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			dir = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			dir = "down"
+		default:
+			continue
+		}
+		base := strings.TrimSuffix(name, "."+dir+".sql")
+		num, label, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: expected NNNN_name", name)
+		}
+		v, err := strconv.Atoi(num)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: bad version: %w", name, err)
+		}
+		body, err := files.ReadFile(path.Join("migrations", name))
+		if err != nil {
+			return nil, err
+		}
+		m := byVersion[v]
+		if m == nil {
+			m = &Migration{Version: v, Name: label}
+			byVersion[v] = m
+		} else if m.Name != label {
+			return nil, fmt.Errorf("migration %d has two names: %s, %s", v, m.Name, label)
+		}
+		if dir == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up file", m.Version, m.Name)
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// Up applies every pending migration and returns the ones it ran.
+func Up(db *gorm.DB) ([]Migration, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+	var ran []Migration
+	err = locked(db, func(conn *gorm.DB) error {
+		applied, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range all {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			if err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(m.Up).Error; err != nil {
+					return err
+				}
+				return tx.Exec(
+					`INSERT INTO "SchemaMigration" ("version","name","appliedAt") VALUES (?, ?, now())`,
+					m.Version, m.Name,
+				).Error
+			}); err != nil {
+				return fmt.Errorf("migration %04d_%s up: %w", m.Version, m.Name, err)
+			}
+			ran = append(ran, m)
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// Down reverts the latest steps applied migrations, newest first.
+func Down(db *gorm.DB, steps int) ([]Migration, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+	known := map[int]Migration{}
+	for _, m := range all {
+		known[m.Version] = m
+	}
+
+	var reverted []Migration
+	err = locked(db, func(conn *gorm.DB) error {
+		applied, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		versions := make([]int, 0, len(applied))
+		for v := range applied {
+			versions = append(versions, v)
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+		for i := 0; i < steps && i < len(versions); i++ {
+			m, ok := known[versions[i]]
+			if !ok {
+				return fmt.Errorf("migration %d is applied but not embedded in this binary", versions[i])
+			}
+			if m.Down == "" {
+				return fmt.Errorf("migration %04d_%s has no down file", m.Version, m.Name)
+			}
+			if err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(m.Down).Error; err != nil {
+					return err
+				}
+				return tx.Exec(`DELETE FROM "SchemaMigration" WHERE "version" = ?`, m.Version).Error
+			}); err != nil {
+				return fmt.Errorf("migration %04d_%s down: %w", m.Version, m.Name, err)
+			}
+			reverted = append(reverted, m)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// StatusOf lists every embedded migration with its applied time, plus any
+// applied version this binary does not know about.
+func StatusOf(db *gorm.DB) ([]Status, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureTable(db); err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Status, 0, len(all))
+	for _, m := range all {
+		s := Status{Version: m.Version, Name: m.Name}
+		if a, ok := applied[m.Version]; ok {
+			s.AppliedAt = &a.AppliedAt
+			delete(applied, m.Version)
+		}
+		out = append(out, s)
+	}
+	for v, a := range applied {
+		out = append(out, Status{Version: v, Name: a.Name + " (unknown)", AppliedAt: &a.AppliedAt})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// Pending returns the embedded migrations that have not been applied.
+func Pending(db *gorm.DB) ([]Migration, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureTable(db); err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+	var out []Migration
+	for _, m := range all {
+		if _, ok := applied[m.Version]; !ok {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+type appliedRow struct {
+	Version   int       `gorm:"column:version"`
+	Name      string    `gorm:"column:name"`
+	AppliedAt time.Time `gorm:"column:appliedAt"`
+}
+
+func ensureTable(db *gorm.DB) error {
+	return db.Exec(`CREATE TABLE IF NOT EXISTS "SchemaMigration" (
+		"version"   INTEGER     NOT NULL,
+		"name"      TEXT        NOT NULL,
+		"appliedAt" TIMESTAMPTZ NOT NULL DEFAULT now(),
+		CONSTRAINT "SchemaMigration_pkey" PRIMARY KEY ("version")
+	)`).Error
+}
+
+func appliedVersions(db *gorm.DB) (map[int]appliedRow, error) {
+	var rows []appliedRow
+	if err := db.Raw(`SELECT "version","name","appliedAt" FROM "SchemaMigration"`).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make(map[int]appliedRow, len(rows))
+	for _, r := range rows {
+		out[r.Version] = r
+	}
+	return out, nil
+}
+
+// locked runs fn on a single pooled connection holding the advisory lock.
+func locked(db *gorm.DB, fn func(conn *gorm.DB) error) error {
+	return db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec(`SELECT pg_advisory_lock(?)`, lockKey).Error; err != nil {
+			return err
+		}
+		defer conn.WithContext(context.Background()).Exec(`SELECT pg_advisory_unlock(?)`, lockKey)
+
+		if err := ensureTable(conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
@@ -0,0 +1,86 @@
+package migrate
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestAllBaselineIsIrreversible(t *testing.T) {
+	all, err := All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all[0].Version != 1 || all[0].Down != "" {
+		t.Fatalf("migration %04d_%s has a down file; the baseline must not", all[0].Version, all[0].Name)
+	}
+	for _, m := range all[1:] {
+		if m.Down == "" {
+			t.Errorf("migration %04d_%s has no down file", m.Version, m.Name)
+		}
+	}
+}
+
+// TestDownStopsAtBaseline applies every migration to a fresh schema in
+// TEST_DATABASE_URL and reverts as far as it can.
+func TestDownStopsAtBaseline(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One connection, so the search_path below holds for every statement.
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	schema := "migrate_test_" + cuid.New()
+	if err := db.Exec(`CREATE SCHEMA "` + schema + `"`).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Exec(`DROP SCHEMA "` + schema + `" CASCADE`) })
+	if err := db.Exec(`SET search_path TO "` + schema + `", public`).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Up(db); err != nil {
+		t.Fatal(err)
+	}
+	reverted, err := Down(db, 99)
+	if err == nil || !strings.Contains(err.Error(), "0001_baseline has no down file") {
+		t.Fatalf("Down(99) error = %v, want the baseline to refuse", err)
+	}
+	if len(reverted) != len(all)-1 {
+		t.Errorf("reverted %d migrations, want %d", len(reverted), len(all)-1)
+	}
+
+	status, err := StatusOf(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range status {
+		if applied := s.AppliedAt != nil; applied != (s.Version == 1) {
+			t.Errorf("migration %04d_%s applied = %v", s.Version, s.Name, applied)
+		}
+	}
+	var tables int64
+	if err := db.Raw(`SELECT count(*) FROM information_schema.tables WHERE table_schema = ? AND table_name IN ('Case', 'User')`, schema).Scan(&tables).Error; err != nil {
+		t.Fatal(err)
+	}
+	if tables != 2 {
+		t.Errorf("%d of the Case and User tables survived, want 2", tables)
+	}
+}
@@ -0,0 +1,260 @@
+-- Baseline: the schema Prisma created for the original app. Every statement is
+-- idempotent so existing Prisma-managed databases can adopt this history.
+-- There is deliberately no down file: this service adopted these tables
+-- rather than creating them, so `migrate down` stops here.
+
+CREATE TABLE IF NOT EXISTS "User" (
+    "id"        TEXT         NOT NULL,
+    "email"     TEXT         NOT NULL,
+    "name"      TEXT,
+    "password"  TEXT,
+    "role"      TEXT         NOT NULL DEFAULT 'USER',
+    "createdAt" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updatedAt" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "User_pkey" PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "User_email_key" ON "User" ("email");
+
+CREATE TABLE IF NOT EXISTS "Case" (
+    "id"                   TEXT         NOT NULL,
+    "userId"               TEXT         NOT NULL,
+    "customerName"         TEXT         NOT NULL DEFAULT '',
+    "projectDetails"       TEXT         NOT NULL DEFAULT '',
+    "uploadToken"          TEXT         NOT NULL DEFAULT gen_random_uuid()::text,
+    "status"               TEXT         NOT NULL DEFAULT 'New',
+    "createdAt"            TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updatedAt"            TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "schoolName"           TEXT         NOT NULL DEFAULT '',
+    "contactPerson"        TEXT         NOT NULL DEFAULT '',
+    "emailAddress"         TEXT         NOT NULL DEFAULT '',
+    "phoneNumber"          TEXT         NOT NULL DEFAULT '',
+    "schoolAddress"        TEXT         NOT NULL DEFAULT '',
+    "num2FtLinearHighBay"  INTEGER      NOT NULL DEFAULT 0,
+    "num150WUFOHighBay"    INTEGER      NOT NULL DEFAULT 0,
+    "num240WUFOHighBay"    INTEGER      NOT NULL DEFAULT 0,
+    "num2x2LEDPanel"       INTEGER      NOT NULL DEFAULT 0,
+    "num2x4LEDPanel"       INTEGER      NOT NULL DEFAULT 0,
+    "num1x4LEDPanel"       INTEGER      NOT NULL DEFAULT 0,
+    "num4FtStripLight"     INTEGER      NOT NULL DEFAULT 0,
+    "lightingPurpose"      TEXT         NOT NULL DEFAULT '',
+    "facilitiesUsedIn"     TEXT         NOT NULL DEFAULT '',
+    "installationService"  TEXT         NOT NULL DEFAULT '',
+    "operationDaysPerYear" INTEGER      NOT NULL DEFAULT 0,
+    "operationHoursPerDay" INTEGER      NOT NULL DEFAULT 0,
+    CONSTRAINT "Case_pkey" PRIMARY KEY ("id"),
+    CONSTRAINT "Case_userId_fkey" FOREIGN KEY ("userId") REFERENCES "User" ("id") ON DELETE RESTRICT ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "Case_userId_idx" ON "Case" ("userId");
+CREATE UNIQUE INDEX IF NOT EXISTS "Case_uploadToken_key" ON "Case" ("uploadToken");
+
+CREATE TABLE IF NOT EXISTS "ActivityLog" (
+    "id"        TEXT         NOT NULL,
+    "caseId"    TEXT         NOT NULL,
+    "action"    TEXT         NOT NULL,
+    "createdAt" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "userId"    TEXT         NOT NULL,
+    CONSTRAINT "ActivityLog_pkey" PRIMARY KEY ("id"),
+    CONSTRAINT "ActivityLog_caseId_fkey" FOREIGN KEY ("caseId") REFERENCES "Case" ("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "ActivityLog_userId_fkey" FOREIGN KEY ("userId") REFERENCES "User" ("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "ActivityLog_caseId_idx" ON "ActivityLog" ("caseId");
+CREATE INDEX IF NOT EXISTS "ActivityLog_userId_idx" ON "ActivityLog" ("userId");
+
+CREATE TABLE IF NOT EXISTS "Photo" (
+    "id"              TEXT         NOT NULL,
+    "url"             TEXT         NOT NULL,
+    "caseId"          TEXT         NOT NULL,
+    "uploadedViaLink" BOOLEAN      NOT NULL DEFAULT false,
+    "comment"         TEXT,
+    "customName"      TEXT,
+    "createdAt"       TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "Photo_pkey" PRIMARY KEY ("id"),
+    CONSTRAINT "Photo_caseId_fkey" FOREIGN KEY ("caseId") REFERENCES "Case" ("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "Photo_caseId_idx" ON "Photo" ("caseId");
+
+CREATE TABLE IF NOT EXISTS "Document" (
+    "id"              TEXT         NOT NULL,
+    "url"             TEXT         NOT NULL,
+    "fileName"        TEXT         NOT NULL,
+    "customName"      TEXT,
+    "caseId"          TEXT         NOT NULL,
+    "uploadedViaLink" BOOLEAN      NOT NULL DEFAULT false,
+    "createdAt"       TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "Document_pkey" PRIMARY KEY ("id"),
+    CONSTRAINT "Document_caseId_fkey" FOREIGN KEY ("caseId") REFERENCES "Case" ("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "Document_caseId_idx" ON "Document" ("caseId");
+
+CREATE TABLE IF NOT EXISTS "LightFixtureType" (
+    "id"          TEXT             NOT NULL,
+    "name"        TEXT             NOT NULL,
+    "description" TEXT,
+    "createdAt"   TIMESTAMP(3)     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "SKU"         TEXT,
+    "wattage"     DOUBLE PRECISION,
+    "imageUrl"    TEXT,
+    CONSTRAINT "LightFixtureType_pkey" PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "LightFixtureType_name_key" ON "LightFixtureType" ("name");
+
+CREATE TABLE IF NOT EXISTS "CaseFixtureCount" (
+    "id"            TEXT    NOT NULL,
+    "caseId"        TEXT    NOT NULL,
+    "fixtureTypeId" TEXT    NOT NULL,
+    "count"         INTEGER NOT NULL DEFAULT 0,
+    CONSTRAINT "CaseFixtureCount_pkey" PRIMARY KEY ("id"),
+    CONSTRAINT "CaseFixtureCount_caseId_fkey" FOREIGN KEY ("caseId") REFERENCES "Case" ("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "CaseFixtureCount_fixtureTypeId_fkey" FOREIGN KEY ("fixtureTypeId") REFERENCES "LightFixtureType" ("id") ON DELETE RESTRICT ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "CaseFixtureCount_caseId_idx" ON "CaseFixtureCount" ("caseId");
+CREATE INDEX IF NOT EXISTS "CaseFixtureCount_fixtureTypeId_idx" ON "CaseFixtureCount" ("fixtureTypeId");
+
+CREATE TABLE IF NOT EXISTS "InstallationDetail" (
+    "id"            TEXT             NOT NULL,
+    "caseId"        TEXT             NOT NULL,
+    "ceilingHeight" DOUBLE PRECISION,
+    "notes"         TEXT,
+    "createdAt"     TIMESTAMP(3)     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updatedAt"     TIMESTAMP(3)     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "InstallationDetail_pkey" PRIMARY KEY ("id"),
+    CONSTRAINT "InstallationDetail_caseId_fkey" FOREIGN KEY ("caseId") REFERENCES "Case" ("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "InstallationDetail_caseId_key" ON "InstallationDetail" ("caseId");
+
+CREATE TABLE IF NOT EXISTS "InstallationTag" (
+    "id"        TEXT         NOT NULL,
+    "name"      TEXT         NOT NULL,
+    "createdAt" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "InstallationTag_pkey" PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "InstallationTag_name_key" ON "InstallationTag" ("name");
+
+CREATE TABLE IF NOT EXISTS "InstallationDetailTag" (
+    "id"                   TEXT NOT NULL,
+    "installationDetailId" TEXT NOT NULL,
+    "tagId"                TEXT NOT NULL,
+    CONSTRAINT "InstallationDetailTag_pkey" PRIMARY KEY ("id"),
+    CONSTRAINT "InstallationDetailTag_installationDetailId_fkey" FOREIGN KEY ("installationDetailId") REFERENCES "InstallationDetail" ("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "InstallationDetailTag_tagId_fkey" FOREIGN KEY ("tagId") REFERENCES "InstallationTag" ("id") ON DELETE RESTRICT ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "InstallationDetailTag_installationDetailId_idx" ON "InstallationDetailTag" ("installationDetailId");
+CREATE INDEX IF NOT EXISTS "InstallationDetailTag_tagId_idx" ON "InstallationDetailTag" ("tagId");
+
+CREATE TABLE IF NOT EXISTS "OnSiteVisit" (
+    "id"        TEXT         NOT NULL,
+    "caseId"    TEXT         NOT NULL,
+    "createdAt" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "OnSiteVisit_pkey" PRIMARY KEY ("id"),
+    CONSTRAINT "OnSiteVisit_caseId_fkey" FOREIGN KEY ("caseId") REFERENCES "Case" ("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "OnSiteVisit_caseId_key" ON "OnSiteVisit" ("caseId");
+
+CREATE TABLE IF NOT EXISTS "Product" (
+    "id"           TEXT             NOT NULL,
+    "name"         TEXT             NOT NULL,
+    "wattage"      DOUBLE PRECISION NOT NULL DEFAULT 0,
+    "description2" TEXT,
+    "category"     TEXT,
+    CONSTRAINT "Product_pkey" PRIMARY KEY ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "OnSiteLocationTag" (
+    "id"        TEXT         NOT NULL,
+    "name"      TEXT         NOT NULL,
+    "createdAt" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "OnSiteLocationTag_pkey" PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "OnSiteLocationTag_name_key" ON "OnSiteLocationTag" ("name");
+
+CREATE TABLE IF NOT EXISTS "OnSiteVisitRoom" (
+    "id"              TEXT         NOT NULL,
+    "onSiteVisitId"   TEXT         NOT NULL,
+    "location"        TEXT         NOT NULL,
+    "locationTagId"   TEXT,
+    "lightingIssue"   TEXT         NOT NULL DEFAULT '',
+    "customerRequest" TEXT         NOT NULL DEFAULT '',
+    "mountingKitQty"  TEXT         NOT NULL DEFAULT '',
+    "motionSensorQty" INTEGER      NOT NULL DEFAULT 0,
+    "createdAt"       TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "ceilingHeight"   INTEGER,
+    CONSTRAINT "OnSiteVisitRoom_pkey" PRIMARY KEY ("id"),
+    CONSTRAINT "OnSiteVisitRoom_onSiteVisitId_fkey" FOREIGN KEY ("onSiteVisitId") REFERENCES "OnSiteVisit" ("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "OnSiteVisitRoom_locationTagId_fkey" FOREIGN KEY ("locationTagId") REFERENCES "OnSiteLocationTag" ("id") ON DELETE SET NULL ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "OnSiteVisitRoom_onSiteVisitId_idx" ON "OnSiteVisitRoom" ("onSiteVisitId");
+CREATE INDEX IF NOT EXISTS "OnSiteVisitRoom_locationTagId_idx" ON "OnSiteVisitRoom" ("locationTagId");
+
+CREATE TABLE IF NOT EXISTS "OnSiteExistingProduct" (
+    "id"            TEXT    NOT NULL,
+    "roomId"        TEXT    NOT NULL,
+    "productId"     TEXT    NOT NULL,
+    "quantity"      INTEGER NOT NULL,
+    "bypassBallast" BOOLEAN NOT NULL DEFAULT false,
+    CONSTRAINT "OnSiteExistingProduct_pkey" PRIMARY KEY ("id"),
+    CONSTRAINT "OnSiteExistingProduct_roomId_fkey" FOREIGN KEY ("roomId") REFERENCES "OnSiteVisitRoom" ("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "OnSiteExistingProduct_productId_fkey" FOREIGN KEY ("productId") REFERENCES "Product" ("id") ON DELETE RESTRICT ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "OnSiteExistingProduct_roomId_idx" ON "OnSiteExistingProduct" ("roomId");
+CREATE INDEX IF NOT EXISTS "OnSiteExistingProduct_productId_idx" ON "OnSiteExistingProduct" ("productId");
+
+-- "productId" holds a LightFixtureType id.
+CREATE TABLE IF NOT EXISTS "OnSiteSuggestedProduct" (
+    "id"        TEXT    NOT NULL,
+    "roomId"    TEXT    NOT NULL,
+    "productId" TEXT    NOT NULL,
+    "quantity"  INTEGER NOT NULL,
+    CONSTRAINT "OnSiteSuggestedProduct_pkey" PRIMARY KEY ("id"),
+    CONSTRAINT "OnSiteSuggestedProduct_roomId_fkey" FOREIGN KEY ("roomId") REFERENCES "OnSiteVisitRoom" ("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "OnSiteSuggestedProduct_roomId_idx" ON "OnSiteSuggestedProduct" ("roomId");
+CREATE INDEX IF NOT EXISTS "OnSiteSuggestedProduct_productId_idx" ON "OnSiteSuggestedProduct" ("productId");
+
+CREATE TABLE IF NOT EXISTS "OnSiteVisitPhoto" (
+    "id"        TEXT         NOT NULL,
+    "roomId"    TEXT         NOT NULL,
+    "url"       TEXT         NOT NULL,
+    "comment"   TEXT         NOT NULL DEFAULT '',
+    "createdAt" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "OnSiteVisitPhoto_pkey" PRIMARY KEY ("id"),
+    CONSTRAINT "OnSiteVisitPhoto_roomId_fkey" FOREIGN KEY ("roomId") REFERENCES "OnSiteVisitRoom" ("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "OnSiteVisitPhoto_roomId_idx" ON "OnSiteVisitPhoto" ("roomId");
+
+CREATE TABLE IF NOT EXISTS "OnSitePhotoTag" (
+    "id"        TEXT         NOT NULL,
+    "name"      TEXT         NOT NULL,
+    "createdAt" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "OnSitePhotoTag_pkey" PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "OnSitePhotoTag_name_key" ON "OnSitePhotoTag" ("name");
+
+CREATE TABLE IF NOT EXISTS "OnSiteVisitPhotoTagPivot" (
+    "id"      TEXT NOT NULL,
+    "photoId" TEXT NOT NULL,
+    "tagId"   TEXT NOT NULL,
+    CONSTRAINT "OnSiteVisitPhotoTagPivot_pkey" PRIMARY KEY ("id"),
+    CONSTRAINT "OnSiteVisitPhotoTagPivot_photoId_fkey" FOREIGN KEY ("photoId") REFERENCES "OnSiteVisitPhoto" ("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "OnSiteVisitPhotoTagPivot_tagId_fkey" FOREIGN KEY ("tagId") REFERENCES "OnSitePhotoTag" ("id") ON DELETE RESTRICT ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "OnSiteVisitPhotoTagPivot_photoId_idx" ON "OnSiteVisitPhotoTagPivot" ("photoId");
+CREATE INDEX IF NOT EXISTS "OnSiteVisitPhotoTagPivot_tagId_idx" ON "OnSiteVisitPhotoTagPivot" ("tagId");
+
+CREATE TABLE IF NOT EXISTS "QuoteCounter" (
+    "id"        TEXT         NOT NULL,
+    "caseId"    TEXT         NOT NULL,
+    "count"     INTEGER      NOT NULL DEFAULT 1,
+    "updatedAt" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "QuoteCounter_pkey" PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "QuoteCounter_caseId_key" ON "QuoteCounter" ("caseId");
+
+CREATE TABLE IF NOT EXISTS "PaybackSetting" (
+    "id"        TEXT             NOT NULL,
+    "caseId"    TEXT             NOT NULL,
+    "value"     DOUBLE PRECISION NOT NULL,
+    "createdAt" TIMESTAMP(3)     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updatedAt" TIMESTAMP(3)     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "PaybackSetting_pkey" PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "PaybackSetting_caseId_key" ON "PaybackSetting" ("caseId");
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "RevokedToken";
+DROP TABLE IF EXISTS "PasswordResetToken";
+ALTER TABLE "User" DROP COLUMN IF EXISTS "passwordChangedAt";
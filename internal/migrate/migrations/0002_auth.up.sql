@@ -0,0 +1,26 @@
+-- IF NOT EXISTS throughout: databases that ran the earlier AutoMigrate builds
+-- may already have these objects.
+
+ALTER TABLE "User" ADD COLUMN IF NOT EXISTS "passwordChangedAt" TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS "PasswordResetToken" (
+    "id"        TEXT        NOT NULL,
+    "userId"    TEXT        NOT NULL,
+    "tokenHash" TEXT        NOT NULL,
+    "expiresAt" TIMESTAMPTZ NOT NULL,
+    "usedAt"    TIMESTAMPTZ,
+    "createdAt" TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT "PasswordResetToken_pkey" PRIMARY KEY ("id"),
+    CONSTRAINT "PasswordResetToken_userId_fkey" FOREIGN KEY ("userId") REFERENCES "User" ("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "PasswordResetToken_userId_idx" ON "PasswordResetToken" ("userId");
+CREATE UNIQUE INDEX IF NOT EXISTS "PasswordResetToken_tokenHash_key" ON "PasswordResetToken" ("tokenHash");
+
+-- Logged-out access tokens, keyed by jti, kept until they would have expired.
+CREATE TABLE IF NOT EXISTS "RevokedToken" (
+    "id"        TEXT        NOT NULL,
+    "expiresAt" TIMESTAMPTZ NOT NULL,
+    "createdAt" TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT "RevokedToken_pkey" PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "RevokedToken_expiresAt_idx" ON "RevokedToken" ("expiresAt");
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS "CaseStatusChange" (
+    "id"         TEXT        NOT NULL,
+    "caseId"     TEXT        NOT NULL,
+    "fromStatus" TEXT        NOT NULL,
+    "toStatus"   TEXT        NOT NULL,
+    "note"       TEXT,
+    "userId"     TEXT        NOT NULL,
+    "createdAt"  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT "CaseStatusChange_pkey" PRIMARY KEY ("id"),
+    CONSTRAINT "CaseStatusChange_caseId_fkey" FOREIGN KEY ("caseId") REFERENCES "Case" ("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "CaseStatusChange_userId_fkey" FOREIGN KEY ("userId") REFERENCES "User" ("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "CaseStatusChange_caseId_idx" ON "CaseStatusChange" ("caseId");
+CREATE INDEX IF NOT EXISTS "CaseStatusChange_userId_idx" ON "CaseStatusChange" ("userId");
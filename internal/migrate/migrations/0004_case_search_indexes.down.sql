@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS "Case_updatedAt_idx";
+DROP INDEX IF EXISTS "Case_createdAt_idx";
+DROP INDEX IF EXISTS "Case_status_idx";
+DROP INDEX IF EXISTS "Case_schoolAddress_trgm_idx";
+DROP INDEX IF EXISTS "Case_emailAddress_trgm_idx";
+DROP INDEX IF EXISTS "Case_contactPerson_trgm_idx";
+DROP INDEX IF EXISTS "Case_schoolName_trgm_idx";
+DROP INDEX IF EXISTS "Case_customerName_trgm_idx";
@@ -0,0 +1,12 @@
+-- Backs the filters and ?q= search on GET /api/cases. pg_trgm GIN indexes make
+-- the ILIKE '%term%' lookups indexable.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS "Case_customerName_trgm_idx" ON "Case" USING gin ("customerName" gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS "Case_schoolName_trgm_idx" ON "Case" USING gin ("schoolName" gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS "Case_contactPerson_trgm_idx" ON "Case" USING gin ("contactPerson" gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS "Case_emailAddress_trgm_idx" ON "Case" USING gin ("emailAddress" gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS "Case_schoolAddress_trgm_idx" ON "Case" USING gin ("schoolAddress" gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS "Case_status_idx" ON "Case" ("status");
+CREATE INDEX IF NOT EXISTS "Case_createdAt_idx" ON "Case" ("createdAt");
+CREATE INDEX IF NOT EXISTS "Case_updatedAt_idx" ON "Case" ("updatedAt");
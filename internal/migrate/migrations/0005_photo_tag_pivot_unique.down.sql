@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "OnSiteVisitPhotoTagPivot_photoId_tagId_key";
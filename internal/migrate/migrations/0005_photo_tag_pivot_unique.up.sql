@@ -0,0 +1,8 @@
+-- One pivot row per (photo, tag) so tagging a photo twice is a no-op.
+DELETE FROM "OnSiteVisitPhotoTagPivot" a
+ USING "OnSiteVisitPhotoTagPivot" b
+ WHERE a."photoId" = b."photoId"
+   AND a."tagId" = b."tagId"
+   AND a."id" > b."id";
+
+CREATE UNIQUE INDEX IF NOT EXISTS "OnSiteVisitPhotoTagPivot_photoId_tagId_key" ON "OnSiteVisitPhotoTagPivot" ("photoId", "tagId");
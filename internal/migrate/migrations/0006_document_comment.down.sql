@@ -0,0 +1 @@
+ALTER TABLE "Document" DROP COLUMN IF EXISTS "comment";
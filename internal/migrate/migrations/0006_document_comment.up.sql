@@ -0,0 +1 @@
+ALTER TABLE "Document" ADD COLUMN IF NOT EXISTS "comment" TEXT;
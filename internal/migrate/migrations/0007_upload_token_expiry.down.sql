@@ -0,0 +1 @@
+ALTER TABLE "Case" DROP COLUMN IF EXISTS "uploadTokenExpiresAt";
@@ -0,0 +1 @@
+ALTER TABLE "Case" ADD COLUMN IF NOT EXISTS "uploadTokenExpiresAt" TIMESTAMPTZ;
@@ -0,0 +1,11 @@
+ALTER TABLE "OnSiteVisitPhoto"
+    DROP COLUMN IF EXISTS "longitude",
+    DROP COLUMN IF EXISTS "latitude",
+    DROP COLUMN IF EXISTS "takenAt",
+    DROP COLUMN IF EXISTS "thumbnailUrl";
+
+ALTER TABLE "Photo"
+    DROP COLUMN IF EXISTS "longitude",
+    DROP COLUMN IF EXISTS "latitude",
+    DROP COLUMN IF EXISTS "takenAt",
+    DROP COLUMN IF EXISTS "thumbnailUrl";
@@ -0,0 +1,12 @@
+-- Thumbnail key plus EXIF capture time and position for uploaded photos.
+ALTER TABLE "Photo"
+    ADD COLUMN IF NOT EXISTS "thumbnailUrl" TEXT,
+    ADD COLUMN IF NOT EXISTS "takenAt"      TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS "latitude"     DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS "longitude"    DOUBLE PRECISION;
+
+ALTER TABLE "OnSiteVisitPhoto"
+    ADD COLUMN IF NOT EXISTS "thumbnailUrl" TEXT,
+    ADD COLUMN IF NOT EXISTS "takenAt"      TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS "latitude"     DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS "longitude"    DOUBLE PRECISION;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS "Product_name_key";
+ALTER TABLE "LightFixtureType" DROP COLUMN IF EXISTS "archivedAt";
+ALTER TABLE "Product" DROP COLUMN IF EXISTS "archivedAt";
+ALTER TABLE "Product" DROP COLUMN IF EXISTS "SKU";
@@ -0,0 +1,26 @@
+-- Product gains the optional SKU LightFixtureType already has.
+ALTER TABLE "Product" ADD COLUMN IF NOT EXISTS "SKU" TEXT;
+
+-- Archived catalog rows stay referenced by past visits but leave the pickers.
+ALTER TABLE "Product" ADD COLUMN IF NOT EXISTS "archivedAt" TIMESTAMPTZ;
+ALTER TABLE "LightFixtureType" ADD COLUMN IF NOT EXISTS "archivedAt" TIMESTAMPTZ;
+
+-- Product names were never unique. Renaming duplicates here would change
+-- names surveyors know without telling anyone, so the migration stops and
+-- lists them instead; rename or merge those rows and run it again.
+DO $$
+DECLARE
+    dupes TEXT;
+BEGIN
+    SELECT string_agg(format('%L (ids %s)', "name", ids), '; ' ORDER BY "name")
+      INTO dupes
+      FROM (SELECT "name", string_agg("id", ', ' ORDER BY "id") AS ids
+              FROM "Product"
+             GROUP BY "name"
+            HAVING count(*) > 1) d;
+    IF dupes IS NOT NULL THEN
+        RAISE EXCEPTION 'Product names must be unique before the catalog can be administered; duplicates: %', dupes;
+    END IF;
+END
+$$;
+CREATE UNIQUE INDEX IF NOT EXISTS "Product_name_key" ON "Product" ("name");
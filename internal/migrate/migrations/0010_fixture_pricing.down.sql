@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS "Accessory";
+ALTER TABLE "LightFixtureType" DROP COLUMN IF EXISTS "laborHours";
+ALTER TABLE "LightFixtureType" DROP COLUMN IF EXISTS "listPrice";
+ALTER TABLE "LightFixtureType" DROP COLUMN IF EXISTS "unitCost";
@@ -0,0 +1,25 @@
+-- Prices for the bill of materials. NULL means not priced yet.
+ALTER TABLE "LightFixtureType" ADD COLUMN IF NOT EXISTS "unitCost"   NUMERIC(12,2);
+ALTER TABLE "LightFixtureType" ADD COLUMN IF NOT EXISTS "listPrice"  NUMERIC(12,2);
+ALTER TABLE "LightFixtureType" ADD COLUMN IF NOT EXISTS "laborHours" NUMERIC(8,2);
+
+-- One priced item per room accessory count: "mountingKit" prices
+-- OnSiteVisitRoom."mountingKitQty", "motionSensor" "motionSensorQty".
+CREATE TABLE IF NOT EXISTS "Accessory" (
+    "id"         TEXT          NOT NULL,
+    "kind"       TEXT          NOT NULL,
+    "name"       TEXT          NOT NULL,
+    "SKU"        TEXT,
+    "unitCost"   NUMERIC(12,2),
+    "listPrice"  NUMERIC(12,2),
+    "laborHours" NUMERIC(8,2),
+    "updatedAt"  TIMESTAMPTZ   NOT NULL DEFAULT now(),
+    CONSTRAINT "Accessory_pkey" PRIMARY KEY ("id"),
+    CONSTRAINT "Accessory_kind_check" CHECK ("kind" IN ('mountingKit', 'motionSensor'))
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "Accessory_kind_key" ON "Accessory" ("kind");
+
+INSERT INTO "Accessory" ("id", "kind", "name")
+VALUES ('accessory-mounting-kit', 'mountingKit', 'Mounting kit'),
+       ('accessory-motion-sensor', 'motionSensor', 'Motion sensor')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,20 @@
+-- Immutable quote revisions. "revision" is the case's QuoteCounter value
+-- when the quote was made; "snapshot" holds the priced bill of materials as
+-- it stood then, and the totals are copied out for listing.
+CREATE TABLE IF NOT EXISTS "Quote" (
+    "id"          TEXT          NOT NULL,
+    "caseId"      TEXT          NOT NULL,
+    "number"      TEXT          NOT NULL,
+    "revision"    INTEGER       NOT NULL,
+    "note"        TEXT,
+    "totalCost"   NUMERIC(12,2) NOT NULL,
+    "totalPrice"  NUMERIC(12,2) NOT NULL,
+    "laborHours"  NUMERIC(10,2) NOT NULL,
+    "snapshot"    JSONB         NOT NULL,
+    "createdById" TEXT          NOT NULL,
+    "createdAt"   TIMESTAMPTZ   NOT NULL DEFAULT now(),
+    CONSTRAINT "Quote_pkey" PRIMARY KEY ("id"),
+    CONSTRAINT "Quote_caseId_fkey" FOREIGN KEY ("caseId") REFERENCES "Case" ("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "Quote_caseId_revision_key" ON "Quote" ("caseId", "revision");
+CREATE UNIQUE INDEX IF NOT EXISTS "Quote_number_key" ON "Quote" ("number");
@@ -0,0 +1,25 @@
+-- Rules for automatic suggestions: existing lighting of "productId", or of
+-- products in "category" when the rule names no product, is replaced by
+-- "fixtureTypeId". The ceiling height bounds (inclusive, in the unit of
+-- OnSiteVisitRoom."ceilingHeight") and "locationTagId" narrow a rule to
+-- matching rooms.
+CREATE TABLE IF NOT EXISTS "FixtureRecommendation" (
+    "id"               TEXT        NOT NULL,
+    "productId"        TEXT,
+    "category"         TEXT,
+    "minCeilingHeight" INTEGER,
+    "maxCeilingHeight" INTEGER,
+    "locationTagId"    TEXT,
+    "fixtureTypeId"    TEXT        NOT NULL,
+    "priority"         INTEGER     NOT NULL DEFAULT 0,
+    "createdAt"        TIMESTAMPTZ NOT NULL DEFAULT now(),
+    "updatedAt"        TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT "FixtureRecommendation_pkey" PRIMARY KEY ("id"),
+    CONSTRAINT "FixtureRecommendation_match_check" CHECK ("productId" IS NOT NULL OR "category" IS NOT NULL),
+    CONSTRAINT "FixtureRecommendation_ceiling_check" CHECK ("minCeilingHeight" <= "maxCeilingHeight"),
+    CONSTRAINT "FixtureRecommendation_productId_fkey" FOREIGN KEY ("productId") REFERENCES "Product" ("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "FixtureRecommendation_locationTagId_fkey" FOREIGN KEY ("locationTagId") REFERENCES "OnSiteLocationTag" ("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "FixtureRecommendation_fixtureTypeId_fkey" FOREIGN KEY ("fixtureTypeId") REFERENCES "LightFixtureType" ("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "FixtureRecommendation_productId_idx" ON "FixtureRecommendation" ("productId");
+CREATE INDEX IF NOT EXISTS "FixtureRecommendation_fixtureTypeId_idx" ON "FixtureRecommendation" ("fixtureTypeId");
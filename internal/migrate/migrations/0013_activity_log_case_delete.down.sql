@@ -0,0 +1,2 @@
+DELETE FROM "ActivityLog" WHERE "caseId" IS NULL;
+ALTER TABLE "ActivityLog" ALTER COLUMN "caseId" SET NOT NULL;
@@ -0,0 +1,3 @@
+-- A deleted case takes its ActivityLog rows with it; the deletion itself is
+-- recorded in a row with no "caseId" that names the case in "action".
+ALTER TABLE "ActivityLog" ALTER COLUMN "caseId" DROP NOT NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS "LightFixtureType_SKU_key";
+DROP INDEX IF EXISTS "Product_SKU_key";
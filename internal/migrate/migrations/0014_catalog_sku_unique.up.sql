@@ -0,0 +1,27 @@
+-- A SKU names one catalog item, so imports can match on it. Existing
+-- duplicates are listed rather than cleared, as for product names in 0009;
+-- fix those rows and run the migration again.
+DO $$
+DECLARE
+    dupes TEXT;
+BEGIN
+    SELECT string_agg(format('%s %L (ids %s)', tbl, "SKU", ids), '; ' ORDER BY tbl, "SKU")
+      INTO dupes
+      FROM (SELECT 'Product' AS tbl, "SKU", string_agg("id", ', ' ORDER BY "id") AS ids
+              FROM "Product"
+             WHERE "SKU" IS NOT NULL
+             GROUP BY "SKU"
+            HAVING count(*) > 1
+             UNION ALL
+            SELECT 'LightFixtureType', "SKU", string_agg("id", ', ' ORDER BY "id")
+              FROM "LightFixtureType"
+             WHERE "SKU" IS NOT NULL
+             GROUP BY "SKU"
+            HAVING count(*) > 1) d;
+    IF dupes IS NOT NULL THEN
+        RAISE EXCEPTION 'catalog SKUs must be unique; duplicates: %', dupes;
+    END IF;
+END
+$$;
+CREATE UNIQUE INDEX IF NOT EXISTS "Product_SKU_key" ON "Product" ("SKU") WHERE "SKU" IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS "LightFixtureType_SKU_key" ON "LightFixtureType" ("SKU") WHERE "SKU" IS NOT NULL;
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/lucsky/cuid"
@@ -14,6 +15,8 @@ Prisma → Go mapping notes
 - Float                         → float64
 - Int                           → int
 - uuid()                        → we model as text; if you prefer DB default, set type:uuid;default:gen_random_uuid().
+- Table/column names            → Prisma's: "Case"."customerName", "OnSiteVisitRoom"."onSiteVisitId" (db.PrismaNamer);
+                                  use an explicit column tag where Prisma diverges (LightFixtureType."SKU", Product."description2").
 */
 
 // ---------- Helpers ----------
@@ -42,44 +45,65 @@ const (
 
 type User struct {
 	BaseStringID
-	Email        string        `gorm:"uniqueIndex;not null" json:"email"`
-	Name         *string       `json:"name,omitempty"`
-	Password     *string       `json:"password,omitempty"`
-	Role         Role          `gorm:"type:text;default:USER;not null" json:"role"`
-	CreatedAt    time.Time     `gorm:"autoCreateTime" json:"createdAt"`
-	UpdatedAt    time.Time     `gorm:"autoUpdateTime" json:"updatedAt"`
-	ActivityLogs []ActivityLog `gorm:"foreignKey:UserID;references:ID" json:"activityLogs,omitempty"`
-	Cases        []Case        `gorm:"foreignKey:UserID;references:ID" json:"cases,omitempty"`
+	Email             string        `gorm:"uniqueIndex;not null" json:"email"`
+	Name              *string       `json:"name,omitempty"`
+	Password          *string       `json:"-"` // bcrypt hash; never serialized
+	Role              Role          `gorm:"type:text;default:USER;not null" json:"role"`
+	CreatedAt         time.Time     `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt         time.Time     `gorm:"autoUpdateTime" json:"updatedAt"`
+	PasswordChangedAt *time.Time    `json:"-"` // tokens issued before this are rejected
+	ActivityLogs      []ActivityLog `gorm:"foreignKey:UserID;references:ID" json:"activityLogs,omitempty"`
+	Cases             []Case        `gorm:"foreignKey:UserID;references:ID" json:"cases,omitempty"`
+}
+
+// ---------- Auth: password reset / revoked tokens ----------
+
+type PasswordResetToken struct {
+	BaseStringID
+	UserID    string     `gorm:"index;not null" json:"userId"`
+	TokenHash string     `gorm:"uniqueIndex;not null" json:"-"` // sha256 of the emailed token
+	ExpiresAt time.Time  `gorm:"not null" json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt,omitempty"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	User      User       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+}
+
+// RevokedToken records logged-out access tokens by their jti until they expire.
+type RevokedToken struct {
+	BaseStringID
+	ExpiresAt time.Time `gorm:"index;not null" json:"expiresAt"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
 }
 
 // ---------- Case ----------
 
 type Case struct {
 	BaseStringID
-	UserID               string    `gorm:"index;not null" json:"userId"`
-	CustomerName         string    `json:"customerName"`
-	ProjectDetails       string    `json:"projectDetails"`
-	UploadToken          string    `gorm:"type:text" json:"uploadToken"` // prisma default(uuid()); keep as text
-	Status               string    `gorm:"default:New;not null" json:"status"`
-	CreatedAt            time.Time `gorm:"autoCreateTime" json:"createdAt"`
-	UpdatedAt            time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
-	SchoolName           string    `json:"schoolName"`
-	ContactPerson        string    `json:"contactPerson"`
-	EmailAddress         string    `json:"emailAddress"`
-	PhoneNumber          string    `json:"phoneNumber"`
-	SchoolAddress        string    `json:"schoolAddress"`
-	Num2FtLinearHighBay  int       `gorm:"default:0" json:"num2FtLinearHighBay"`
-	Num150WUFOHighBay    int       `gorm:"default:0" json:"num150WUFOHighBay"`
-	Num240WUFOHighBay    int       `gorm:"default:0" json:"num240WUFOHighBay"`
-	Num2x2LEDPanel       int       `gorm:"default:0" json:"num2x2LEDPanel"`
-	Num2x4LEDPanel       int       `gorm:"default:0" json:"num2x4LEDPanel"`
-	Num1x4LEDPanel       int       `gorm:"default:0" json:"num1x4LEDPanel"`
-	Num4FtStripLight     int       `gorm:"default:0" json:"num4FtStripLight"`
-	LightingPurpose      string    `json:"lightingPurpose"`
-	FacilitiesUsedIn     string    `json:"facilitiesUsedIn"`
-	InstallationService  string    `json:"installationService"`
-	OperationDaysPerYear int       `gorm:"default:0" json:"operationDaysPerYear"`
-	OperationHoursPerDay int       `gorm:"default:0" json:"operationHoursPerDay"`
+	UserID               string     `gorm:"index;not null" json:"userId"`
+	CustomerName         string     `json:"customerName"`
+	ProjectDetails       string     `json:"projectDetails"`
+	UploadToken          string     `gorm:"type:text" json:"uploadToken"` // prisma default(uuid()); keep as text
+	UploadTokenExpiresAt *time.Time `json:"uploadTokenExpiresAt"`         // nil: the link does not expire
+	Status               string     `gorm:"default:New;not null" json:"status"`
+	CreatedAt            time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt            time.Time  `gorm:"autoUpdateTime" json:"updatedAt"`
+	SchoolName           string     `json:"schoolName"`
+	ContactPerson        string     `json:"contactPerson"`
+	EmailAddress         string     `json:"emailAddress"`
+	PhoneNumber          string     `json:"phoneNumber"`
+	SchoolAddress        string     `json:"schoolAddress"`
+	Num2FtLinearHighBay  int        `gorm:"default:0" json:"num2FtLinearHighBay"`
+	Num150WUFOHighBay    int        `gorm:"default:0" json:"num150WUFOHighBay"`
+	Num240WUFOHighBay    int        `gorm:"default:0" json:"num240WUFOHighBay"`
+	Num2x2LEDPanel       int        `gorm:"default:0" json:"num2x2LEDPanel"`
+	Num2x4LEDPanel       int        `gorm:"default:0" json:"num2x4LEDPanel"`
+	Num1x4LEDPanel       int        `gorm:"default:0" json:"num1x4LEDPanel"`
+	Num4FtStripLight     int        `gorm:"default:0" json:"num4FtStripLight"`
+	LightingPurpose      string     `json:"lightingPurpose"`
+	FacilitiesUsedIn     string     `json:"facilitiesUsedIn"`
+	InstallationService  string     `json:"installationService"`
+	OperationDaysPerYear int        `gorm:"default:0" json:"operationDaysPerYear"`
+	OperationHoursPerDay int        `gorm:"default:0" json:"operationHoursPerDay"`
 
 	// Relations
 	User               User                `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;" json:"-"`
@@ -95,7 +119,7 @@ type Case struct {
 
 type ActivityLog struct {
 	BaseStringID
-	CaseID    string    `gorm:"index;not null" json:"caseId"`
+	CaseID    *string   `gorm:"index" json:"caseId"` // nil for a case deletion
 	Action    string    `json:"action"`
 	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
 	UserID    string    `gorm:"index;not null" json:"userId"`
@@ -103,17 +127,36 @@ type ActivityLog struct {
 	User      User      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
 }
 
+// ---------- CaseStatusChange ----------
+
+// CaseStatusChange is the audit trail of Case.status transitions.
+type CaseStatusChange struct {
+	BaseStringID
+	CaseID     string    `gorm:"index;not null" json:"caseId"`
+	FromStatus string    `gorm:"not null" json:"fromStatus"`
+	ToStatus   string    `gorm:"not null" json:"toStatus"`
+	Note       *string   `json:"note,omitempty"`
+	UserID     string    `gorm:"index;not null" json:"userId"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	Case       Case      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	User       User      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+}
+
 // ---------- Photo / Document ----------
 
 type Photo struct {
 	BaseStringID
-	URL             string    `json:"url"`
-	CaseID          string    `gorm:"index;not null" json:"caseId"`
-	UploadedViaLink bool      `gorm:"default:false" json:"uploadedViaLink"`
-	Comment         *string   `json:"comment,omitempty"`
-	CustomName      *string   `json:"customName,omitempty"`
-	CreatedAt       time.Time `gorm:"autoCreateTime" json:"createdAt"`
-	Case            Case      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	URL             string     `json:"url"`
+	CaseID          string     `gorm:"index;not null" json:"caseId"`
+	UploadedViaLink bool       `gorm:"default:false" json:"uploadedViaLink"`
+	Comment         *string    `json:"comment,omitempty"`
+	CustomName      *string    `json:"customName,omitempty"`
+	ThumbnailURL    *string    `json:"thumbnailUrl,omitempty"`
+	TakenAt         *time.Time `json:"takenAt,omitempty"` // EXIF capture time
+	Latitude        *float64   `json:"latitude,omitempty"`
+	Longitude       *float64   `json:"longitude,omitempty"`
+	CreatedAt       time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	Case            Case       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
 }
 
 type Document struct {
@@ -121,6 +164,7 @@ type Document struct {
 	URL             string    `json:"url"`
 	FileName        string    `json:"fileName"`
 	CustomName      *string   `json:"customName,omitempty"`
+	Comment         *string   `json:"comment,omitempty"`
 	CaseID          string    `gorm:"index;not null" json:"caseId"`
 	UploadedViaLink bool      `gorm:"default:false" json:"uploadedViaLink"`
 	CreatedAt       time.Time `gorm:"autoCreateTime" json:"createdAt"`
@@ -134,9 +178,13 @@ type LightFixtureType struct {
 	Name          string             `gorm:"uniqueIndex;not null" json:"name"`
 	Description   *string            `json:"description,omitempty"`
 	CreatedAt     time.Time          `gorm:"autoCreateTime" json:"createdAt"`
-	SKU           *string            `json:"SKU,omitempty"`
+	SKU           *string            `gorm:"column:SKU" json:"SKU,omitempty"`
 	Wattage       *float64           `json:"wattage,omitempty"`
 	ImageURL      *string            `json:"imageUrl,omitempty"`
+	ArchivedAt    *time.Time         `json:"archivedAt,omitempty"` // hidden from the picker
+	UnitCost      *float64           `gorm:"type:numeric(12,2)" json:"unitCost,omitempty"`
+	ListPrice     *float64           `gorm:"type:numeric(12,2)" json:"listPrice,omitempty"`
+	LaborHours    *float64           `gorm:"type:numeric(8,2)" json:"laborHours,omitempty"`
 	FixtureCounts []CaseFixtureCount `gorm:"foreignKey:FixtureTypeID;references:ID" json:"fixtureCounts,omitempty"`
 }
 
@@ -190,14 +238,33 @@ type OnSiteVisit struct {
 
 type Product struct {
 	BaseStringID
-	Name        string  `json:"name"`
-	Wattage     float64 `json:"wattage"`
-	Description *string `json:"description,omitempty"`
-	Category    *string `json:"category,omitempty"`
+	Name        string     `gorm:"uniqueIndex;not null" json:"name"`
+	Wattage     float64    `json:"wattage"`
+	Description *string    `gorm:"column:description2" json:"description,omitempty"` // column name inherited from the Prisma schema
+	Category    *string    `json:"category,omitempty"`
+	SKU         *string    `gorm:"column:SKU" json:"SKU,omitempty"`
+	ArchivedAt  *time.Time `json:"archivedAt,omitempty"` // hidden from the picker
 
 	ExistingProducts []OnSiteExistingProduct `gorm:"foreignKey:ProductID;references:ID" json:"existingProducts,omitempty"`
 }
 
+// FixtureRecommendation maps existing lighting to the fixture type that
+// replaces it: lines of ProductID, or of Category when ProductID is nil, in
+// rooms within the ceiling height bounds and carrying LocationTagID, when
+// those are set.
+type FixtureRecommendation struct {
+	BaseStringID
+	ProductID        *string   `gorm:"index" json:"productId,omitempty"`
+	Category         *string   `json:"category,omitempty"`
+	MinCeilingHeight *int      `json:"minCeilingHeight,omitempty"`
+	MaxCeilingHeight *int      `json:"maxCeilingHeight,omitempty"`
+	LocationTagID    *string   `json:"locationTagId,omitempty"`
+	FixtureTypeID    string    `gorm:"index;not null" json:"fixtureTypeId"`
+	Priority         int       `gorm:"not null;default:0" json:"priority"`
+	CreatedAt        time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt        time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
 type OnSiteVisitRoom struct {
 	BaseStringID
 	OnSiteVisitID   string    `gorm:"index;not null" json:"onSiteVisitId"`
@@ -237,12 +304,16 @@ type OnSiteSuggestedProduct struct {
 
 type OnSiteVisitPhoto struct {
 	BaseStringID
-	RoomID    string                     `gorm:"index;not null" json:"roomId"`
-	URL       string                     `json:"url"`
-	Comment   string                     `json:"comment"`
-	CreatedAt time.Time                  `gorm:"autoCreateTime" json:"createdAt"`
-	Room      OnSiteVisitRoom            `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
-	Tags      []OnSiteVisitPhotoTagPivot `gorm:"foreignKey:PhotoID;references:ID" json:"tags,omitempty"`
+	RoomID       string                     `gorm:"index;not null" json:"roomId"`
+	URL          string                     `json:"url"`
+	Comment      string                     `json:"comment"`
+	ThumbnailURL *string                    `json:"thumbnailUrl,omitempty"`
+	TakenAt      *time.Time                 `json:"takenAt,omitempty"` // EXIF capture time
+	Latitude     *float64                   `json:"latitude,omitempty"`
+	Longitude    *float64                   `json:"longitude,omitempty"`
+	CreatedAt    time.Time                  `gorm:"autoCreateTime" json:"createdAt"`
+	Room         OnSiteVisitRoom            `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-"`
+	Tags         []OnSiteVisitPhotoTagPivot `gorm:"foreignKey:PhotoID;references:ID" json:"tags,omitempty"`
 }
 
 type OnSitePhotoTag struct {
@@ -269,6 +340,23 @@ type OnSiteLocationTag struct {
 
 // ---------- QuoteCounter / PaybackSetting ----------
 
+// Accessory prices the per-room accessory counts; there is one row per kind.
+type Accessory struct {
+	BaseStringID
+	Kind       string    `gorm:"uniqueIndex;not null" json:"kind"` // AccessoryMountingKit or AccessoryMotionSensor
+	Name       string    `gorm:"not null" json:"name"`
+	SKU        *string   `gorm:"column:SKU" json:"SKU,omitempty"`
+	UnitCost   *float64  `gorm:"type:numeric(12,2)" json:"unitCost,omitempty"`
+	ListPrice  *float64  `gorm:"type:numeric(12,2)" json:"listPrice,omitempty"`
+	LaborHours *float64  `gorm:"type:numeric(8,2)" json:"laborHours,omitempty"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+const (
+	AccessoryMountingKit  = "mountingKit"  // OnSiteVisitRoom.MountingKitQty
+	AccessoryMotionSensor = "motionSensor" // OnSiteVisitRoom.MotionSensorQty
+)
+
 type QuoteCounter struct {
 	BaseStringID
 	CaseID    string    `gorm:"uniqueIndex;not null" json:"caseId"`
@@ -276,6 +364,23 @@ type QuoteCounter struct {
 	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
 }
 
+// Quote is an immutable priced revision of a case's bill of materials.
+// Revision comes from the case's QuoteCounter; Snapshot holds the lines and
+// totals as they stood.
+type Quote struct {
+	BaseStringID
+	CaseID      string          `gorm:"not null;uniqueIndex:Quote_caseId_revision_key,priority:1" json:"caseId"`
+	Number      string          `gorm:"uniqueIndex;not null" json:"number"` // Q-<case>-003
+	Revision    int             `gorm:"not null;uniqueIndex:Quote_caseId_revision_key,priority:2" json:"revision"`
+	Note        *string         `json:"note,omitempty"`
+	TotalCost   float64         `gorm:"type:numeric(12,2);not null" json:"totalCost"`
+	TotalPrice  float64         `gorm:"type:numeric(12,2);not null" json:"totalPrice"`
+	LaborHours  float64         `gorm:"type:numeric(10,2);not null" json:"laborHours"`
+	Snapshot    json.RawMessage `gorm:"type:jsonb;not null" json:"snapshot"`
+	CreatedByID string          `gorm:"not null" json:"createdById"`
+	CreatedAt   time.Time       `gorm:"autoCreateTime" json:"createdAt"`
+}
+
 type PaybackSetting struct {
 	BaseStringID
 	CaseID    string    `gorm:"uniqueIndex;not null" json:"caseId"`
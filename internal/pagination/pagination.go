@@ -0,0 +1,176 @@
+// Package pagination implements keyset (cursor) pagination and the response
+// envelope shared by list endpoints.
+//
+// A cursor encodes the sort key, direction, and the last row's sort value and
+// id. The next page continues strictly after that (value, id) pair, so rows
+// inserted mid-scroll never shift or duplicate results.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Kind tells the cursor how to turn its stored value back into a query arg.
+type Kind int
+
+const (
+	String Kind = iota
+	Time
+	Int
+	Float
+)
+
+// SortKey is a sortable column. Column must be non-null (wrap nullable
+// columns in COALESCE) because keyset comparisons skip NULLs.
+type SortKey struct {
+	Column string
+	Kind   Kind
+}
+
+// Params are parsed from ?limit=&cursor=&total=.
+type Params struct {
+	Limit     int
+	WithTotal bool
+	cursor    *cursor
+}
+
+type cursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d"`
+	Value string `json:"v"`
+	ID    string `json:"i"`
+}
+
+// ErrBadCursor is returned for cursors that are malformed or were issued for
+// a different sort order.
+var ErrBadCursor = errors.New("invalid cursor")
+
+// Parse reads paging params. limit falls back to defLimit when missing or
+// outside 1..maxLimit; total defaults to true.
+func Parse(c *gin.Context, defLimit, maxLimit int) (Params, error) {
+	p := Params{Limit: defLimit, WithTotal: true}
+	if v := c.Query("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 1 && n <= maxLimit {
+			p.Limit = n
+		}
+	}
+	if v := c.Query("total"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return p, fmt.Errorf("total must be true or false")
+		}
+		p.WithTotal = b
+	}
+	if v := c.Query("cursor"); v != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(v)
+		if err != nil {
+			return p, ErrBadCursor
+		}
+		var cur cursor
+		if err := json.Unmarshal(raw, &cur); err != nil || cur.ID == "" {
+			return p, ErrBadCursor
+		}
+		p.cursor = &cur
+	}
+	return p, nil
+}
+
+// After returns the keyset predicate that continues after the cursor, e.g.
+// `(c."createdAt", c."id") < (?, ?)`, or "" on the first page. sortName and
+// desc must match the current request's ordering.
+func (p Params) After(sortName string, key SortKey, idCol string, desc bool) (string, []any, error) {
+	v, id, ok, err := p.Position(sortName, key.Kind, desc)
+	if !ok || err != nil {
+		return "", nil, err
+	}
+	op := ">"
+	if desc {
+		op = "<"
+	}
+	return fmt.Sprintf("(%s, %s) %s (?, ?)", key.Column, idCol, op), []any{v, id}, nil
+}
+
+// Position returns the decoded sort value and id the cursor continues after,
+// for callers that page without SQL. ok is false on the first page.
+func (p Params) Position(sortName string, kind Kind, desc bool) (value any, id string, ok bool, err error) {
+	if p.cursor == nil {
+		return nil, "", false, nil
+	}
+	if p.cursor.Sort != sortName || p.cursor.Desc != desc {
+		return nil, "", false, ErrBadCursor
+	}
+	v, err := decodeValue(kind, p.cursor.Value)
+	if err != nil {
+		return nil, "", false, ErrBadCursor
+	}
+	return v, p.cursor.ID, true, nil
+}
+
+// OrderBy returns the ORDER BY clause matching After.
+func OrderBy(key SortKey, idCol string, desc bool) string {
+	dir := "ASC"
+	if desc {
+		dir = "DESC"
+	}
+	return fmt.Sprintf(" ORDER BY %s %s, %s %s", key.Column, dir, idCol, dir)
+}
+
+// Page is the envelope returned by every paginated list endpoint.
+type Page[T any] struct {
+	Items      []T     `json:"items"`
+	NextCursor *string `json:"nextCursor"`
+	HasMore    bool    `json:"hasMore"`
+	Total      *int64  `json:"total,omitempty"`
+}
+
+// NewPage trims rows fetched with LIMIT p.Limit+1 and builds the envelope.
+// position returns the sort value and id of a row for the next cursor.
+func NewPage[T any](rows []T, p Params, sortName string, desc bool, position func(T) (any, string)) Page[T] {
+	page := Page[T]{Items: rows}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	if len(rows) > p.Limit {
+		page.Items = rows[:p.Limit]
+		page.HasMore = true
+		v, id := position(page.Items[len(page.Items)-1])
+		raw, _ := json.Marshal(cursor{Sort: sortName, Desc: desc, Value: encodeValue(v), ID: id})
+		next := base64.RawURLEncoding.EncodeToString(raw)
+		page.NextCursor = &next
+	}
+	return page
+}
+
+func encodeValue(v any) string {
+	switch x := v.(type) {
+	case time.Time:
+		return x.UTC().Format(time.RFC3339Nano)
+	case *string:
+		if x == nil {
+			return ""
+		}
+		return *x
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+func decodeValue(k Kind, s string) (any, error) {
+	switch k {
+	case Time:
+		return time.Parse(time.RFC3339Nano, s)
+	case Int:
+		return strconv.ParseInt(s, 10, 64)
+	case Float:
+		return strconv.ParseFloat(s, 64)
+	default:
+		return s, nil
+	}
+}
@@ -0,0 +1,77 @@
+package pdf
+
+// Glyph widths of the standard Helvetica fonts for codes 32–126, in
+// thousandths of the font size (from the Adobe Core 14 AFM files).
+var (
+	helveticaWidths = [95]uint16{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // ' '–'/'
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556, // '0'–'?'
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778, // '@'–'O'
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556, // 'P'–'_'
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556, // '`'–'o'
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, // 'p'–'~'
+	}
+	helveticaBoldWidths = [95]uint16{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+)
+
+// Widths of the WinAnsi codes above 126 that differ between the fonts'
+// defaults; the rest are taken as 556, close enough for layout.
+var winAnsiWidths = map[byte][2]uint16{
+	0x85: {1000, 1000}, // …
+	0x91: {222, 278}, 0x92: {222, 278}, 0x93: {333, 500}, 0x94: {333, 500},
+	0x95: {350, 350},   // •
+	0x96: {556, 556},   // –
+	0x97: {1000, 1000}, // —
+	0xA0: {278, 278},   // no-break space
+	0xB0: {400, 400},   // °
+	0xD7: {584, 584},   // ×
+}
+
+// winAnsi maps the runes outside Latin-1 that WinAnsiEncoding has.
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// encode converts s to WinAnsiEncoding; runes it lacks become '?' and
+// control characters a space.
+func encode(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r < 0x20:
+			out = append(out, ' ')
+		case r < 0x7F || (r >= 0xA0 && r <= 0xFF):
+			out = append(out, byte(r))
+		default:
+			if b, ok := winAnsi[r]; ok {
+				out = append(out, b)
+			} else {
+				out = append(out, '?')
+			}
+		}
+	}
+	return out
+}
+
+func glyphWidth(f Font, b byte) uint16 {
+	if b >= 32 && b <= 126 {
+		if f == HelveticaBold {
+			return helveticaBoldWidths[b-32]
+		}
+		return helveticaWidths[b-32]
+	}
+	if w, ok := winAnsiWidths[b]; ok {
+		return w[f]
+	}
+	return 556
+}
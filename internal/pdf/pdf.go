@@ -0,0 +1,320 @@
+// Package pdf writes simple PDF documents with the standard library only:
+// text in the built-in Helvetica fonts, filled rectangles, lines and JPEG
+// images, on US Letter pages. Coordinates are in points from the top-left
+// corner of the page, y growing downwards.
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Page size in points.
+const (
+	PageWidth  = 612.0
+	PageHeight = 792.0
+)
+
+// Font is one of the two standard fonts every PDF reader has; nothing is
+// embedded.
+type Font int
+
+const (
+	Helvetica Font = iota
+	HelveticaBold
+)
+
+// Color is an RGB fill or stroke colour.
+type Color struct{ R, G, B uint8 }
+
+var (
+	Black = Color{0, 0, 0}
+	White = Color{255, 255, 255}
+)
+
+// ParseColor reads "#rrggbb".
+func ParseColor(s string) (Color, error) {
+	v, err := strconv.ParseUint(strings.TrimPrefix(s, "#"), 16, 32)
+	if err != nil || len(strings.TrimPrefix(s, "#")) != 6 {
+		return Color{}, fmt.Errorf("pdf: bad colour %q", s)
+	}
+	return Color{uint8(v >> 16), uint8(v >> 8), uint8(v)}, nil
+}
+
+func (c Color) op(fill bool) string {
+	op := "RG"
+	if fill {
+		op = "rg"
+	}
+	return fmt.Sprintf("%s %s %s %s\n", num(float64(c.R)/255), num(float64(c.G)/255), num(float64(c.B)/255), op)
+}
+
+// Image is a JPEG ready to be placed on any number of pages.
+type Image struct {
+	Width, Height int
+	data          []byte
+	colorSpace    string
+}
+
+var ErrUnsupportedImage = errors.New("pdf: only grey and colour JPEGs are supported")
+
+// NewImage wraps JPEG data. The bytes are embedded as they are, so PDF
+// readers decode them directly.
+func NewImage(data []byte) (*Image, error) {
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("pdf: %w", err)
+	}
+	img := &Image{Width: cfg.Width, Height: cfg.Height, data: data}
+	switch cfg.ColorModel {
+	case color.GrayModel:
+		img.colorSpace = "/DeviceGray"
+	case color.YCbCrModel:
+		img.colorSpace = "/DeviceRGB"
+	default:
+		return nil, ErrUnsupportedImage
+	}
+	return img, nil
+}
+
+// Document collects pages; drawing goes to the current page.
+type Document struct {
+	Title    string
+	pages    []*bytes.Buffer
+	cur      int
+	images   []*Image
+	imageIDs map[*Image]int // 1-based index in images
+}
+
+// New returns a document with no pages.
+func New() *Document {
+	return &Document{imageIDs: map[*Image]int{}}
+}
+
+// AddPage appends a page and makes it current.
+func (d *Document) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.cur = len(d.pages) - 1
+}
+
+// Pages is the number of pages so far.
+func (d *Document) Pages() int { return len(d.pages) }
+
+// SetPage makes the 0-based page i current, e.g. to add footers once the
+// page count is known.
+func (d *Document) SetPage(i int) { d.cur = i }
+
+func (d *Document) out() *bytes.Buffer {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	return d.pages[d.cur]
+}
+
+// Text draws s with its baseline at y.
+func (d *Document) Text(x, y float64, f Font, size float64, c Color, s string) {
+	w := d.out()
+	w.WriteString(c.op(true))
+	fmt.Fprintf(w, "BT /F%d %s Tf %s %s Td (", f+1, num(size), num(x), num(PageHeight-y))
+	for _, b := range encode(s) {
+		switch b {
+		case '(', ')', '\\':
+			w.WriteByte('\\')
+			w.WriteByte(b)
+		default:
+			w.WriteByte(b)
+		}
+	}
+	w.WriteString(") Tj ET\n")
+}
+
+// TextRight draws s so that it ends at x.
+func (d *Document) TextRight(x, y float64, f Font, size float64, c Color, s string) {
+	d.Text(x-Width(f, size, s), y, f, size, c, s)
+}
+
+// Rect fills a rectangle whose top-left corner is (x, y).
+func (d *Document) Rect(x, y, w, h float64, c Color) {
+	out := d.out()
+	out.WriteString(c.op(true))
+	fmt.Fprintf(out, "%s %s %s %s re f\n", num(x), num(PageHeight-y-h), num(w), num(h))
+}
+
+// Line strokes a straight line.
+func (d *Document) Line(x1, y1, x2, y2, width float64, c Color) {
+	out := d.out()
+	out.WriteString(c.op(false))
+	fmt.Fprintf(out, "%s w %s %s m %s %s l S\n", num(width), num(x1), num(PageHeight-y1), num(x2), num(PageHeight-y2))
+}
+
+// Image draws img scaled to w×h with its top-left corner at (x, y).
+func (d *Document) Image(img *Image, x, y, w, h float64) {
+	id, ok := d.imageIDs[img]
+	if !ok {
+		d.images = append(d.images, img)
+		id = len(d.images)
+		d.imageIDs[img] = id
+	}
+	fmt.Fprintf(d.out(), "q %s 0 0 %s %s %s cm /Im%d Do Q\n", num(w), num(h), num(x), num(PageHeight-y-h), id)
+}
+
+// Width is the advance width of s in points.
+func Width(f Font, size float64, s string) float64 {
+	total := 0
+	for _, b := range encode(s) {
+		total += int(glyphWidth(f, b))
+	}
+	return float64(total) * size / 1000
+}
+
+// Wrap breaks s into lines no wider than width, at spaces where it can and
+// inside words that are too long on their own. Newlines in s are kept.
+func Wrap(f Font, size, width float64, s string) []string {
+	var lines []string
+	for _, para := range strings.Split(s, "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			try := word
+			if line != "" {
+				try = line + " " + word
+			}
+			if Width(f, size, try) <= width {
+				line = try
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+			}
+			for Width(f, size, word) > width {
+				n := len([]rune(word)) - 1
+				for n > 1 && Width(f, size, string([]rune(word)[:n])) > width {
+					n--
+				}
+				lines = append(lines, string([]rune(word)[:n]))
+				word = string([]rune(word)[n:])
+			}
+			line = word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// WriteTo writes the document as PDF 1.4.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	cw := &countWriter{w: bufio.NewWriter(w)}
+	var offsets []int64
+	obj := func(body func()) {
+		offsets = append(offsets, cw.n)
+		fmt.Fprintf(cw, "%d 0 obj\n", len(offsets))
+		body()
+		cw.WriteString("\nendobj\n")
+	}
+	stream := func(dict string, data []byte) {
+		fmt.Fprintf(cw, "<< %s /Length %d >>\nstream\n", dict, len(data))
+		cw.Write(data)
+		cw.WriteString("\nendstream")
+	}
+
+	// Object numbers: 1 catalog, 2 page tree, 3–4 fonts, 5 info, then the
+	// images, then a page and its content stream per page.
+	imageBase := 6
+	pageBase := imageBase + len(d.images)
+	cw.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	obj(func() { cw.WriteString("<< /Type /Catalog /Pages 2 0 R >>") })
+	obj(func() {
+		kids := make([]string, len(d.pages))
+		for i := range d.pages {
+			kids[i] = fmt.Sprintf("%d 0 R", pageBase+2*i)
+		}
+		fmt.Fprintf(cw, "<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages))
+	})
+	for _, name := range []string{"Helvetica", "Helvetica-Bold"} {
+		obj(func() {
+			fmt.Fprintf(cw, "<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name)
+		})
+	}
+	obj(func() {
+		fmt.Fprintf(cw, "<< /Title %s /Producer (go-neon-api) /CreationDate (D:%s) >>",
+			textString(d.Title), time.Now().UTC().Format("20060102150405Z"))
+	})
+	for _, img := range d.images {
+		obj(func() {
+			stream(fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s /BitsPerComponent 8 /Filter /DCTDecode",
+				img.Width, img.Height, img.colorSpace), img.data)
+		})
+	}
+	var xobjects strings.Builder
+	for i := range d.images {
+		fmt.Fprintf(&xobjects, " /Im%d %d 0 R", i+1, imageBase+i)
+	}
+	for i, content := range d.pages {
+		obj(func() {
+			fmt.Fprintf(cw, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Contents %d 0 R "+
+				"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> /XObject <<%s >> >> >>",
+				num(PageWidth), num(PageHeight), pageBase+2*i+1, xobjects.String())
+		})
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		zw.Write(content.Bytes())
+		zw.Close()
+		obj(func() { stream("/Filter /FlateDecode", z.Bytes()) })
+	}
+
+	xref := cw.n
+	fmt.Fprintf(cw, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(cw, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(cw, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// textString encodes s as a PDF literal string.
+func textString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`)
+	return "(" + r.Replace(string(encode(s))) + ")"
+}
+
+// num formats a number to three decimals, without trailing zeros.
+func num(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+}
+
+// countWriter tracks the byte offsets the cross-reference table needs and
+// keeps the first write error.
+type countWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
+
+func (c *countWriter) WriteString(s string) (int, error) {
+	return c.Write([]byte(s))
+}
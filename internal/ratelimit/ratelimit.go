@@ -0,0 +1,76 @@
+// Package ratelimit throttles unauthenticated endpoints per client IP with a
+// fixed-window counter held in process memory. Behind several API instances
+// each enforces its own budget. The IP is gin's ClientIP, which honours
+// X-Forwarded-For only from the engine's trusted proxies.
+package ratelimit
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limiter allows up to limit requests per key in each window.
+type Limiter struct {
+	limit  int
+	window time.Duration
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+type bucket struct {
+	start time.Time
+	count int
+}
+
+func New(limit int, window time.Duration) *Limiter {
+	return &Limiter{limit: limit, window: window, buckets: map[string]*bucket{}}
+}
+
+// Allow counts a request for key. When the budget is spent it returns false
+// and how long until the window resets.
+func (l *Limiter) Allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Drop finished windows now and then so idle clients do not pile up.
+	if now.Sub(l.swept) > l.window {
+		for k, b := range l.buckets {
+			if now.Sub(b.start) >= l.window {
+				delete(l.buckets, k)
+			}
+		}
+		l.swept = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok || now.Sub(b.start) >= l.window {
+		b = &bucket{start: now}
+		l.buckets[key] = b
+	}
+	if b.count >= l.limit {
+		return false, b.start.Add(l.window).Sub(now)
+	}
+	b.count++
+	return true, 0
+}
+
+// PerIP rejects requests over the limiter's budget for the client IP with
+// 429 and a Retry-After header.
+func PerIP(l *Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ok, wait := l.Allow(c.ClientIP(), time.Now())
+		if !ok {
+			secs := int(wait.Seconds()) + 1
+			c.Header("Retry-After", strconv.Itoa(secs))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too many requests"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,117 @@
+// Package sheet reads and writes the simple tables used for catalog import
+// and export: CSV, and XLSX workbooks limited to the first worksheet's cell
+// values. Formulas, styles and further sheets are ignored on read and never
+// written.
+package sheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// MaxRows bounds what Read accepts, header included.
+const MaxRows = 10_000
+
+var (
+	ErrTooManyRows = fmt.Errorf("sheet: more than %d rows", MaxRows)
+	ErrEmpty       = errors.New("sheet: no rows")
+)
+
+// Read parses a CSV or XLSX file, telling them apart by content: XLSX is a
+// ZIP archive. Rows are returned as read, without trimming; trailing empty
+// rows are dropped.
+func Read(data []byte) ([][]string, error) {
+	var (
+		rows [][]string
+		err  error
+	)
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		rows, err = readXLSX(data)
+	} else {
+		rows, err = readCSV(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for len(rows) > 0 && blank(rows[len(rows)-1]) {
+		rows = rows[:len(rows)-1]
+	}
+	if len(rows) == 0 {
+		return nil, ErrEmpty
+	}
+	return rows, nil
+}
+
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // Excel writes a BOM
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	var rows [][]string
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("sheet: %w", err)
+		}
+		if len(rows) == MaxRows {
+			return nil, ErrTooManyRows
+		}
+		rows = append(rows, rec)
+	}
+}
+
+func blank(row []string) bool {
+	for _, v := range row {
+		if v != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteCSV writes rows as CSV. Cells may be string, float64, *string or
+// *float64; nil pointers are written empty.
+func WriteCSV(w io.Writer, rows [][]any) error {
+	cw := csv.NewWriter(w)
+	for _, row := range rows {
+		rec := make([]string, len(row))
+		for i, v := range row {
+			rec[i], _ = cellText(v)
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// cellText renders a cell value and reports whether it is a number.
+func cellText(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, false
+	case *string:
+		if v == nil {
+			return "", false
+		}
+		return *v, false
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case *float64:
+		if v == nil {
+			return "", false
+		}
+		return strconv.FormatFloat(*v, 'f', -1, 64), true
+	case nil:
+		return "", false
+	}
+	return fmt.Sprint(v), false
+}
@@ -0,0 +1,197 @@
+package sheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// xlsx builds a workbook whose first sheet has the given sheetData, with
+// shared strings when sst is not empty.
+func xlsx(t *testing.T, sheetData, sst string) []byte {
+	t.Helper()
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Data" sheetId="1" r:id="rId7"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId3" Target="worksheets/other.xml"/>` +
+			`<Relationship Id="rId7" Target="/xl/worksheets/data.xml"/></Relationships>`,
+		"xl/worksheets/data.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+			sheetData + `</sheetData></worksheet>`,
+	}
+	if sst != "" {
+		parts["xl/sharedStrings.xml"] = `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` + sst + `</sst>`
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range parts {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// emptyZip is an archive with a local file header but no workbook parts.
+func emptyZip(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if _, err := zw.Create("readme.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRead(t *testing.T) {
+	const shared = `<si><t>name</t></si>` +
+		`<si><r><t>LED </t></r><r><rPr><b/></rPr><t>panel</t></r></si>` +
+		`<si><t xml:space="preserve"> padded </t></si>`
+
+	for _, tc := range []struct {
+		name    string
+		data    []byte
+		want    [][]string
+		wantErr string
+	}{
+		{
+			name: "csv",
+			data: []byte("name,wattage\nT8,32\n"),
+			want: [][]string{{"name", "wattage"}, {"T8", "32"}},
+		},
+		{
+			name: "csv with BOM, ragged rows and trailing blanks",
+			data: []byte("\xef\xbb\xbfname,wattage\nT8\n\"Quoted, name\",40\n,\n\n,,\n"),
+			want: [][]string{{"name", "wattage"}, {"T8"}, {"Quoted, name", "40"}},
+		},
+		{
+			name: "csv blank rows inside are kept",
+			data: []byte("name\n,\nT8\n"),
+			want: [][]string{{"name"}, {"", ""}, {"T8"}},
+		},
+		{
+			name:    "csv only blank rows",
+			data:    []byte("\xef\xbb\xbf,,\n\n"),
+			wantErr: ErrEmpty.Error(),
+		},
+		{
+			name: "xlsx shared, inline and rich text",
+			data: xlsx(t, `<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="inlineStr"><is><t>wattage</t></is></c></row>`+
+				`<row r="2"><c r="A2" t="s"><v>1</v></c><c r="B2"><v>32.5</v></c><c r="C2" t="inlineStr"><is><r><t>a</t></r><r><t>b</t></r></is></c></row>`+
+				`<row r="3"><c r="A3" t="s"><v>2</v></c><c r="B3" t="b"><v>1</v></c><c r="C3" t="str"><v>=formula result</v></c></row>`, shared),
+			want: [][]string{{"name", "wattage"}, {"LED panel", "32.5", "ab"}, {" padded ", "TRUE", "=formula result"}},
+		},
+		{
+			name: "xlsx column refs skip and reorder cells",
+			data: xlsx(t, `<row r="1"><c r="C1" t="inlineStr"><is><t>c</t></is></c><c r="A1" t="inlineStr"><is><t>a</t></is></c></row>`+
+				`<row r="2"><c t="inlineStr"><is><t>x</t></is></c><c t="inlineStr"><is><t>y</t></is></c></row>`, ""),
+			want: [][]string{{"a", "", "c"}, {"x", "y"}},
+		},
+		{
+			name: "xlsx missing rows are blank and trailing ones dropped",
+			data: xlsx(t, `<row r="1"><c r="A1" t="inlineStr"><is><t>name</t></is></c></row>`+
+				`<row r="4"><c r="A4" t="inlineStr"><is><t>T8</t></is></c></row>`+
+				`<row><c r="A5" t="inlineStr"><is><t></t></is></c></row>`+
+				`<row r="9"/>`, ""),
+			want: [][]string{{"name"}, nil, nil, {"T8"}},
+		},
+		{
+			name: "xlsx rows out of order",
+			data: xlsx(t, `<row r="2"><c r="A2"><v>1</v></c></row>`+
+				`<row r="1"><c r="A1"><v>2</v></c></row>`, ""),
+			wantErr: "sheet: row 1 out of order",
+		},
+		{
+			name:    "xlsx bad shared string",
+			data:    xlsx(t, `<row r="1"><c r="A1" t="s"><v>3</v></c></row>`, shared),
+			wantErr: "sheet: cell A1: bad shared string",
+		},
+		{
+			name:    "xlsx bad cell reference",
+			data:    xlsx(t, `<row r="1"><c r="1A"><v>1</v></c></row>`, ""),
+			wantErr: `sheet: bad cell reference "1A"`,
+		},
+		{
+			name:    "xlsx too many columns",
+			data:    xlsx(t, `<row r="1"><c r="ZZ1"><v>1</v></c></row>`, ""),
+			wantErr: "sheet: cell ZZ1: more than 256 columns",
+		},
+		{
+			name:    "xlsx too many rows",
+			data:    xlsx(t, `<row r="10001"><c r="A10001"><v>1</v></c></row>`, ""),
+			wantErr: ErrTooManyRows.Error(),
+		},
+		{
+			name:    "zip that is not a workbook",
+			data:    emptyZip(t),
+			wantErr: errNotXLSX.Error(),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Read(tc.data)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("Read() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Read() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReadTooManyCSVRows(t *testing.T) {
+	data := bytes.Repeat([]byte("x\n"), MaxRows+1)
+	if _, err := Read(data); !errors.Is(err, ErrTooManyRows) {
+		t.Fatalf("Read() error = %v, want %v", err, ErrTooManyRows)
+	}
+	if rows, err := Read(data[:2*MaxRows]); err != nil || len(rows) != MaxRows {
+		t.Fatalf("Read() = %d rows, %v; want %d rows", len(rows), err, MaxRows)
+	}
+}
+
+// TestWriteRead checks that what WriteXLSX and WriteCSV produce reads back.
+func TestWriteRead(t *testing.T) {
+	name, watts := "LED <panel> & co", 32.5
+	rows := [][]any{{"name", "wattage", "notes"}, {&name, &watts, (*string)(nil)}, {"=1+1", 7.0, "x"}}
+	want := [][]string{{"name", "wattage", "notes"}, {name, "32.5"}, {"=1+1", "7", "x"}}
+	wantCSV := [][]string{{"name", "wattage", "notes"}, {name, "32.5", ""}, {"=1+1", "7", "x"}}
+
+	var x, c bytes.Buffer
+	if err := WriteXLSX(&x, "Products", rows); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteCSV(&c, rows); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name string
+		data []byte
+		want [][]string
+	}{{"xlsx", x.Bytes(), want}, {"csv", c.Bytes(), wantCSV}} {
+		got, err := Read(tc.data)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: Read() = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
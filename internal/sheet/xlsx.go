@@ -0,0 +1,295 @@
+package sheet
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxPart bounds each decompressed XML part, so a small upload cannot
+	// expand without limit.
+	maxPart = 32 << 20
+	// maxCols bounds the column index a cell reference may name.
+	maxCols = 256
+)
+
+var errNotXLSX = errors.New("sheet: not an XLSX workbook")
+
+type xlsxRels struct {
+	Items []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RelID string `xml:"id,attr"` // r:id
+	} `xml:"sheets>sheet"`
+}
+
+// xlsxText is a shared or inline string: plain <t>, or rich-text runs.
+type xlsxText struct {
+	T    *string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (s xlsxText) String() string {
+	if s.T != nil {
+		return *s.T
+	}
+	var b strings.Builder
+	for _, r := range s.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"` // 1-based; 0 when omitted
+		Cells []struct {
+			R      string    `xml:"r,attr"` // "B3"; empty when omitted
+			T      string    `xml:"t,attr"`
+			V      string    `xml:"v"`
+			Inline *xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX returns the cell values of the workbook's first worksheet.
+func readXLSX(data []byte) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, errNotXLSX
+	}
+	var wb xlsxWorkbook
+	if err := decodePart(zr, "xl/workbook.xml", &wb); err != nil || len(wb.Sheets) == 0 {
+		return nil, errNotXLSX
+	}
+	var rels xlsxRels
+	if err := decodePart(zr, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, errNotXLSX
+	}
+	sheetPath := ""
+	for _, r := range rels.Items {
+		if r.ID == wb.Sheets[0].RelID {
+			if strings.HasPrefix(r.Target, "/") {
+				sheetPath = strings.TrimPrefix(r.Target, "/")
+			} else {
+				sheetPath = path.Join("xl", r.Target)
+			}
+		}
+	}
+	if sheetPath == "" {
+		return nil, errNotXLSX
+	}
+
+	var shared []string
+	var sst struct {
+		Items []xlsxText `xml:"si"`
+	}
+	switch err := decodePart(zr, "xl/sharedStrings.xml", &sst); {
+	case err == nil:
+		for _, s := range sst.Items {
+			shared = append(shared, s.String())
+		}
+	case !errors.Is(err, errNoPart):
+		return nil, err
+	}
+
+	var ws xlsxWorksheet
+	if err := decodePart(zr, sheetPath, &ws); err != nil {
+		return nil, err
+	}
+	var rows [][]string
+	for _, row := range ws.Rows {
+		r := row.R
+		if r == 0 {
+			r = len(rows) + 1
+		}
+		if r > MaxRows {
+			return nil, ErrTooManyRows
+		}
+		if r <= len(rows) {
+			return nil, fmt.Errorf("sheet: row %d out of order", r)
+		}
+		for len(rows) < r {
+			rows = append(rows, nil)
+		}
+		var out []string
+		for _, c := range row.Cells {
+			col := len(out)
+			if c.R != "" {
+				if col, err = columnIndex(c.R); err != nil {
+					return nil, err
+				}
+			}
+			var v string
+			switch c.T {
+			case "s":
+				i, err := strconv.Atoi(c.V)
+				if err != nil || i < 0 || i >= len(shared) {
+					return nil, fmt.Errorf("sheet: cell %s: bad shared string", c.R)
+				}
+				v = shared[i]
+			case "inlineStr":
+				if c.Inline != nil {
+					v = c.Inline.String()
+				}
+			case "b":
+				v = "FALSE"
+				if c.V == "1" {
+					v = "TRUE"
+				}
+			default: // n, str, d, e: the stored text is the value
+				v = c.V
+			}
+			for len(out) <= col {
+				out = append(out, "")
+			}
+			out[col] = v
+		}
+		rows[r-1] = out
+	}
+	return rows, nil
+}
+
+// errNoPart is returned by decodePart for a part the archive lacks.
+var errNoPart = errors.New("sheet: part not found")
+
+func decodePart(zr *zip.Reader, name string, v any) error {
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("sheet: %s: %w", name, err)
+		}
+		defer rc.Close()
+		lr := &io.LimitedReader{R: rc, N: maxPart + 1}
+		if err := xml.NewDecoder(lr).Decode(v); err != nil {
+			if lr.N <= 0 {
+				return fmt.Errorf("sheet: %s larger than %d MB", name, maxPart>>20)
+			}
+			return fmt.Errorf("sheet: %s: %w", name, err)
+		}
+		return nil
+	}
+	return errNoPart
+}
+
+// columnIndex returns the 0-based column of a cell reference like "AB12".
+func columnIndex(ref string) (int, error) {
+	col := 0
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		col = col*26 + int(ref[i]-'A'+1)
+		if col > maxCols {
+			return 0, fmt.Errorf("sheet: cell %s: more than %d columns", ref, maxCols)
+		}
+	}
+	if i == 0 {
+		return 0, fmt.Errorf("sheet: bad cell reference %q", ref)
+	}
+	return col - 1, nil
+}
+
+// columnName is the inverse of columnIndex without the row: 0 → "A".
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// The fixed parts of a one-sheet workbook without styles.
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`
+)
+
+// WriteXLSX writes rows as a workbook with one sheet called name. Cells take
+// the same values as WriteCSV; numbers are written as numeric cells and
+// everything else as inline strings, so nothing is evaluated as a formula.
+func WriteXLSX(w io.Writer, name string, rows [][]any) error {
+	zw := zip.NewWriter(w)
+	part := func(p, body string) error {
+		f, err := zw.Create(p)
+		if err == nil {
+			_, err = io.WriteString(f, body)
+		}
+		return err
+	}
+	var esc bytes.Buffer
+	xml.EscapeText(&esc, []byte(name))
+	workbook := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="` + esc.String() + `" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	for _, p := range [][2]string{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", workbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	} {
+		if err := part(p[0], p[1]); err != nil {
+			return err
+		}
+	}
+
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	bw.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for i, row := range rows {
+		fmt.Fprintf(bw, `<row r="%d">`, i+1)
+		for j, v := range row {
+			text, number := cellText(v)
+			if text == "" {
+				continue
+			}
+			ref := columnName(j) + strconv.Itoa(i+1)
+			if number {
+				fmt.Fprintf(bw, `<c r="%s"><v>%s</v></c>`, ref, text)
+				continue
+			}
+			fmt.Fprintf(bw, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+			xml.EscapeText(bw, []byte(text))
+			bw.WriteString(`</t></is></c>`)
+		}
+		bw.WriteString(`</row>`)
+	}
+	bw.WriteString(`</sheetData></worksheet>`)
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
@@ -0,0 +1,176 @@
+// Package storage keeps the bytes of uploaded photos and documents. Rows
+// record the object key in their "url" column; clients download through
+// short-lived signed URLs rather than from a public bucket.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lucsky/cuid"
+)
+
+var (
+	// ErrNotFound is returned by Open for a key that holds no object.
+	ErrNotFound = errors.New("storage: object not found")
+	// ErrBadSignature is returned by Local.Verify for a tampered or expired link.
+	ErrBadSignature = errors.New("storage: invalid or expired signature")
+)
+
+// Storage is an object store addressed by slash-separated keys.
+type Storage interface {
+	// Put stores size bytes from r under key, replacing any existing object.
+	// contentType should be ContentType(key).
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes key; deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a link that downloads key until ttl has passed.
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// Limits are the upload and download settings handlers apply.
+type Limits struct {
+	MaxUploadBytes int64
+	URLTTL         time.Duration
+}
+
+// IsKey reports whether a stored url column holds an object key rather than
+// an external URL saved before uploads went through this API.
+func IsKey(stored string) bool {
+	return stored != "" && !strings.Contains(stored, "://")
+}
+
+// servedTypes are the content types objects are stored and served as, by key
+// extension. Anything else, HTML and SVG included, is served as
+// application/octet-stream so it can never run in the API's origin.
+var servedTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".bmp":  "image/bmp",
+	".pdf":  "application/pdf",
+	".txt":  "text/plain; charset=utf-8",
+	".zip":  "application/zip", // also .docx and .xlsx
+	".bin":  "application/octet-stream",
+}
+
+// NewKey returns a fresh key under prefix whose extension records
+// contentType, which must be sniffed from the bytes: the client's file name
+// never reaches the key.
+func NewKey(prefix, contentType string) string {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	for ext, t := range servedTypes {
+		if t, _, _ := strings.Cut(t, ";"); t == mediaType {
+			return path.Join(prefix, cuid.New()+ext)
+		}
+	}
+	return path.Join(prefix, cuid.New()+".bin")
+}
+
+// ContentType is what the object at key is served as.
+func ContentType(key string) string {
+	if t, ok := servedTypes[path.Ext(key)]; ok {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// Inline reports whether browsers may show contentType in place; everything
+// but images and PDFs is sent as an attachment.
+func Inline(contentType string) bool {
+	return strings.HasPrefix(contentType, "image/") || contentType == "application/pdf"
+}
+
+// ThumbnailKey is where the JPEG thumbnail of the object at key is stored.
+func ThumbnailKey(key string) string {
+	return strings.TrimSuffix(key, path.Ext(key)) + ".thumb.jpg"
+}
+
+// FromEnv builds the configured backend:
+//
+//	STORAGE_DRIVER         local (default) or s3
+//	STORAGE_DIR            local: root directory, default ./uploads
+//	STORAGE_PUBLIC_URL     local: origin prefixed to signed /files links, default none (relative)
+//	STORAGE_SIGNING_KEY    local: HMAC key for signed links, default AUTH_SECRET
+//	S3_ENDPOINT            s3: host[:port], e.g. minio:9000
+//	S3_BUCKET              s3: bucket, which must already exist
+//	S3_ACCESS_KEY          s3
+//	S3_SECRET_KEY          s3
+//	S3_REGION              s3: optional
+//	S3_USE_SSL             s3: default true
+//	STORAGE_URL_TTL        lifetime of signed links, default 15m
+//	STORAGE_MAX_UPLOAD_MB  largest accepted upload, default 25
+func FromEnv(ctx context.Context) (Storage, Limits, error) {
+	limits := Limits{MaxUploadBytes: 25 << 20, URLTTL: 15 * time.Minute}
+	if v := os.Getenv("STORAGE_URL_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, limits, fmt.Errorf("invalid STORAGE_URL_TTL %q", v)
+		}
+		limits.URLTTL = d
+	}
+	if v := os.Getenv("STORAGE_MAX_UPLOAD_MB"); v != "" {
+		mb, err := strconv.Atoi(v)
+		if err != nil || mb <= 0 {
+			return nil, limits, fmt.Errorf("invalid STORAGE_MAX_UPLOAD_MB %q", v)
+		}
+		limits.MaxUploadBytes = int64(mb) << 20
+	}
+
+	switch driver := os.Getenv("STORAGE_DRIVER"); driver {
+	case "", "local":
+		dir := os.Getenv("STORAGE_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		key := os.Getenv("STORAGE_SIGNING_KEY")
+		if key == "" {
+			key = os.Getenv("AUTH_SECRET")
+		}
+		if key == "" {
+			return nil, limits, errors.New("STORAGE_SIGNING_KEY or AUTH_SECRET must be set for local storage")
+		}
+		s, err := NewLocal(dir, os.Getenv("STORAGE_PUBLIC_URL"), []byte(key))
+		if err != nil {
+			return nil, limits, err
+		}
+		log.Printf("storage: local files in %s", dir)
+		return s, limits, nil
+
+	case "s3":
+		useSSL := true
+		if v := os.Getenv("S3_USE_SSL"); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, limits, fmt.Errorf("invalid S3_USE_SSL %q", v)
+			}
+			useSSL = b
+		}
+		s, err := NewS3(ctx, S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			Region:    os.Getenv("S3_REGION"),
+			UseSSL:    useSSL,
+		})
+		if err != nil {
+			return nil, limits, err
+		}
+		log.Printf("storage: s3 bucket %s at %s", os.Getenv("S3_BUCKET"), os.Getenv("S3_ENDPOINT"))
+		return s, limits, nil
+
+	default:
+		return nil, limits, fmt.Errorf("unknown STORAGE_DRIVER %q", driver)
+	}
+}
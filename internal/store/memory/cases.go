@@ -0,0 +1,436 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lucsky/cuid"
+	"github.com/rick/go-neon-api/internal/models"
+	"github.com/rick/go-neon-api/internal/pagination"
+	"github.com/rick/go-neon-api/internal/store"
+)
+
+type cases struct{ d *DB }
+
+func (s *cases) OwnerOf(_ context.Context, res store.Resource, id string) (string, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	// Walk up one level at a time: fixture or photo → room → visit → case.
+	switch res {
+	case store.ExistingProductResource:
+		e, ok := s.d.existing[id]
+		if !ok {
+			return "", store.ErrNotFound
+		}
+		id, res = e.RoomID, store.RoomResource
+	case store.SuggestedProductResource:
+		sg, ok := s.d.suggested[id]
+		if !ok {
+			return "", store.ErrNotFound
+		}
+		id, res = sg.RoomID, store.RoomResource
+	case store.RoomPhotoResource:
+		p, ok := s.d.roomPhotos[id]
+		if !ok {
+			return "", store.ErrNotFound
+		}
+		id, res = p.RoomID, store.RoomResource
+	}
+	if res == store.RoomResource {
+		r, ok := s.d.rooms[id]
+		if !ok {
+			return "", store.ErrNotFound
+		}
+		id, res = r.OnSiteVisitID, store.VisitResource
+	}
+	if res == store.VisitResource {
+		v, ok := s.d.visits[id]
+		if !ok {
+			return "", store.ErrNotFound
+		}
+		id = v.CaseID
+	}
+	c, ok := s.d.cases[id]
+	if !ok {
+		return "", store.ErrNotFound
+	}
+	return c.UserID, nil
+}
+
+// summary joins a case with its owner like the SQL list query does.
+func (d *DB) summary(c *models.Case) (store.CaseSummary, bool) {
+	u, ok := d.users[c.UserID]
+	if !ok {
+		return store.CaseSummary{}, false
+	}
+	return store.CaseSummary{
+		ID:                   c.ID,
+		CustomerName:         c.CustomerName,
+		ProjectDetails:       c.ProjectDetails,
+		ContactPerson:        c.ContactPerson,
+		SchoolName:           c.SchoolName,
+		EmailAddress:         c.EmailAddress,
+		PhoneNumber:          c.PhoneNumber,
+		SchoolAddress:        c.SchoolAddress,
+		InstallationService:  c.InstallationService,
+		OperationDaysPerYear: c.OperationDaysPerYear,
+		OperationHoursPerDay: c.OperationHoursPerDay,
+		Status:               c.Status,
+		CreatedAt:            c.CreatedAt,
+		UpdatedAt:            c.UpdatedAt,
+		UserName:             u.Name,
+		UserEmail:            u.Email,
+	}, true
+}
+
+func matchesCase(f store.CaseFilter, c *models.Case) bool {
+	switch {
+	case f.OwnerID != "" && c.UserID != f.OwnerID,
+		len(f.Statuses) > 0 && !slices.Contains(f.Statuses, c.Status),
+		f.SchoolName != "" && !containsFold(c.SchoolName, f.SchoolName),
+		f.InstallationService != "" && !strings.EqualFold(c.InstallationService, f.InstallationService),
+		f.CreatedFrom != nil && c.CreatedAt.Before(*f.CreatedFrom),
+		f.CreatedBefore != nil && !c.CreatedAt.Before(*f.CreatedBefore),
+		f.UpdatedFrom != nil && c.UpdatedAt.Before(*f.UpdatedFrom),
+		f.UpdatedBefore != nil && !c.UpdatedAt.Before(*f.UpdatedBefore):
+		return false
+	}
+	for _, word := range f.Words {
+		hit := false
+		for _, field := range []string{c.CustomerName, c.SchoolName, c.ContactPerson, c.EmailAddress, c.SchoolAddress} {
+			if containsFold(field, word) {
+				hit = true
+				break
+			}
+		}
+		if !hit {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *cases) List(_ context.Context, f store.CaseFilter, p pagination.Params) (pagination.Page[store.CaseSummary], error) {
+	kind, ok := store.CaseSorts[f.Sort]
+	if !ok {
+		return pagination.Page[store.CaseSummary]{}, fmt.Errorf("memory: unknown case sort %q", f.Sort)
+	}
+
+	s.d.mu.Lock()
+	var rows []store.CaseSummary
+	for _, c := range s.d.cases {
+		if !matchesCase(f, c) {
+			continue
+		}
+		if r, ok := s.d.summary(c); ok {
+			rows = append(rows, r)
+		}
+	}
+	s.d.mu.Unlock()
+
+	total := int64(len(rows))
+	page, err := keysetPage(rows, p, f.Sort, kind, f.Desc,
+		func(r store.CaseSummary) any { return r.SortValue(f.Sort) },
+		func(r store.CaseSummary) string { return r.ID })
+	if err != nil {
+		return page, err
+	}
+	if p.WithTotal {
+		page.Total = &total
+	}
+	return page, nil
+}
+
+func (s *cases) Get(_ context.Context, id string) (*store.CaseDetail, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	c, ok := s.d.cases[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	u, ok := s.d.users[c.UserID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	out := &store.CaseDetail{
+		ID:             c.ID,
+		CustomerName:   c.CustomerName,
+		ProjectDetails: c.ProjectDetails,
+		ContactPerson:  c.ContactPerson,
+		SchoolName:     c.SchoolName,
+		EmailAddress:   c.EmailAddress,
+		PhoneNumber:    c.PhoneNumber,
+		SchoolAddress:  c.SchoolAddress,
+		Status:         c.Status,
+		CreatedAt:      c.CreatedAt,
+		UpdatedAt:      c.UpdatedAt,
+		UserName:       u.Name,
+		UserEmail:      u.Email,
+		Documents:      s.d.caseDocuments(id),
+		Photos:         s.d.casePhotos(id),
+	}
+	return out, nil
+}
+
+func (s *cases) Create(_ context.Context, actorID string, in store.CaseInput) (*store.CaseRecord, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	if _, ok := s.d.users[in.UserID]; !ok {
+		return nil, fmt.Errorf("memory: case owner %q does not exist", in.UserID)
+	}
+	now := time.Now()
+	c := &models.Case{
+		UserID:               in.UserID,
+		CustomerName:         in.CustomerName,
+		ProjectDetails:       in.ProjectDetails,
+		UploadToken:          cuid.New(),
+		Status:               in.Status,
+		CreatedAt:            now,
+		UpdatedAt:            now,
+		SchoolName:           in.SchoolName,
+		ContactPerson:        in.ContactPerson,
+		EmailAddress:         in.EmailAddress,
+		PhoneNumber:          in.PhoneNumber,
+		SchoolAddress:        in.SchoolAddress,
+		LightingPurpose:      in.LightingPurpose,
+		FacilitiesUsedIn:     in.FacilitiesUsedIn,
+		InstallationService:  in.InstallationService,
+		OperationDaysPerYear: in.OperationDaysPerYear,
+		OperationHoursPerDay: in.OperationHoursPerDay,
+	}
+	c.ID = cuid.New()
+	s.d.cases[c.ID] = c
+	s.d.logActivity(c.ID, actorID, "Case created")
+
+	return &store.CaseRecord{
+		ID:                   c.ID,
+		UserID:               c.UserID,
+		CustomerName:         c.CustomerName,
+		ProjectDetails:       c.ProjectDetails,
+		UploadToken:          c.UploadToken,
+		Status:               c.Status,
+		CreatedAt:            c.CreatedAt,
+		UpdatedAt:            c.UpdatedAt,
+		SchoolName:           c.SchoolName,
+		ContactPerson:        c.ContactPerson,
+		EmailAddress:         c.EmailAddress,
+		PhoneNumber:          c.PhoneNumber,
+		SchoolAddress:        c.SchoolAddress,
+		LightingPurpose:      c.LightingPurpose,
+		FacilitiesUsedIn:     c.FacilitiesUsedIn,
+		InstallationService:  c.InstallationService,
+		OperationDaysPerYear: c.OperationDaysPerYear,
+		OperationHoursPerDay: c.OperationHoursPerDay,
+	}, nil
+}
+
+func (s *cases) Update(_ context.Context, actorID, id string, set map[string]any) error {
+	if len(set) == 0 {
+		return nil
+	}
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	c, ok := s.d.cases[id]
+	if !ok {
+		return store.ErrNotFound
+	}
+	if err := patch(c, set); err != nil {
+		return err
+	}
+	c.UpdatedAt = time.Now()
+	s.d.logActivity(id, actorID, "Case updated: "+strings.Join(sortedKeys(set), ", "))
+	return nil
+}
+
+func (s *cases) Delete(_ context.Context, actorID, id string) (store.DeletedFiles, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	c, ok := s.d.cases[id]
+	if !ok {
+		return store.DeletedFiles{}, store.ErrNotFound
+	}
+	var files store.DeletedFiles
+	for vid, v := range s.d.visits {
+		if v.CaseID == id {
+			for rid, r := range s.d.rooms {
+				if r.OnSiteVisitID == vid {
+					files.Photos = append(files.Photos, s.d.deleteRoom(rid)...)
+				}
+			}
+			delete(s.d.visits, vid)
+		}
+	}
+	for pid, p := range s.d.photos {
+		if p.CaseID == id {
+			files.Photos = append(files.Photos, p.URL)
+			delete(s.d.photos, pid)
+		}
+	}
+	for did, doc := range s.d.documents {
+		if doc.CaseID == id {
+			files.Documents = append(files.Documents, doc.URL)
+			delete(s.d.documents, did)
+		}
+	}
+	for qid, q := range s.d.quotes {
+		if q.CaseID == id {
+			delete(s.d.quotes, qid)
+		}
+	}
+	delete(s.d.quoteCounters, id)
+	delete(s.d.paybackSettings, id)
+	s.d.statuses = slices.DeleteFunc(s.d.statuses, func(sc models.CaseStatusChange) bool { return sc.CaseID == id })
+	s.d.activity = slices.DeleteFunc(s.d.activity, func(a models.ActivityLog) bool { return deref(a.CaseID) == id })
+	delete(s.d.cases, id)
+	a := models.ActivityLog{UserID: actorID, Action: store.CaseDeletedAction(id, c.CustomerName), CreatedAt: time.Now()}
+	a.ID = cuid.New()
+	s.d.activity = append(s.d.activity, a)
+	return files, nil
+}
+
+func (s *cases) Operation(_ context.Context, id string) (*store.CaseOperation, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	c, ok := s.d.cases[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	op := &store.CaseOperation{DaysPerYear: c.OperationDaysPerYear, HoursPerDay: c.OperationHoursPerDay}
+	if p, ok := s.d.paybackSettings[id]; ok {
+		v := p.Value
+		op.PaybackValue = &v
+	}
+	return op, nil
+}
+
+func (s *cases) StatusHistory(_ context.Context, id string) (string, []store.StatusChange, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	c, ok := s.d.cases[id]
+	if !ok {
+		return "", nil, store.ErrNotFound
+	}
+	history := []store.StatusChange{}
+	for _, sc := range s.d.statuses {
+		if sc.CaseID == id {
+			history = append(history, s.d.statusChange(sc))
+		}
+	}
+	sort.SliceStable(history, func(i, j int) bool { return history[i].CreatedAt.After(history[j].CreatedAt) })
+	return c.Status, history, nil
+}
+
+func (d *DB) statusChange(sc models.CaseStatusChange) store.StatusChange {
+	out := store.StatusChange{
+		ID:         sc.ID,
+		FromStatus: sc.FromStatus,
+		ToStatus:   sc.ToStatus,
+		Note:       sc.Note,
+		UserID:     sc.UserID,
+		CreatedAt:  sc.CreatedAt,
+	}
+	if u, ok := d.users[sc.UserID]; ok {
+		out.UserName = u.Name
+	}
+	return out
+}
+
+func (s *cases) Transition(_ context.Context, actorID, id, to string, note *string, allow func(from string) bool) (*store.StatusChange, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	c, ok := s.d.cases[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	if !allow(c.Status) {
+		return nil, &store.TransitionError{From: c.Status, To: to}
+	}
+	now := time.Now()
+	sc := models.CaseStatusChange{CaseID: id, FromStatus: c.Status, ToStatus: to, Note: note, UserID: actorID, CreatedAt: now}
+	sc.ID = cuid.New()
+	s.d.statuses = append(s.d.statuses, sc)
+	s.d.logActivity(id, actorID, fmt.Sprintf("Status changed from %s to %s", c.Status, to))
+	c.Status, c.UpdatedAt = to, now
+
+	// The SQL store returns the inserted row without the joined user name.
+	out := s.d.statusChange(sc)
+	out.UserName = nil
+	return &out, nil
+}
+
+// uploadLink must be called with d.mu held.
+func uploadLink(c *models.Case) *store.UploadLink {
+	return &store.UploadLink{
+		CaseID:       c.ID,
+		OwnerID:      c.UserID,
+		CustomerName: c.CustomerName,
+		SchoolName:   c.SchoolName,
+		Token:        c.UploadToken,
+		ExpiresAt:    c.UploadTokenExpiresAt,
+	}
+}
+
+func (s *cases) UploadLink(_ context.Context, caseID string) (*store.UploadLink, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	c, ok := s.d.cases[caseID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return uploadLink(c), nil
+}
+
+func (s *cases) ByUploadToken(_ context.Context, token string) (*store.UploadLink, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	for _, c := range s.d.cases {
+		if c.UploadToken == token {
+			if link := uploadLink(c); !link.Expired(time.Now()) {
+				return link, nil
+			}
+			break
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (s *cases) RotateUploadToken(_ context.Context, actorID, caseID string, expiresAt *time.Time) (*store.UploadLink, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	c, ok := s.d.cases[caseID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	c.UploadToken, c.UploadTokenExpiresAt = cuid.New(), expiresAt
+	s.d.logActivity(caseID, actorID, "Upload link rotated")
+	return uploadLink(c), nil
+}
+
+func (s *cases) ExpireUploadToken(_ context.Context, actorID, caseID string) error {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	c, ok := s.d.cases[caseID]
+	if !ok {
+		return store.ErrNotFound
+	}
+	if now := time.Now(); c.UploadTokenExpiresAt == nil || c.UploadTokenExpiresAt.After(now) {
+		c.UploadTokenExpiresAt = &now
+	}
+	s.d.logActivity(caseID, actorID, "Upload link disabled")
+	return nil
+}
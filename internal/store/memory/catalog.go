@@ -0,0 +1,543 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/lucsky/cuid"
+	"github.com/rick/go-neon-api/internal/models"
+	"github.com/rick/go-neon-api/internal/pagination"
+	"github.com/rick/go-neon-api/internal/store"
+)
+
+type catalog struct{ d *DB }
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func (s *catalog) ListProducts(_ context.Context, f store.ProductFilter, p pagination.Params) (pagination.Page[store.ProductRow], error) {
+	s.d.mu.Lock()
+	var rows []store.ProductRow
+	for _, pr := range s.d.products {
+		if f.Q != "" && !containsFold(pr.Name, f.Q) && !containsFold(deref(pr.Description), f.Q) {
+			continue
+		}
+		if f.Category != "" && deref(pr.Category) != f.Category {
+			continue
+		}
+		if pr.ArchivedAt != nil && !f.IncludeArchived {
+			continue
+		}
+		rows = append(rows, productRow(pr))
+	}
+	s.d.mu.Unlock()
+	return pageByName(rows, p, func(r store.ProductRow) (string, string) { return r.Name, r.ID })
+}
+
+func (s *catalog) ListFixtureTypes(_ context.Context, f store.FixtureTypeFilter, p pagination.Params) (pagination.Page[store.FixtureTypeRow], error) {
+	s.d.mu.Lock()
+	var rows []store.FixtureTypeRow
+	for _, t := range s.d.fixtureTypes {
+		if f.Q != "" && !containsFold(t.Name, f.Q) && !containsFold(deref(t.Description), f.Q) && !containsFold(deref(t.SKU), f.Q) {
+			continue
+		}
+		if t.ArchivedAt != nil && !f.IncludeArchived {
+			continue
+		}
+		rows = append(rows, fixtureTypeRow(t))
+	}
+	s.d.mu.Unlock()
+	return pageByName(rows, p, func(r store.FixtureTypeRow) (string, string) { return r.Name, r.ID })
+}
+
+func productRow(p *models.Product) store.ProductRow {
+	return store.ProductRow{
+		ID: p.ID, Name: p.Name, Wattage: p.Wattage, Category: p.Category, Description: p.Description,
+		SKU: p.SKU, ArchivedAt: p.ArchivedAt,
+	}
+}
+
+func fixtureTypeRow(t *models.LightFixtureType) store.FixtureTypeRow {
+	return store.FixtureTypeRow{
+		ID: t.ID, Name: t.Name, SKU: t.SKU, Wattage: t.Wattage, ImageURL: t.ImageURL, Description: t.Description,
+		ArchivedAt: t.ArchivedAt, Pricing: fixtureTypePricing(t),
+	}
+}
+
+func fixtureTypePricing(t *models.LightFixtureType) store.Pricing {
+	return store.Pricing{UnitCost: t.UnitCost, ListPrice: t.ListPrice, LaborHours: t.LaborHours}
+}
+
+// setFixtureType copies the editable columns onto t.
+func setFixtureType(t *models.LightFixtureType, in store.FixtureTypeInput) {
+	t.Name, t.Wattage, t.Description, t.SKU, t.ImageURL = in.Name, in.Wattage, in.Description, in.SKU, in.ImageURL
+	t.UnitCost, t.ListPrice, t.LaborHours = in.UnitCost, in.ListPrice, in.LaborHours
+}
+
+func fixtureTypeInput(r store.FixtureTypeRow) store.FixtureTypeInput {
+	return store.FixtureTypeInput{
+		Name: r.Name, Wattage: r.Wattage, Description: r.Description, SKU: r.SKU, ImageURL: r.ImageURL, Pricing: r.Pricing,
+	}
+}
+
+func accessoryRow(a *models.Accessory) store.Accessory {
+	return store.Accessory{
+		ID: a.ID, Kind: a.Kind, Name: a.Name, SKU: a.SKU, UpdatedAt: a.UpdatedAt,
+		Pricing: store.Pricing{UnitCost: a.UnitCost, ListPrice: a.ListPrice, LaborHours: a.LaborHours},
+	}
+}
+
+func (s *catalog) Accessories(_ context.Context) ([]store.Accessory, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	rows := []store.Accessory{}
+	for _, kind := range slices.Sorted(maps.Keys(s.d.accessories)) {
+		rows = append(rows, accessoryRow(s.d.accessories[kind]))
+	}
+	return rows, nil
+}
+
+func (s *catalog) UpdateAccessory(_ context.Context, kind string, in store.AccessoryInput) (*store.Accessory, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	a, ok := s.d.accessories[kind]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	a.Name, a.SKU, a.UnitCost, a.ListPrice, a.LaborHours = in.Name, in.SKU, in.UnitCost, in.ListPrice, in.LaborHours
+	a.UpdatedAt = time.Now()
+	row := accessoryRow(a)
+	return &row, nil
+}
+
+func (s *catalog) Recommendations(_ context.Context) ([]store.FixtureRecommendation, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	rows := []store.FixtureRecommendation{}
+	for _, r := range s.d.recommendations {
+		rows = append(rows, s.d.recommendationRow(r))
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if !rows[i].CreatedAt.Equal(rows[j].CreatedAt) {
+			return rows[i].CreatedAt.Before(rows[j].CreatedAt)
+		}
+		return rows[i].ID < rows[j].ID
+	})
+	return rows, nil
+}
+
+func (s *catalog) CreateRecommendation(_ context.Context, in store.FixtureRecommendationInput) (*store.FixtureRecommendation, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	if !s.d.recommendationRefsExist(in) {
+		return nil, store.ErrInvalidReference
+	}
+	r := &models.FixtureRecommendation{CreatedAt: time.Now()}
+	r.UpdatedAt = r.CreatedAt
+	setRecommendation(r, in)
+	r.ID = cuid.New()
+	s.d.recommendations[r.ID] = r
+	row := s.d.recommendationRow(r)
+	return &row, nil
+}
+
+func (s *catalog) UpdateRecommendation(_ context.Context, id string, in store.FixtureRecommendationInput) (*store.FixtureRecommendation, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	r, ok := s.d.recommendations[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	if !s.d.recommendationRefsExist(in) {
+		return nil, store.ErrInvalidReference
+	}
+	setRecommendation(r, in)
+	r.UpdatedAt = time.Now()
+	row := s.d.recommendationRow(r)
+	return &row, nil
+}
+
+func (s *catalog) DeleteRecommendation(_ context.Context, id string) error {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	if _, ok := s.d.recommendations[id]; !ok {
+		return store.ErrNotFound
+	}
+	delete(s.d.recommendations, id)
+	return nil
+}
+
+func setRecommendation(r *models.FixtureRecommendation, in store.FixtureRecommendationInput) {
+	r.ProductID, r.Category, r.LocationTagID, r.FixtureTypeID = in.ProductID, in.Category, in.LocationTagID, in.FixtureTypeID
+	r.MinCeilingHeight, r.MaxCeilingHeight, r.Priority = in.MinCeilingHeight, in.MaxCeilingHeight, in.Priority
+}
+
+// recommendationRefsExist stands in for the rule's foreign keys; d.mu must
+// be held.
+func (d *DB) recommendationRefsExist(in store.FixtureRecommendationInput) bool {
+	if _, ok := d.fixtureTypes[in.FixtureTypeID]; !ok {
+		return false
+	}
+	if in.ProductID != nil {
+		if _, ok := d.products[*in.ProductID]; !ok {
+			return false
+		}
+	}
+	if in.LocationTagID != nil {
+		if _, ok := d.locationTags[*in.LocationTagID]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// recommendationRow joins the rule's names; d.mu must be held.
+func (d *DB) recommendationRow(r *models.FixtureRecommendation) store.FixtureRecommendation {
+	row := store.FixtureRecommendation{
+		ID: r.ID, ProductID: r.ProductID, Category: r.Category,
+		MinCeilingHeight: r.MinCeilingHeight, MaxCeilingHeight: r.MaxCeilingHeight,
+		LocationTagID: r.LocationTagID, FixtureTypeID: r.FixtureTypeID, Priority: r.Priority,
+		CreatedAt: r.CreatedAt, UpdatedAt: r.UpdatedAt,
+	}
+	if r.ProductID != nil {
+		if p, ok := d.products[*r.ProductID]; ok {
+			row.ProductName = &p.Name
+		}
+	}
+	if r.LocationTagID != nil {
+		if t, ok := d.locationTags[*r.LocationTagID]; ok {
+			row.LocationTag = &t.Name
+		}
+	}
+	if t, ok := d.fixtureTypes[r.FixtureTypeID]; ok {
+		row.FixtureTypeName, row.FixtureTypeArchived = t.Name, t.ArchivedAt != nil
+	}
+	return row
+}
+
+// deleteRecommendations emulates the rules' ON DELETE CASCADE; d.mu must be
+// held.
+func (d *DB) deleteRecommendations(refers func(*models.FixtureRecommendation) bool) {
+	for id, r := range d.recommendations {
+		if refers(r) {
+			delete(d.recommendations, id)
+		}
+	}
+}
+
+// productTaken and fixtureTypeTaken stand in for the unique name and SKU
+// indexes: they report whether an item other than id has name or sku.
+func (d *DB) productTaken(id, name string, sku *string) bool {
+	for _, p := range d.products {
+		if p.ID != id && (p.Name == name || sameSKU(p.SKU, sku)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *DB) fixtureTypeTaken(id, name string, sku *string) bool {
+	for _, t := range d.fixtureTypes {
+		if t.ID != id && (t.Name == name || sameSKU(t.SKU, sku)) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameSKU is true when both are set and equal; any number of items may have
+// no SKU.
+func sameSKU(a, b *string) bool {
+	return a != nil && b != nil && *a == *b
+}
+
+// setArchived mirrors the Postgres UPDATE: the first archive time is kept.
+func setArchived(at **time.Time, archived bool) {
+	switch {
+	case !archived:
+		*at = nil
+	case *at == nil:
+		now := time.Now()
+		*at = &now
+	}
+}
+
+func (s *catalog) CreateProduct(_ context.Context, in store.ProductInput) (*store.ProductRow, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	if s.d.productTaken("", in.Name, in.SKU) {
+		return nil, store.ErrConflict
+	}
+	p := &models.Product{Name: in.Name, Wattage: in.Wattage, Category: in.Category, Description: in.Description, SKU: in.SKU}
+	p.ID = cuid.New()
+	s.d.products[p.ID] = p
+	row := productRow(p)
+	return &row, nil
+}
+
+func (s *catalog) UpdateProduct(_ context.Context, id string, in store.ProductInput) (*store.ProductRow, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	p, ok := s.d.products[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	if s.d.productTaken(id, in.Name, in.SKU) {
+		return nil, store.ErrConflict
+	}
+	p.Name, p.Wattage, p.Category, p.Description, p.SKU = in.Name, in.Wattage, in.Category, in.Description, in.SKU
+	row := productRow(p)
+	return &row, nil
+}
+
+func (s *catalog) ArchiveProduct(_ context.Context, id string, archived bool) (*store.ProductRow, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	p, ok := s.d.products[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	setArchived(&p.ArchivedAt, archived)
+	row := productRow(p)
+	return &row, nil
+}
+
+func (s *catalog) DeleteProduct(_ context.Context, id string) (bool, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	p, ok := s.d.products[id]
+	if !ok {
+		return false, store.ErrNotFound
+	}
+	for _, e := range s.d.existing {
+		if e.ProductID == id {
+			setArchived(&p.ArchivedAt, true)
+			return true, nil
+		}
+	}
+	delete(s.d.products, id)
+	s.d.deleteRecommendations(func(r *models.FixtureRecommendation) bool { return deref(r.ProductID) == id })
+	return false, nil
+}
+
+func (s *catalog) CreateFixtureType(_ context.Context, in store.FixtureTypeInput) (*store.FixtureTypeRow, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	if s.d.fixtureTypeTaken("", in.Name, in.SKU) {
+		return nil, store.ErrConflict
+	}
+	t := &models.LightFixtureType{CreatedAt: time.Now()}
+	setFixtureType(t, in)
+	t.ID = cuid.New()
+	s.d.fixtureTypes[t.ID] = t
+	row := fixtureTypeRow(t)
+	return &row, nil
+}
+
+func (s *catalog) UpdateFixtureType(_ context.Context, id string, in store.FixtureTypeInput) (*store.FixtureTypeRow, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	t, ok := s.d.fixtureTypes[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	if s.d.fixtureTypeTaken(id, in.Name, in.SKU) {
+		return nil, store.ErrConflict
+	}
+	setFixtureType(t, in)
+	row := fixtureTypeRow(t)
+	return &row, nil
+}
+
+func (s *catalog) ArchiveFixtureType(_ context.Context, id string, archived bool) (*store.FixtureTypeRow, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	t, ok := s.d.fixtureTypes[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	setArchived(&t.ArchivedAt, archived)
+	row := fixtureTypeRow(t)
+	return &row, nil
+}
+
+// DeleteFixtureType only checks suggested lines; the memory store keeps no
+// case fixture counts.
+func (s *catalog) DeleteFixtureType(_ context.Context, id string) (bool, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	t, ok := s.d.fixtureTypes[id]
+	if !ok {
+		return false, store.ErrNotFound
+	}
+	for _, sp := range s.d.suggested {
+		if sp.ProductID == id {
+			setArchived(&t.ArchivedAt, true)
+			return true, nil
+		}
+	}
+	delete(s.d.fixtureTypes, id)
+	s.d.deleteRecommendations(func(r *models.FixtureRecommendation) bool { return r.FixtureTypeID == id })
+	return false, nil
+}
+
+func (s *catalog) ExportProducts(_ context.Context, includeArchived bool) ([]store.ProductRow, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	var rows []store.ProductRow
+	for _, p := range s.d.products {
+		if p.ArchivedAt == nil || includeArchived {
+			rows = append(rows, productRow(p))
+		}
+	}
+	sortByName(rows, func(r store.ProductRow) (string, string) { return r.Name, r.ID })
+	return rows, nil
+}
+
+func (s *catalog) ExportFixtureTypes(_ context.Context, includeArchived bool) ([]store.FixtureTypeRow, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	var rows []store.FixtureTypeRow
+	for _, t := range s.d.fixtureTypes {
+		if t.ArchivedAt == nil || includeArchived {
+			rows = append(rows, fixtureTypeRow(t))
+		}
+	}
+	sortByName(rows, func(r store.FixtureTypeRow) (string, string) { return r.Name, r.ID })
+	return rows, nil
+}
+
+// The imports check names before writing anything, standing in for the
+// Postgres transaction rolling back on a unique violation.
+
+func (s *catalog) ImportProducts(_ context.Context, plan func([]store.ProductRow) ([]store.ProductRow, []store.ProductRow, bool)) error {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	var existing []store.ProductRow
+	for _, p := range s.d.products {
+		existing = append(existing, productRow(p))
+	}
+	sortByName(existing, func(r store.ProductRow) (string, string) { return r.Name, r.ID })
+	creates, updates, commit := plan(existing)
+	if !commit {
+		return nil
+	}
+	names, skus := map[string]string{}, map[string]string{}
+	for _, p := range s.d.products {
+		names[p.Name] = p.ID
+		if p.SKU != nil {
+			skus[*p.SKU] = p.ID
+		}
+	}
+	for _, p := range updates {
+		cur := s.d.products[p.ID]
+		delete(names, cur.Name)
+		if cur.SKU != nil {
+			delete(skus, *cur.SKU)
+		}
+	}
+	for _, p := range append(updates, creates...) {
+		if _, taken := names[p.Name]; taken {
+			return store.ErrConflict
+		}
+		names[p.Name] = p.ID
+		if p.SKU != nil {
+			if _, taken := skus[*p.SKU]; taken {
+				return store.ErrConflict
+			}
+			skus[*p.SKU] = p.ID
+		}
+	}
+	for _, p := range updates {
+		cur := s.d.products[p.ID]
+		cur.Name, cur.Wattage, cur.Category, cur.Description, cur.SKU = p.Name, p.Wattage, p.Category, p.Description, p.SKU
+	}
+	for _, p := range creates {
+		row := &models.Product{Name: p.Name, Wattage: p.Wattage, Category: p.Category, Description: p.Description, SKU: p.SKU}
+		row.ID = cuid.New()
+		s.d.products[row.ID] = row
+	}
+	return nil
+}
+
+func (s *catalog) ImportFixtureTypes(_ context.Context, plan func([]store.FixtureTypeRow) ([]store.FixtureTypeRow, []store.FixtureTypeRow, bool)) error {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	var existing []store.FixtureTypeRow
+	for _, t := range s.d.fixtureTypes {
+		existing = append(existing, fixtureTypeRow(t))
+	}
+	sortByName(existing, func(r store.FixtureTypeRow) (string, string) { return r.Name, r.ID })
+	creates, updates, commit := plan(existing)
+	if !commit {
+		return nil
+	}
+	names, skus := map[string]string{}, map[string]string{}
+	for _, t := range s.d.fixtureTypes {
+		names[t.Name] = t.ID
+		if t.SKU != nil {
+			skus[*t.SKU] = t.ID
+		}
+	}
+	for _, t := range updates {
+		cur := s.d.fixtureTypes[t.ID]
+		delete(names, cur.Name)
+		if cur.SKU != nil {
+			delete(skus, *cur.SKU)
+		}
+	}
+	for _, t := range append(updates, creates...) {
+		if _, taken := names[t.Name]; taken {
+			return store.ErrConflict
+		}
+		names[t.Name] = t.ID
+		if t.SKU != nil {
+			if _, taken := skus[*t.SKU]; taken {
+				return store.ErrConflict
+			}
+			skus[*t.SKU] = t.ID
+		}
+	}
+	for _, t := range updates {
+		setFixtureType(s.d.fixtureTypes[t.ID], fixtureTypeInput(t))
+	}
+	for _, t := range creates {
+		row := &models.LightFixtureType{CreatedAt: time.Now()}
+		setFixtureType(row, fixtureTypeInput(t))
+		row.ID = cuid.New()
+		s.d.fixtureTypes[row.ID] = row
+	}
+	return nil
+}
+
+func sortByName[T any](rows []T, key func(T) (string, string)) {
+	sort.Slice(rows, func(i, j int) bool {
+		ni, ii := key(rows[i])
+		nj, ij := key(rows[j])
+		if ni != nj {
+			return ni < nj
+		}
+		return ii < ij
+	})
+}
+
+func pageByName[T any](rows []T, p pagination.Params, key func(T) (string, string)) (pagination.Page[T], error) {
+	total := int64(len(rows))
+	page, err := keysetPage(rows, p, "name", pagination.String, false,
+		func(r T) any { name, _ := key(r); return name },
+		func(r T) string { _, id := key(r); return id })
+	if err != nil {
+		return page, err
+	}
+	if p.WithTotal {
+		page.Total = &total
+	}
+	return page, nil
+}
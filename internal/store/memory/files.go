@@ -0,0 +1,151 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lucsky/cuid"
+	"github.com/rick/go-neon-api/internal/models"
+	"github.com/rick/go-neon-api/internal/store"
+)
+
+type files struct{ d *DB }
+
+// casePhotos must be called with d.mu held.
+func (d *DB) casePhotos(caseID string) []store.PhotoRow {
+	rows := []store.PhotoRow{}
+	for _, ph := range d.photos {
+		if ph.CaseID == caseID {
+			rows = append(rows, store.PhotoRow{
+				ID: ph.ID, URL: ph.URL, Comment: ph.Comment, CustomName: ph.CustomName,
+				UploadedViaLink: ph.UploadedViaLink, CreatedAt: ph.CreatedAt,
+				PhotoMeta: store.PhotoMeta{
+					ThumbnailURL: ph.ThumbnailURL, TakenAt: ph.TakenAt, Latitude: ph.Latitude, Longitude: ph.Longitude,
+				},
+			})
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].CreatedAt.After(rows[j].CreatedAt) })
+	return rows
+}
+
+// caseDocuments must be called with d.mu held.
+func (d *DB) caseDocuments(caseID string) []store.DocumentRow {
+	rows := []store.DocumentRow{}
+	for _, doc := range d.documents {
+		if doc.CaseID == caseID {
+			rows = append(rows, store.DocumentRow{
+				ID: doc.ID, URL: doc.URL, FileName: doc.FileName, CustomName: doc.CustomName,
+				Comment: doc.Comment, UploadedViaLink: doc.UploadedViaLink, CreatedAt: doc.CreatedAt,
+			})
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].CreatedAt.After(rows[j].CreatedAt) })
+	return rows
+}
+
+func (s *files) Photos(_ context.Context, caseID string) ([]store.PhotoRow, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	return s.d.casePhotos(caseID), nil
+}
+
+func (s *files) Documents(_ context.Context, caseID string) ([]store.DocumentRow, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	return s.d.caseDocuments(caseID), nil
+}
+
+func (s *files) AddPhoto(_ context.Context, actorID string, p *models.Photo) error {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	if _, ok := s.d.cases[p.CaseID]; !ok {
+		return store.ErrNotFound
+	}
+	if p.ID == "" {
+		p.ID = cuid.New()
+	}
+	p.CreatedAt = time.Now()
+	cp := *p
+	s.d.photos[p.ID] = &cp
+	action := "Photo added"
+	if p.UploadedViaLink {
+		action = "Photo uploaded by customer"
+	}
+	s.d.logActivity(p.CaseID, actorID, action)
+	return nil
+}
+
+func (s *files) AddDocument(_ context.Context, actorID string, doc *models.Document) error {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	if _, ok := s.d.cases[doc.CaseID]; !ok {
+		return store.ErrNotFound
+	}
+	if doc.ID == "" {
+		doc.ID = cuid.New()
+	}
+	doc.CreatedAt = time.Now()
+	cp := *doc
+	s.d.documents[doc.ID] = &cp
+	action := "Document added: "
+	if doc.UploadedViaLink {
+		action = "Document uploaded by customer: "
+	}
+	s.d.logActivity(doc.CaseID, actorID, action+doc.FileName)
+	return nil
+}
+
+func (s *files) UpdatePhoto(_ context.Context, actorID, caseID, id string, set map[string]any) error {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	p, ok := s.d.photos[id]
+	if !ok || p.CaseID != caseID {
+		return store.ErrNotFound
+	}
+	if err := patch(p, set); err != nil {
+		return err
+	}
+	s.d.logActivity(caseID, actorID, "Photo updated: "+strings.Join(sortedKeys(set), ", "))
+	return nil
+}
+
+func (s *files) UpdateDocument(_ context.Context, actorID, caseID, id string, set map[string]any) error {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	doc, ok := s.d.documents[id]
+	if !ok || doc.CaseID != caseID {
+		return store.ErrNotFound
+	}
+	if err := patch(doc, set); err != nil {
+		return err
+	}
+	s.d.logActivity(caseID, actorID, "Document updated: "+strings.Join(sortedKeys(set), ", "))
+	return nil
+}
+
+func (s *files) DeletePhoto(_ context.Context, actorID, caseID, id string) (string, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	p, ok := s.d.photos[id]
+	if !ok || p.CaseID != caseID {
+		return "", store.ErrNotFound
+	}
+	delete(s.d.photos, id)
+	s.d.logActivity(caseID, actorID, "Photo deleted")
+	return p.URL, nil
+}
+
+func (s *files) DeleteDocument(_ context.Context, actorID, caseID, id string) (string, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	doc, ok := s.d.documents[id]
+	if !ok || doc.CaseID != caseID {
+		return "", store.ErrNotFound
+	}
+	delete(s.d.documents, id)
+	s.d.logActivity(caseID, actorID, "Document deleted: "+doc.FileName)
+	return doc.URL, nil
+}
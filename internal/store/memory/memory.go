@@ -0,0 +1,323 @@
+// Package memory is an in-process implementation of the store interfaces for
+// handler tests. It keeps models in maps behind one mutex and mirrors the
+// Postgres store's ordering, filtering and error semantics closely enough
+// for request-level tests; it does not emulate SQL collation.
+package memory
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lucsky/cuid"
+	"github.com/rick/go-neon-api/internal/models"
+	"github.com/rick/go-neon-api/internal/pagination"
+	"github.com/rick/go-neon-api/internal/store"
+)
+
+// DB holds every table the stores touch.
+type DB struct {
+	mu sync.Mutex
+
+	users       map[string]*models.User
+	revoked     map[string]time.Time
+	resetTokens map[string]*models.PasswordResetToken // by tokenHash
+
+	cases    map[string]*models.Case
+	activity []models.ActivityLog
+	statuses []models.CaseStatusChange
+
+	visits    map[string]*models.OnSiteVisit
+	rooms     map[string]*models.OnSiteVisitRoom
+	existing  map[string]*models.OnSiteExistingProduct
+	suggested map[string]*models.OnSiteSuggestedProduct
+
+	roomPhotos   map[string]*models.OnSiteVisitPhoto
+	photoTags    map[string]*models.OnSitePhotoTag
+	photoLinks   map[string]*models.OnSiteVisitPhotoTagPivot
+	locationTags map[string]*models.OnSiteLocationTag
+
+	products        map[string]*models.Product
+	fixtureTypes    map[string]*models.LightFixtureType
+	accessories     map[string]*models.Accessory // by kind
+	recommendations map[string]*models.FixtureRecommendation
+
+	photos    map[string]*models.Photo
+	documents map[string]*models.Document
+
+	quoteCounters   map[string]int // QuoteCounter.count by caseId
+	quotes          map[string]*models.Quote
+	paybackSettings map[string]*models.PaybackSetting // by caseId
+}
+
+func New() *DB {
+	return &DB{
+		users:        map[string]*models.User{},
+		revoked:      map[string]time.Time{},
+		resetTokens:  map[string]*models.PasswordResetToken{},
+		cases:        map[string]*models.Case{},
+		visits:       map[string]*models.OnSiteVisit{},
+		rooms:        map[string]*models.OnSiteVisitRoom{},
+		existing:     map[string]*models.OnSiteExistingProduct{},
+		suggested:    map[string]*models.OnSiteSuggestedProduct{},
+		roomPhotos:   map[string]*models.OnSiteVisitPhoto{},
+		photoTags:    map[string]*models.OnSitePhotoTag{},
+		photoLinks:   map[string]*models.OnSiteVisitPhotoTagPivot{},
+		locationTags: map[string]*models.OnSiteLocationTag{},
+		products:     map[string]*models.Product{},
+		fixtureTypes: map[string]*models.LightFixtureType{},
+		accessories: map[string]*models.Accessory{
+			// Seeded like the migration does.
+			models.AccessoryMountingKit: {
+				BaseStringID: models.BaseStringID{ID: "accessory-mounting-kit"},
+				Kind:         models.AccessoryMountingKit, Name: "Mounting kit", UpdatedAt: time.Now(),
+			},
+			models.AccessoryMotionSensor: {
+				BaseStringID: models.BaseStringID{ID: "accessory-motion-sensor"},
+				Kind:         models.AccessoryMotionSensor, Name: "Motion sensor", UpdatedAt: time.Now(),
+			},
+		},
+		recommendations: map[string]*models.FixtureRecommendation{},
+		photos:          map[string]*models.Photo{},
+		documents:       map[string]*models.Document{},
+		quoteCounters:   map[string]int{},
+		quotes:          map[string]*models.Quote{},
+		paybackSettings: map[string]*models.PaybackSetting{},
+	}
+}
+
+// Store returns the per-aggregate stores backed by d.
+func (d *DB) Store() *store.Store {
+	return &store.Store{
+		Users:   &users{d},
+		Cases:   &cases{d},
+		OnSite:  &onSite{d},
+		Catalog: &catalog{d},
+		Files:   &files{d},
+		Quotes:  &quotes{d},
+	}
+}
+
+// ---------- Seeding / inspection ----------
+
+// PutUser inserts or replaces a user; an empty ID is filled in.
+func (d *DB) PutUser(u models.User) *models.User {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if u.ID == "" {
+		u.ID = cuid.New()
+	}
+	if u.Role == "" {
+		u.Role = models.RoleUser
+	}
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now()
+		u.UpdatedAt = u.CreatedAt
+	}
+	d.users[u.ID] = &u
+	return &u
+}
+
+// PutProduct inserts or replaces an existing-lighting catalog row.
+func (d *DB) PutProduct(p models.Product) *models.Product {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if p.ID == "" {
+		p.ID = cuid.New()
+	}
+	d.products[p.ID] = &p
+	return &p
+}
+
+// PutFixtureType inserts or replaces a suggested-lighting catalog row.
+func (d *DB) PutFixtureType(t models.LightFixtureType) *models.LightFixtureType {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if t.ID == "" {
+		t.ID = cuid.New()
+	}
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	d.fixtureTypes[t.ID] = &t
+	return &t
+}
+
+// PutPaybackSetting sets a case's PaybackSetting value.
+func (d *DB) PutPaybackSetting(caseID string, value float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	d.paybackSettings[caseID] = &models.PaybackSetting{
+		BaseStringID: models.BaseStringID{ID: cuid.New()},
+		CaseID:       caseID, Value: value, CreatedAt: now, UpdatedAt: now,
+	}
+}
+
+// DeleteAccessory removes one of the seeded accessories.
+func (d *DB) DeleteAccessory(kind string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.accessories, kind)
+}
+
+// Activity returns the ActivityLog rows recorded for a case, oldest first.
+func (d *DB) Activity(caseID string) []models.ActivityLog {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var out []models.ActivityLog
+	for _, a := range d.activity {
+		if deref(a.CaseID) == caseID {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// logActivity must be called with d.mu held.
+func (d *DB) logActivity(caseID, userID, action string) {
+	a := models.ActivityLog{CaseID: &caseID, UserID: userID, Action: action, CreatedAt: time.Now()}
+	a.ID = cuid.New()
+	d.activity = append(d.activity, a)
+}
+
+// ---------- Helpers ----------
+
+// patch applies column → value pairs to a model through its JSON tags, which
+// match the column names for every patchable field. dst is left untouched on
+// error, mirroring a failed UPDATE.
+func patch[T any](dst *T, set map[string]any) error {
+	raw, err := json.Marshal(set)
+	if err != nil {
+		return err
+	}
+	next := *dst
+	if err := json.Unmarshal(raw, &next); err != nil {
+		return err
+	}
+	*dst = next
+	return nil
+}
+
+// sortedKeys returns the column names of set in order, as the SQL stores log
+// them.
+func sortedKeys(set map[string]any) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func containsFold(s, sub string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(sub))
+}
+
+// compare orders two sort values of the same pagination.Kind. Pointers are
+// followed and nil sorts after every value, as NULL does in an ascending
+// Postgres ORDER BY.
+func compare(a, b any) int {
+	a, b = sortValue(a), sortValue(b)
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	switch x := a.(type) {
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			return x.Compare(y)
+		}
+	case int64:
+		if y, ok := b.(int64); ok {
+			return cmp.Compare(x, y)
+		}
+	case float64:
+		if y, ok := b.(float64); ok {
+			return cmp.Compare(x, y)
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y)
+		}
+	}
+	// Mixed types only meet when a cursor was built from a NULL; compare
+	// the text the cursor would hold.
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// sortValue dereferences v and widens numbers to the int64 and float64 a
+// decoded cursor holds. A nil pointer becomes nil.
+func sortValue(v any) any {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	}
+	return rv.Interface()
+}
+
+// keysetPage sorts rows by (value, id), skips past the cursor and builds the
+// page the way the SQL stores do with LIMIT p.Limit+1.
+func keysetPage[T any](rows []T, p pagination.Params, sortName string, kind pagination.Kind, desc bool, value func(T) any, id func(T) string) (pagination.Page[T], error) {
+	order := func(a, b T) int {
+		c := compare(value(a), value(b))
+		if c == 0 {
+			c = strings.Compare(id(a), id(b))
+		}
+		if desc {
+			c = -c
+		}
+		return c
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return order(rows[i], rows[j]) < 0 })
+
+	after, afterID, ok, err := p.Position(sortName, kind, desc)
+	if err != nil {
+		return pagination.Page[T]{}, err
+	}
+	start := 0
+	if ok {
+		for start < len(rows) {
+			c := compare(value(rows[start]), after)
+			if c == 0 {
+				c = strings.Compare(id(rows[start]), afterID)
+			}
+			if desc {
+				c = -c
+			}
+			if c > 0 {
+				break
+			}
+			start++
+		}
+	}
+	rows = rows[start:]
+	if len(rows) > p.Limit+1 {
+		rows = rows[:p.Limit+1]
+	}
+	return pagination.NewPage(rows, p, sortName, desc, func(r T) (any, string) { return value(r), id(r) }), nil
+}
@@ -0,0 +1,31 @@
+package memory
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompare(t *testing.T) {
+	name := "b"
+	now := time.Now()
+	for _, tc := range []struct {
+		a, b any
+		want int
+	}{
+		{"a", "b", -1},
+		{int64(3), int64(3), 0},
+		{7, int64(3), 1}, // an int row value against a decoded cursor
+		{float32(1.5), 2.0, -1},
+		{now, now.Add(time.Second), -1},
+		{&name, "a", 1},
+		{(*string)(nil), "z", 1}, // NULLs last
+		{"z", (*string)(nil), -1},
+		{(*time.Time)(nil), (*string)(nil), 0},
+		{(*int)(nil), nil, 0},
+		{true, "false", 1}, // no Postgres type of its own; compared as text
+	} {
+		if got := compare(tc.a, tc.b); got != tc.want {
+			t.Errorf("compare(%#v, %#v) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
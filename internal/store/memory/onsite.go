@@ -0,0 +1,582 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/lucsky/cuid"
+	"github.com/rick/go-neon-api/internal/models"
+	"github.com/rick/go-neon-api/internal/store"
+)
+
+type onSite struct{ d *DB }
+
+func visitRow(v *models.OnSiteVisit) *store.Visit {
+	return &store.Visit{ID: v.ID, CaseID: v.CaseID, CreatedAt: v.CreatedAt}
+}
+
+// visitFor must be called with d.mu held.
+func (d *DB) visitFor(caseID string) *models.OnSiteVisit {
+	for _, v := range d.visits {
+		if v.CaseID == caseID {
+			return v
+		}
+	}
+	return nil
+}
+
+func (s *onSite) Visit(_ context.Context, caseID string) (*store.Visit, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	v := s.d.visitFor(caseID)
+	if v == nil {
+		return nil, store.ErrNotFound
+	}
+	return visitRow(v), nil
+}
+
+func (s *onSite) EnsureVisit(_ context.Context, caseID string) (*store.Visit, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	if v := s.d.visitFor(caseID); v != nil {
+		return visitRow(v), nil
+	}
+	if _, ok := s.d.cases[caseID]; !ok {
+		return nil, store.ErrNotFound
+	}
+	v := &models.OnSiteVisit{CaseID: caseID, CreatedAt: time.Now()}
+	v.ID = cuid.New()
+	s.d.visits[v.ID] = v
+	return visitRow(v), nil
+}
+
+// roomRow must be called with d.mu held.
+func (d *DB) roomRow(r *models.OnSiteVisitRoom) store.Room {
+	var tagName *string
+	if r.LocationTagID != nil {
+		if t, ok := d.locationTags[*r.LocationTagID]; ok {
+			tagName = &t.Name
+		}
+	}
+	return store.Room{
+		ID:              r.ID,
+		OnSiteVisitID:   r.OnSiteVisitID,
+		Location:        r.Location,
+		LocationTagID:   r.LocationTagID,
+		LocationTag:     tagName,
+		LightingIssue:   r.LightingIssue,
+		CustomerRequest: r.CustomerRequest,
+		MountingKitQty:  r.MountingKitQty,
+		MotionSensorQty: r.MotionSensorQty,
+		CreatedAt:       r.CreatedAt,
+		CeilingHeight:   r.CeilingHeight,
+		Existing:        []store.ExistingLine{},
+		Suggested:       []store.SuggestedLine{},
+		Photos:          []store.RoomPhoto{},
+	}
+}
+
+func (s *onSite) Rooms(_ context.Context, visitID string) ([]store.Room, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	rooms := []store.Room{}
+	index := map[string]int{}
+	for _, r := range s.d.rooms {
+		if r.OnSiteVisitID == visitID {
+			index[r.ID] = len(rooms)
+			rooms = append(rooms, s.d.roomRow(r))
+		}
+	}
+	// Fixtures join their catalog row; lines whose row is gone are dropped,
+	// as with the SQL inner join.
+	for _, e := range s.d.existing {
+		i, ok := index[e.RoomID]
+		p, found := s.d.products[e.ProductID]
+		if !ok || !found {
+			continue
+		}
+		rooms[i].Existing = append(rooms[i].Existing, store.ExistingLine{
+			ID: e.ID, RoomID: e.RoomID, ProductID: e.ProductID, ProductName: p.Name, ProductWatt: p.Wattage,
+			Category: p.Category, Quantity: e.Quantity, BypassBallast: e.BypassBallast,
+		})
+	}
+	for _, sg := range s.d.suggested {
+		i, ok := index[sg.RoomID]
+		t, found := s.d.fixtureTypes[sg.ProductID]
+		if !ok || !found {
+			continue
+		}
+		rooms[i].Suggested = append(rooms[i].Suggested, store.SuggestedLine{
+			ID: sg.ID, RoomID: sg.RoomID, ProductID: sg.ProductID, TypeName: t.Name, SKU: t.SKU,
+			ImageURL: t.ImageURL, Wattage: t.Wattage, Quantity: sg.Quantity, Pricing: fixtureTypePricing(t),
+		})
+	}
+
+	inVisit := func(p *models.OnSiteVisitPhoto) bool {
+		_, ok := index[p.RoomID]
+		return ok
+	}
+	for _, p := range s.d.photosWhere(inVisit) {
+		i := index[p.RoomID]
+		rooms[i].Photos = append(rooms[i].Photos, p)
+	}
+
+	sort.Slice(rooms, func(i, j int) bool { return rooms[i].CreatedAt.After(rooms[j].CreatedAt) })
+	for i := range rooms {
+		ex, sg := rooms[i].Existing, rooms[i].Suggested
+		sort.Slice(ex, func(a, b int) bool { return ex[a].ID < ex[b].ID })
+		sort.Slice(sg, func(a, b int) bool { return sg[a].ID < sg[b].ID })
+	}
+	return rooms, nil
+}
+
+// ---------- Rooms ----------
+
+func (s *onSite) RoomVisit(_ context.Context, roomID string) (string, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	r, ok := s.d.rooms[roomID]
+	if !ok {
+		return "", store.ErrNotFound
+	}
+	return r.OnSiteVisitID, nil
+}
+
+func (s *onSite) CreateRoom(_ context.Context, visitID string, in store.RoomInput) (*store.Room, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	if _, ok := s.d.visits[visitID]; !ok {
+		return nil, store.ErrNotFound
+	}
+	if in.LocationTagID != nil {
+		if _, ok := s.d.locationTags[*in.LocationTagID]; !ok {
+			return nil, store.ErrNotFound
+		}
+	}
+	r := &models.OnSiteVisitRoom{
+		OnSiteVisitID:   visitID,
+		Location:        in.Location,
+		LocationTagID:   in.LocationTagID,
+		LightingIssue:   in.LightingIssue,
+		CustomerRequest: in.CustomerRequest,
+		MountingKitQty:  in.MountingKitQty,
+		MotionSensorQty: in.MotionSensorQty,
+		CreatedAt:       time.Now(),
+		CeilingHeight:   in.CeilingHeight,
+	}
+	r.ID = cuid.New()
+	s.d.rooms[r.ID] = r
+	row := s.d.roomRow(r)
+	return &row, nil
+}
+
+func (s *onSite) UpdateRoom(_ context.Context, id string, set map[string]any) error {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	r, ok := s.d.rooms[id]
+	if !ok {
+		return store.ErrNotFound
+	}
+	if tagID, ok := set["locationTagId"].(string); ok {
+		if _, found := s.d.locationTags[tagID]; !found {
+			return store.ErrNotFound
+		}
+	}
+	return patch(r, set)
+}
+
+func (s *onSite) DeleteRoom(_ context.Context, id string) ([]string, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	if _, ok := s.d.rooms[id]; !ok {
+		return nil, store.ErrNotFound
+	}
+	return s.d.deleteRoom(id), nil
+}
+
+// deleteRoom removes a room with its photos and fixtures and returns the
+// photo urls; d.mu must be held.
+func (d *DB) deleteRoom(id string) []string {
+	var urls []string
+	for pid, p := range d.roomPhotos {
+		if p.RoomID == id {
+			urls = append(urls, p.URL)
+			d.deleteRoomPhoto(pid)
+		}
+	}
+	for eid, e := range d.existing {
+		if e.RoomID == id {
+			delete(d.existing, eid)
+		}
+	}
+	for sid, sg := range d.suggested {
+		if sg.RoomID == id {
+			delete(d.suggested, sid)
+		}
+	}
+	delete(d.rooms, id)
+	return urls
+}
+
+// ---------- Fixtures ----------
+
+func (s *onSite) AddExisting(_ context.Context, roomID, productID string, quantity int, bypassBallast bool) (string, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	if _, ok := s.d.rooms[roomID]; !ok {
+		return "", store.ErrNotFound
+	}
+	if _, ok := s.d.products[productID]; !ok {
+		return "", store.ErrNotFound
+	}
+	e := &models.OnSiteExistingProduct{RoomID: roomID, ProductID: productID, Quantity: quantity, BypassBallast: bypassBallast}
+	e.ID = cuid.New()
+	s.d.existing[e.ID] = e
+	return e.ID, nil
+}
+
+func (s *onSite) UpdateExisting(_ context.Context, id string, set map[string]any) error {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	e, ok := s.d.existing[id]
+	if !ok {
+		return store.ErrNotFound
+	}
+	return patch(e, set)
+}
+
+func (s *onSite) DeleteExisting(_ context.Context, id string) error {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	if _, ok := s.d.existing[id]; !ok {
+		return store.ErrNotFound
+	}
+	delete(s.d.existing, id)
+	return nil
+}
+
+func (s *onSite) AddSuggested(_ context.Context, roomID, fixtureTypeID string, quantity int) (string, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	if _, ok := s.d.rooms[roomID]; !ok {
+		return "", store.ErrNotFound
+	}
+	sg := &models.OnSiteSuggestedProduct{RoomID: roomID, ProductID: fixtureTypeID, Quantity: quantity}
+	sg.ID = cuid.New()
+	s.d.suggested[sg.ID] = sg
+	return sg.ID, nil
+}
+
+func (s *onSite) AddSuggestedMissing(_ context.Context, lines []store.NewSuggested) ([]store.NewSuggested, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	type key struct{ room, fixtureType string }
+	skip := map[key]bool{}
+	for _, sg := range s.d.suggested {
+		skip[key{sg.RoomID, sg.ProductID}] = true
+	}
+	added := []store.NewSuggested{}
+	for _, l := range lines {
+		k := key{l.RoomID, l.FixtureTypeID}
+		if _, ok := s.d.rooms[l.RoomID]; !ok || skip[k] {
+			continue
+		}
+		sg := &models.OnSiteSuggestedProduct{RoomID: l.RoomID, ProductID: l.FixtureTypeID, Quantity: l.Quantity}
+		sg.ID = cuid.New()
+		s.d.suggested[sg.ID] = sg
+		l.ID = sg.ID
+		added = append(added, l)
+		skip[k] = true
+	}
+	return added, nil
+}
+
+func (s *onSite) UpdateSuggested(_ context.Context, id string, set map[string]any) error {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	sg, ok := s.d.suggested[id]
+	if !ok {
+		return store.ErrNotFound
+	}
+	return patch(sg, set)
+}
+
+func (s *onSite) DeleteSuggested(_ context.Context, id string) error {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	if _, ok := s.d.suggested[id]; !ok {
+		return store.ErrNotFound
+	}
+	delete(s.d.suggested, id)
+	return nil
+}
+
+// ---------- Photos ----------
+
+// photosWhere returns the matching photos oldest first with their tags by
+// name; d.mu must be held.
+func (d *DB) photosWhere(match func(*models.OnSiteVisitPhoto) bool) []store.RoomPhoto {
+	photos := []store.RoomPhoto{}
+	index := map[string]int{}
+	for _, p := range d.roomPhotos {
+		if match(p) {
+			index[p.ID] = len(photos)
+			photos = append(photos, roomPhotoRow(p))
+		}
+	}
+	for _, l := range d.photoLinks {
+		i, ok := index[l.PhotoID]
+		t, found := d.photoTags[l.TagID]
+		if !ok || !found {
+			continue
+		}
+		photos[i].Tags = append(photos[i].Tags, photoTagRow(t))
+	}
+	sort.Slice(photos, func(i, j int) bool {
+		if !photos[i].CreatedAt.Equal(photos[j].CreatedAt) {
+			return photos[i].CreatedAt.Before(photos[j].CreatedAt)
+		}
+		return photos[i].ID < photos[j].ID
+	})
+	for i := range photos {
+		tags := photos[i].Tags
+		sort.Slice(tags, func(a, b int) bool { return tags[a].Name < tags[b].Name })
+	}
+	return photos
+}
+
+// deleteRoomPhoto removes a photo and its tag links; d.mu must be held.
+func (d *DB) deleteRoomPhoto(id string) {
+	for lid, l := range d.photoLinks {
+		if l.PhotoID == id {
+			delete(d.photoLinks, lid)
+		}
+	}
+	delete(d.roomPhotos, id)
+}
+
+func (s *onSite) RoomPhotos(_ context.Context, roomID string) ([]store.RoomPhoto, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	return s.d.photosWhere(func(p *models.OnSiteVisitPhoto) bool { return p.RoomID == roomID }), nil
+}
+
+func roomPhotoRow(p *models.OnSiteVisitPhoto) store.RoomPhoto {
+	return store.RoomPhoto{
+		ID: p.ID, RoomID: p.RoomID, URL: p.URL, Comment: p.Comment, CreatedAt: p.CreatedAt,
+		PhotoMeta: store.PhotoMeta{
+			ThumbnailURL: p.ThumbnailURL, TakenAt: p.TakenAt, Latitude: p.Latitude, Longitude: p.Longitude,
+		},
+		Tags: []store.Tag{},
+	}
+}
+
+func (s *onSite) AddRoomPhoto(_ context.Context, roomID, url, comment string, meta store.PhotoMeta) (*store.RoomPhoto, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	if _, ok := s.d.rooms[roomID]; !ok {
+		return nil, store.ErrNotFound
+	}
+	p := &models.OnSiteVisitPhoto{
+		RoomID: roomID, URL: url, Comment: comment, CreatedAt: time.Now(),
+		ThumbnailURL: meta.ThumbnailURL, TakenAt: meta.TakenAt, Latitude: meta.Latitude, Longitude: meta.Longitude,
+	}
+	p.ID = cuid.New()
+	s.d.roomPhotos[p.ID] = p
+	row := roomPhotoRow(p)
+	return &row, nil
+}
+
+func (s *onSite) SetRoomPhotoComment(_ context.Context, id, comment string) error {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	p, ok := s.d.roomPhotos[id]
+	if !ok {
+		return store.ErrNotFound
+	}
+	p.Comment = comment
+	return nil
+}
+
+func (s *onSite) DeleteRoomPhoto(_ context.Context, id string) (string, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	p, ok := s.d.roomPhotos[id]
+	if !ok {
+		return "", store.ErrNotFound
+	}
+	s.d.deleteRoomPhoto(id)
+	return p.URL, nil
+}
+
+func (s *onSite) TagRoomPhoto(_ context.Context, photoID, tagID string) error {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	if _, ok := s.d.roomPhotos[photoID]; !ok {
+		return store.ErrNotFound
+	}
+	if _, ok := s.d.photoTags[tagID]; !ok {
+		return store.ErrNotFound
+	}
+	for _, l := range s.d.photoLinks {
+		if l.PhotoID == photoID && l.TagID == tagID {
+			return nil
+		}
+	}
+	l := &models.OnSiteVisitPhotoTagPivot{PhotoID: photoID, TagID: tagID}
+	l.ID = cuid.New()
+	s.d.photoLinks[l.ID] = l
+	return nil
+}
+
+func (s *onSite) UntagRoomPhoto(_ context.Context, photoID, tagID string) error {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	for lid, l := range s.d.photoLinks {
+		if l.PhotoID == photoID && l.TagID == tagID {
+			delete(s.d.photoLinks, lid)
+			return nil
+		}
+	}
+	return store.ErrNotFound
+}
+
+// ---------- Tag vocabularies ----------
+
+func photoTagRow(t *models.OnSitePhotoTag) store.Tag {
+	return store.Tag{ID: t.ID, Name: t.Name, CreatedAt: t.CreatedAt}
+}
+
+func locationTagRow(t *models.OnSiteLocationTag) store.Tag {
+	return store.Tag{ID: t.ID, Name: t.Name, CreatedAt: t.CreatedAt}
+}
+
+// photoTagNamed must be called with d.mu held.
+func (d *DB) photoTagNamed(name string) *models.OnSitePhotoTag {
+	for _, t := range d.photoTags {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+func (s *onSite) PhotoTags(_ context.Context) ([]store.Tag, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	tags := []store.Tag{}
+	for _, t := range s.d.photoTags {
+		tags = append(tags, photoTagRow(t))
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
+
+func (s *onSite) CreatePhotoTag(_ context.Context, name string) (*store.Tag, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	if s.d.photoTagNamed(name) != nil {
+		return nil, store.ErrConflict
+	}
+	t := &models.OnSitePhotoTag{Name: name, CreatedAt: time.Now()}
+	t.ID = cuid.New()
+	s.d.photoTags[t.ID] = t
+	row := photoTagRow(t)
+	return &row, nil
+}
+
+func (s *onSite) RenamePhotoTag(_ context.Context, id, name string) error {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	t, ok := s.d.photoTags[id]
+	if !ok {
+		return store.ErrNotFound
+	}
+	if other := s.d.photoTagNamed(name); other != nil && other.ID != id {
+		return store.ErrConflict
+	}
+	t.Name = name
+	return nil
+}
+
+func (s *onSite) DeletePhotoTag(_ context.Context, id string) error {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	if _, ok := s.d.photoTags[id]; !ok {
+		return store.ErrNotFound
+	}
+	for _, l := range s.d.photoLinks {
+		if l.TagID == id {
+			return store.ErrConflict
+		}
+	}
+	delete(s.d.photoTags, id)
+	return nil
+}
+
+// locationTagNamed must be called with d.mu held.
+func (d *DB) locationTagNamed(name string) *models.OnSiteLocationTag {
+	for _, t := range d.locationTags {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+func (s *onSite) LocationTags(_ context.Context) ([]store.Tag, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	tags := []store.Tag{}
+	for _, t := range s.d.locationTags {
+		tags = append(tags, locationTagRow(t))
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
+
+func (s *onSite) CreateLocationTag(_ context.Context, name string) (*store.Tag, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	if s.d.locationTagNamed(name) != nil {
+		return nil, store.ErrConflict
+	}
+	t := &models.OnSiteLocationTag{Name: name, CreatedAt: time.Now()}
+	t.ID = cuid.New()
+	s.d.locationTags[t.ID] = t
+	row := locationTagRow(t)
+	return &row, nil
+}
+
+func (s *onSite) RenameLocationTag(_ context.Context, id, name string) error {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	t, ok := s.d.locationTags[id]
+	if !ok {
+		return store.ErrNotFound
+	}
+	if other := s.d.locationTagNamed(name); other != nil && other.ID != id {
+		return store.ErrConflict
+	}
+	t.Name = name
+	return nil
+}
+
+func (s *onSite) DeleteLocationTag(_ context.Context, id string) error {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	if _, ok := s.d.locationTags[id]; !ok {
+		return store.ErrNotFound
+	}
+	for _, r := range s.d.rooms {
+		if r.LocationTagID != nil && *r.LocationTagID == id {
+			r.LocationTagID = nil
+		}
+	}
+	delete(s.d.locationTags, id)
+	s.d.deleteRecommendations(func(r *models.FixtureRecommendation) bool { return deref(r.LocationTagID) == id })
+	return nil
+}
@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/lucsky/cuid"
+	"github.com/rick/go-neon-api/internal/models"
+	"github.com/rick/go-neon-api/internal/store"
+)
+
+type quotes struct{ d *DB }
+
+func (s *quotes) List(_ context.Context, caseID string) ([]store.QuoteRow, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	rows := []store.QuoteRow{}
+	for _, q := range s.d.quotes {
+		if q.CaseID == caseID {
+			rows = append(rows, store.QuoteRow{
+				ID: q.ID, Number: q.Number, Revision: q.Revision, Note: q.Note,
+				TotalCost: q.TotalCost, TotalPrice: q.TotalPrice, LaborHours: q.LaborHours,
+				CreatedByID: q.CreatedByID, CreatedAt: q.CreatedAt,
+			})
+		}
+	}
+	slices.SortFunc(rows, func(a, b store.QuoteRow) int { return b.Revision - a.Revision })
+	return rows, nil
+}
+
+func (s *quotes) Get(_ context.Context, caseID string, revision int) (*models.Quote, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	for _, q := range s.d.quotes {
+		if q.CaseID == caseID && q.Revision == revision {
+			cp := *q
+			cp.Snapshot = slices.Clone(q.Snapshot)
+			return &cp, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (s *quotes) Create(_ context.Context, actorID string, q *models.Quote) error {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	if _, ok := s.d.cases[q.CaseID]; !ok {
+		return store.ErrNotFound
+	}
+	s.d.quoteCounters[q.CaseID]++
+	q.ID = cuid.New()
+	q.Revision = s.d.quoteCounters[q.CaseID]
+	q.Number = store.QuoteNumber(q.CaseID, q.Revision)
+	q.CreatedByID = actorID
+	q.CreatedAt = time.Now()
+	cp := *q
+	cp.Snapshot = slices.Clone(q.Snapshot)
+	s.d.quotes[q.ID] = &cp
+	s.d.logActivity(q.CaseID, actorID, "Quote created: "+q.Number)
+	return nil
+}
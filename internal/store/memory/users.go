@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/lucsky/cuid"
+	"github.com/rick/go-neon-api/internal/models"
+	"github.com/rick/go-neon-api/internal/store"
+)
+
+type users struct{ d *DB }
+
+func (s *users) ByID(_ context.Context, id string) (*models.User, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	u, ok := s.d.users[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	cp := *u
+	return &cp, nil
+}
+
+func (s *users) ByEmail(_ context.Context, email string) (*models.User, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	for _, u := range s.d.users {
+		if strings.EqualFold(u.Email, email) {
+			cp := *u
+			return &cp, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (s *users) SetPassword(_ context.Context, userID, hash string) error {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	return s.d.setPassword(userID, hash)
+}
+
+// setPassword must be called with d.mu held.
+func (d *DB) setPassword(userID, hash string) error {
+	u, ok := d.users[userID]
+	if !ok {
+		return store.ErrNotFound
+	}
+	now := time.Now()
+	u.Password = &hash
+	u.PasswordChangedAt = &now
+	u.UpdatedAt = now
+	return nil
+}
+
+func (s *users) IsRevoked(_ context.Context, tokenID string) (bool, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	_, ok := s.d.revoked[tokenID]
+	return ok, nil
+}
+
+func (s *users) Revoke(_ context.Context, tokenID string, expiresAt time.Time) error {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.revoked[tokenID] = expiresAt
+	now := time.Now()
+	for id, exp := range s.d.revoked {
+		if exp.Before(now) {
+			delete(s.d.revoked, id)
+		}
+	}
+	return nil
+}
+
+func (s *users) CreateResetToken(_ context.Context, userID, digest string, expiresAt time.Time) error {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	t := &models.PasswordResetToken{UserID: userID, TokenHash: digest, ExpiresAt: expiresAt, CreatedAt: time.Now()}
+	t.ID = cuid.New()
+	s.d.resetTokens[digest] = t
+	return nil
+}
+
+func (s *users) ConsumeResetToken(_ context.Context, digest, hash string) (string, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	t, ok := s.d.resetTokens[digest]
+	now := time.Now()
+	if !ok || t.UsedAt != nil || !t.ExpiresAt.After(now) {
+		return "", store.ErrNotFound
+	}
+	if err := s.d.setPassword(t.UserID, hash); err != nil {
+		return "", err
+	}
+	for _, other := range s.d.resetTokens {
+		if other.UserID == t.UserID && other.UsedAt == nil {
+			other.UsedAt = &now
+		}
+	}
+	return t.UserID, nil
+}
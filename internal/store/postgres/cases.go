@@ -0,0 +1,499 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lucsky/cuid"
+	"github.com/rick/go-neon-api/internal/pagination"
+	"github.com/rick/go-neon-api/internal/store"
+	"gorm.io/gorm"
+)
+
+type cases struct{ db *gorm.DB }
+
+// ownerSQL resolves a resource id to the owning Case."userId" by walking up
+// fixture or photo → room → visit → case.
+var ownerSQL = map[store.Resource]string{
+	store.CaseResource: `
+		SELECT c."userId" FROM "Case" c
+		 WHERE c."id" = ?`,
+	store.VisitResource: `
+		SELECT c."userId" FROM "OnSiteVisit" v
+		  JOIN "Case" c ON c."id" = v."caseId"
+		 WHERE v."id" = ?`,
+	store.RoomResource: `
+		SELECT c."userId" FROM "OnSiteVisitRoom" r
+		  JOIN "OnSiteVisit" v ON v."id" = r."onSiteVisitId"
+		  JOIN "Case" c ON c."id" = v."caseId"
+		 WHERE r."id" = ?`,
+	store.ExistingProductResource: `
+		SELECT c."userId" FROM "OnSiteExistingProduct" e
+		  JOIN "OnSiteVisitRoom" r ON r."id" = e."roomId"
+		  JOIN "OnSiteVisit" v ON v."id" = r."onSiteVisitId"
+		  JOIN "Case" c ON c."id" = v."caseId"
+		 WHERE e."id" = ?`,
+	store.SuggestedProductResource: `
+		SELECT c."userId" FROM "OnSiteSuggestedProduct" s
+		  JOIN "OnSiteVisitRoom" r ON r."id" = s."roomId"
+		  JOIN "OnSiteVisit" v ON v."id" = r."onSiteVisitId"
+		  JOIN "Case" c ON c."id" = v."caseId"
+		 WHERE s."id" = ?`,
+	store.RoomPhotoResource: `
+		SELECT c."userId" FROM "OnSiteVisitPhoto" p
+		  JOIN "OnSiteVisitRoom" r ON r."id" = p."roomId"
+		  JOIN "OnSiteVisit" v ON v."id" = r."onSiteVisitId"
+		  JOIN "Case" c ON c."id" = v."caseId"
+		 WHERE p."id" = ?`,
+}
+
+func (s *cases) OwnerOf(ctx context.Context, res store.Resource, id string) (string, error) {
+	query, ok := ownerSQL[res]
+	if !ok {
+		return "", fmt.Errorf("postgres: no owner query for resource %d", res)
+	}
+	var owner struct {
+		UserID string `gorm:"column:userId"`
+	}
+	if err := notFoundIfNone(s.db.WithContext(ctx).Raw(query, id).Scan(&owner)); err != nil {
+		return "", err
+	}
+	return owner.UserID, nil
+}
+
+// ---------- List ----------
+
+// caseSortColumns maps store.CaseSorts keys to SQL. Nullable columns are
+// wrapped in COALESCE because keyset comparisons skip NULLs.
+var caseSortColumns = map[string]string{
+	"createdAt":            `c."createdAt"`,
+	"updatedAt":            `c."updatedAt"`,
+	"customerName":         `c."customerName"`,
+	"projectDetails":       `c."projectDetails"`,
+	"schoolName":           `c."schoolName"`,
+	"contactPerson":        `c."contactPerson"`,
+	"emailAddress":         `c."emailAddress"`,
+	"phoneNumber":          `c."phoneNumber"`,
+	"schoolAddress":        `c."schoolAddress"`,
+	"status":               `c."status"`,
+	"installationService":  `c."installationService"`,
+	"operationDaysPerYear": `c."operationDaysPerYear"`,
+	"operationHoursPerDay": `c."operationHoursPerDay"`,
+	"userName":             `COALESCE(u."name", '')`,
+	"userEmail":            `u."email"`,
+}
+
+// caseSearchColumns are matched by CaseFilter.Words; each has a pg_trgm index
+// so the ILIKE '%term%' lookups stay indexed (migration 0004_case_search_indexes).
+var caseSearchColumns = []string{
+	`c."customerName"`,
+	`c."schoolName"`,
+	`c."contactPerson"`,
+	`c."emailAddress"`,
+	`c."schoolAddress"`,
+}
+
+// where accumulates AND-ed conditions and their args.
+type where struct {
+	conds []string
+	args  []any
+}
+
+func (w *where) add(cond string, args ...any) {
+	w.conds = append(w.conds, cond)
+	w.args = append(w.args, args...)
+}
+
+func (w *where) sql() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+	return ` WHERE ` + strings.Join(w.conds, " AND ")
+}
+
+func caseWhere(f store.CaseFilter) where {
+	var w where
+	if f.OwnerID != "" {
+		w.add(`c."userId" = ?`, f.OwnerID)
+	}
+	if len(f.Statuses) > 0 {
+		w.add(`c."status" IN ?`, f.Statuses)
+	}
+	if f.SchoolName != "" {
+		w.add(`c."schoolName" ILIKE ?`, "%"+escapeLike(f.SchoolName)+"%")
+	}
+	if f.InstallationService != "" {
+		w.add(`lower(c."installationService") = lower(?)`, f.InstallationService)
+	}
+	if f.CreatedFrom != nil {
+		w.add(`c."createdAt" >= ?`, *f.CreatedFrom)
+	}
+	if f.CreatedBefore != nil {
+		w.add(`c."createdAt" < ?`, *f.CreatedBefore)
+	}
+	if f.UpdatedFrom != nil {
+		w.add(`c."updatedAt" >= ?`, *f.UpdatedFrom)
+	}
+	if f.UpdatedBefore != nil {
+		w.add(`c."updatedAt" < ?`, *f.UpdatedBefore)
+	}
+	for _, word := range f.Words {
+		pattern := "%" + escapeLike(word) + "%"
+		ors := make([]string, len(caseSearchColumns))
+		args := make([]any, len(caseSearchColumns))
+		for i, col := range caseSearchColumns {
+			ors[i] = col + " ILIKE ?"
+			args[i] = pattern
+		}
+		w.add("("+strings.Join(ors, " OR ")+")", args...)
+	}
+	return w
+}
+
+func (s *cases) List(ctx context.Context, f store.CaseFilter, p pagination.Params) (pagination.Page[store.CaseSummary], error) {
+	var page pagination.Page[store.CaseSummary]
+	col, ok := caseSortColumns[f.Sort]
+	if !ok {
+		return page, fmt.Errorf("postgres: unknown case sort %q", f.Sort)
+	}
+	key := pagination.SortKey{Column: col, Kind: store.CaseSorts[f.Sort]}
+	db := s.db.WithContext(ctx)
+	w := caseWhere(f)
+
+	var total *int64
+	if p.WithTotal {
+		var n int64
+		if err := db.Raw(
+			`SELECT count(*) FROM "Case" c JOIN "User" u ON u."id" = c."userId"`+w.sql(), w.args...,
+		).Scan(&n).Error; err != nil {
+			return page, err
+		}
+		total = &n
+	}
+
+	after, afterArgs, err := p.After(f.Sort, key, `c."id"`, f.Desc)
+	if err != nil {
+		return page, err
+	}
+	if after != "" {
+		w.add(after, afterArgs...)
+	}
+
+	var rows []store.CaseSummary
+	sql := `
+		SELECT
+			c."id", c."customerName", c."projectDetails", c."contactPerson", c."schoolName",
+			c."emailAddress", c."phoneNumber", c."schoolAddress", c."installationService",
+			c."operationDaysPerYear", c."operationHoursPerDay",
+			c."status", c."createdAt", c."updatedAt",
+			u."name" AS user_name, u."email" AS user_email
+		FROM "Case" c
+		JOIN "User" u ON u."id" = c."userId"` +
+		w.sql() + pagination.OrderBy(key, `c."id"`, f.Desc) + ` LIMIT ?`
+	if err := db.Raw(sql, append(w.args, p.Limit+1)...).Scan(&rows).Error; err != nil {
+		return page, err
+	}
+	page = pagination.NewPage(rows, p, f.Sort, f.Desc, func(r store.CaseSummary) (any, string) {
+		return r.SortValue(f.Sort), r.ID
+	})
+	page.Total = total
+	return page, nil
+}
+
+// escapeLike escapes LIKE wildcards so user input matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// ---------- Get ----------
+
+func (s *cases) Get(ctx context.Context, id string) (*store.CaseDetail, error) {
+	db := s.db.WithContext(ctx)
+
+	var head store.CaseDetail
+	res := db.Raw(`
+		SELECT
+			c."id", c."customerName", c."projectDetails", c."contactPerson",
+			c."schoolName", c."emailAddress", c."phoneNumber", c."schoolAddress",
+			c."status", c."createdAt", c."updatedAt",
+			u."name" AS user_name, u."email" AS user_email
+		FROM "Case" c
+		JOIN "User" u ON u."id" = c."userId"
+		WHERE c."id" = ?
+		LIMIT 1`, id).Scan(&head)
+	if err := notFoundIfNone(res); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if head.Documents, err = caseDocuments(db, id); err != nil {
+		return nil, err
+	}
+	if head.Photos, err = casePhotos(db, id); err != nil {
+		return nil, err
+	}
+	return &head, nil
+}
+
+func (s *cases) Operation(ctx context.Context, id string) (*store.CaseOperation, error) {
+	var op store.CaseOperation
+	res := s.db.WithContext(ctx).Raw(`
+		SELECT c."operationDaysPerYear", c."operationHoursPerDay", p."value" AS "paybackValue"
+		FROM "Case" c
+		LEFT JOIN "PaybackSetting" p ON p."caseId" = c."id"
+		WHERE c."id" = ?`, id).Scan(&op)
+	if err := notFoundIfNone(res); err != nil {
+		return nil, err
+	}
+	return &op, nil
+}
+
+// ---------- Create / Update / Delete ----------
+
+func (s *cases) Create(ctx context.Context, actorID string, in store.CaseInput) (*store.CaseRecord, error) {
+	var row store.CaseRecord
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Raw(
+			`INSERT INTO "Case"
+			 ("id","userId","customerName","projectDetails","uploadToken","status","createdAt","updatedAt",
+			  "schoolName","contactPerson","emailAddress","phoneNumber","schoolAddress",
+			  "lightingPurpose","facilitiesUsedIn","installationService",
+			  "operationDaysPerYear","operationHoursPerDay")
+			 VALUES (?, ?, ?, ?, gen_random_uuid()::text, ?, now(), now(), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			 RETURNING *`,
+			cuid.New(), in.UserID, in.CustomerName, in.ProjectDetails, in.Status,
+			in.SchoolName, in.ContactPerson, in.EmailAddress, in.PhoneNumber, in.SchoolAddress,
+			in.LightingPurpose, in.FacilitiesUsedIn, in.InstallationService,
+			in.OperationDaysPerYear, in.OperationHoursPerDay,
+		).Scan(&row).Error; err != nil {
+			return err
+		}
+		return logActivity(tx, row.ID, actorID, "Case created")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+func (s *cases) Update(ctx context.Context, actorID, id string, set map[string]any) error {
+	if len(set) == 0 {
+		return nil
+	}
+	assign, cols, args := setClause(set)
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		sql := `UPDATE "Case" SET ` + assign + `, "updatedAt" = now() WHERE "id" = ?`
+		if err := notFoundIfNone(tx.Exec(sql, append(args, id)...)); err != nil {
+			return err
+		}
+		return logActivity(tx, id, actorID, "Case updated: "+strings.Join(cols, ", "))
+	})
+}
+
+// caseChildDeletes removes everything hanging off a case, leaf tables first.
+// Each relation on Case is ON DELETE CASCADE in the schema; doing it
+// explicitly keeps deletes working on databases whose FKs were created
+// without cascades, and fails on any RESTRICT reference we do not own.
+// Deletes of rows with stored objects return their "url" and its "kind".
+var caseChildDeletes = []string{
+	`DELETE FROM "OnSiteVisitPhotoTagPivot" WHERE "photoId" IN (
+		SELECT p."id" FROM "OnSiteVisitPhoto" p
+		  JOIN "OnSiteVisitRoom" r ON r."id" = p."roomId"
+		  JOIN "OnSiteVisit" v ON v."id" = r."onSiteVisitId"
+		 WHERE v."caseId" = @case)`,
+	`DELETE FROM "OnSiteVisitPhoto" WHERE "roomId" IN (
+		SELECT r."id" FROM "OnSiteVisitRoom" r
+		  JOIN "OnSiteVisit" v ON v."id" = r."onSiteVisitId"
+		 WHERE v."caseId" = @case)
+	 RETURNING "url", 'photo' AS "kind"`,
+	`DELETE FROM "OnSiteSuggestedProduct" WHERE "roomId" IN (
+		SELECT r."id" FROM "OnSiteVisitRoom" r
+		  JOIN "OnSiteVisit" v ON v."id" = r."onSiteVisitId"
+		 WHERE v."caseId" = @case)`,
+	`DELETE FROM "OnSiteExistingProduct" WHERE "roomId" IN (
+		SELECT r."id" FROM "OnSiteVisitRoom" r
+		  JOIN "OnSiteVisit" v ON v."id" = r."onSiteVisitId"
+		 WHERE v."caseId" = @case)`,
+	`DELETE FROM "OnSiteVisitRoom" WHERE "onSiteVisitId" IN (
+		SELECT "id" FROM "OnSiteVisit" WHERE "caseId" = @case)`,
+	`DELETE FROM "OnSiteVisit" WHERE "caseId" = @case`,
+	`DELETE FROM "InstallationDetailTag" WHERE "installationDetailId" IN (
+		SELECT "id" FROM "InstallationDetail" WHERE "caseId" = @case)`,
+	`DELETE FROM "InstallationDetail" WHERE "caseId" = @case`,
+	`DELETE FROM "CaseFixtureCount" WHERE "caseId" = @case`,
+	`DELETE FROM "Document" WHERE "caseId" = @case RETURNING "url", 'document' AS "kind"`,
+	`DELETE FROM "Photo" WHERE "caseId" = @case RETURNING "url", 'photo' AS "kind"`,
+	`DELETE FROM "Quote" WHERE "caseId" = @case`,
+	`DELETE FROM "QuoteCounter" WHERE "caseId" = @case`,
+	`DELETE FROM "PaybackSetting" WHERE "caseId" = @case`,
+	`DELETE FROM "CaseStatusChange" WHERE "caseId" = @case`,
+	`DELETE FROM "ActivityLog" WHERE "caseId" = @case`,
+}
+
+func (s *cases) Delete(ctx context.Context, actorID, id string) (store.DeletedFiles, error) {
+	var files store.DeletedFiles
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var cur struct {
+			CustomerName string `gorm:"column:customerName"`
+		}
+		if err := notFoundIfNone(tx.Raw(`SELECT "customerName" FROM "Case" WHERE "id" = ? FOR UPDATE`, id).Scan(&cur)); err != nil {
+			return err
+		}
+		for _, stmt := range caseChildDeletes {
+			var deleted []struct {
+				URL  string `gorm:"column:url"`
+				Kind string `gorm:"column:kind"`
+			}
+			if err := tx.Raw(stmt, map[string]any{"case": id}).Scan(&deleted).Error; err != nil {
+				return err
+			}
+			for _, d := range deleted {
+				if d.Kind == "document" {
+					files.Documents = append(files.Documents, d.URL)
+				} else {
+					files.Photos = append(files.Photos, d.URL)
+				}
+			}
+		}
+		if err := notFoundIfNone(tx.Exec(`DELETE FROM "Case" WHERE "id" = ?`, id)); err != nil {
+			return err
+		}
+		return tx.Exec(
+			`INSERT INTO "ActivityLog" ("id","caseId","action","createdAt","userId")
+			 VALUES (?, NULL, ?, now(), ?)`,
+			cuid.New(), store.CaseDeletedAction(id, cur.CustomerName), actorID,
+		).Error
+	})
+	if isForeignKeyViolation(err) {
+		return store.DeletedFiles{}, fmt.Errorf("%w: %v", store.ErrConflict, err)
+	}
+	if err != nil {
+		return store.DeletedFiles{}, err
+	}
+	return files, nil
+}
+
+// ---------- Status ----------
+
+func (s *cases) StatusHistory(ctx context.Context, id string) (string, []store.StatusChange, error) {
+	db := s.db.WithContext(ctx)
+
+	var cur struct {
+		Status string `gorm:"column:status"`
+	}
+	if err := notFoundIfNone(db.Raw(`SELECT "status" FROM "Case" WHERE "id" = ?`, id).Scan(&cur)); err != nil {
+		return "", nil, err
+	}
+
+	history := []store.StatusChange{}
+	if err := db.Raw(
+		`SELECT s."id", s."fromStatus", s."toStatus", s."note", s."userId", u."name" AS "userName", s."createdAt"
+		   FROM "CaseStatusChange" s
+		   LEFT JOIN "User" u ON u."id" = s."userId"
+		  WHERE s."caseId" = ?
+		  ORDER BY s."createdAt" DESC`,
+		id,
+	).Scan(&history).Error; err != nil {
+		return "", nil, err
+	}
+	return cur.Status, history, nil
+}
+
+func (s *cases) Transition(ctx context.Context, actorID, id, to string, note *string, allow func(from string) bool) (*store.StatusChange, error) {
+	var row store.StatusChange
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the row so concurrent transitions see each other's result.
+		var cur struct {
+			Status string `gorm:"column:status"`
+		}
+		if err := notFoundIfNone(tx.Raw(`SELECT "status" FROM "Case" WHERE "id" = ? FOR UPDATE`, id).Scan(&cur)); err != nil {
+			return err
+		}
+		if !allow(cur.Status) {
+			return &store.TransitionError{From: cur.Status, To: to}
+		}
+
+		if err := tx.Exec(
+			`UPDATE "Case" SET "status" = ?, "updatedAt" = now() WHERE "id" = ?`, to, id,
+		).Error; err != nil {
+			return err
+		}
+		if err := tx.Raw(
+			`INSERT INTO "CaseStatusChange" ("id","caseId","fromStatus","toStatus","note","userId","createdAt")
+			 VALUES (?, ?, ?, ?, ?, ?, now())
+			 RETURNING "id","fromStatus","toStatus","note","userId","createdAt"`,
+			cuid.New(), id, cur.Status, to, note, actorID,
+		).Scan(&row).Error; err != nil {
+			return err
+		}
+		return logActivity(tx, id, actorID, fmt.Sprintf("Status changed from %s to %s", cur.Status, to))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// ---------- Upload link ----------
+
+const uploadLinkCols = `"id","userId","customerName","schoolName","uploadToken","uploadTokenExpiresAt"`
+
+func (s *cases) UploadLink(ctx context.Context, caseID string) (*store.UploadLink, error) {
+	var link store.UploadLink
+	err := notFoundIfNone(s.db.WithContext(ctx).Raw(
+		`SELECT `+uploadLinkCols+` FROM "Case" WHERE "id" = ?`, caseID,
+	).Scan(&link))
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (s *cases) ByUploadToken(ctx context.Context, token string) (*store.UploadLink, error) {
+	var link store.UploadLink
+	err := notFoundIfNone(s.db.WithContext(ctx).Raw(
+		`SELECT `+uploadLinkCols+` FROM "Case"
+		  WHERE "uploadToken" = ?
+		    AND ("uploadTokenExpiresAt" IS NULL OR "uploadTokenExpiresAt" > now())`,
+		token,
+	).Scan(&link))
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (s *cases) RotateUploadToken(ctx context.Context, actorID, caseID string, expiresAt *time.Time) (*store.UploadLink, error) {
+	var link store.UploadLink
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := notFoundIfNone(tx.Raw(
+			`UPDATE "Case" SET "uploadToken" = gen_random_uuid()::text, "uploadTokenExpiresAt" = ?
+			  WHERE "id" = ?
+			 RETURNING `+uploadLinkCols,
+			expiresAt, caseID,
+		).Scan(&link)); err != nil {
+			return err
+		}
+		return logActivity(tx, caseID, actorID, "Upload link rotated")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (s *cases) ExpireUploadToken(ctx context.Context, actorID, caseID string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Keep an earlier expiry rather than extending it to now.
+		if err := notFoundIfNone(tx.Exec(
+			`UPDATE "Case" SET "uploadTokenExpiresAt" = LEAST(COALESCE("uploadTokenExpiresAt", now()), now())
+			  WHERE "id" = ?`,
+			caseID,
+		)); err != nil {
+			return err
+		}
+		return logActivity(tx, caseID, actorID, "Upload link disabled")
+	})
+}
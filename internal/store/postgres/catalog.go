@@ -0,0 +1,389 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/lucsky/cuid"
+	"github.com/rick/go-neon-api/internal/pagination"
+	"github.com/rick/go-neon-api/internal/store"
+	"gorm.io/gorm"
+)
+
+type catalog struct{ db *gorm.DB }
+
+// Both pickers page by name; "name" is NOT NULL on Product and LightFixtureType.
+var catalogNameSort = pagination.SortKey{Column: `"name"`, Kind: pagination.String}
+
+const (
+	productColumns     = `"id","name","wattage","category","description2","SKU","archivedAt"`
+	fixtureTypeColumns = `"id","name","SKU","wattage","imageUrl","description","archivedAt","unitCost","listPrice","laborHours"`
+)
+
+func (s *catalog) ListProducts(ctx context.Context, f store.ProductFilter, p pagination.Params) (pagination.Page[store.ProductRow], error) {
+	var w where
+	if f.Q != "" {
+		w.add(`("name" ILIKE ? OR "description2" ILIKE ?)`, "%"+f.Q+"%", "%"+f.Q+"%")
+	}
+	if f.Category != "" {
+		w.add(`"category" = ?`, f.Category)
+	}
+	if !f.IncludeArchived {
+		w.add(`"archivedAt" IS NULL`)
+	}
+	return listByName(s.db.WithContext(ctx), `"Product"`, productColumns, w, p,
+		func(r store.ProductRow) (any, string) { return r.Name, r.ID })
+}
+
+func (s *catalog) ListFixtureTypes(ctx context.Context, f store.FixtureTypeFilter, p pagination.Params) (pagination.Page[store.FixtureTypeRow], error) {
+	var w where
+	if f.Q != "" {
+		w.add(`("name" ILIKE ? OR "description" ILIKE ? OR "SKU" ILIKE ?)`, "%"+f.Q+"%", "%"+f.Q+"%", "%"+f.Q+"%")
+	}
+	if !f.IncludeArchived {
+		w.add(`"archivedAt" IS NULL`)
+	}
+	return listByName(s.db.WithContext(ctx), `"LightFixtureType"`, fixtureTypeColumns, w, p,
+		func(r store.FixtureTypeRow) (any, string) { return r.Name, r.ID })
+}
+
+func (s *catalog) CreateProduct(ctx context.Context, in store.ProductInput) (*store.ProductRow, error) {
+	var row store.ProductRow
+	res := s.db.WithContext(ctx).Raw(
+		`INSERT INTO "Product" ("id","name","wattage","category","description2","SKU")
+		 VALUES (?, ?, ?, ?, ?, ?)
+		 RETURNING `+productColumns,
+		cuid.New(), in.Name, in.Wattage, in.Category, in.Description, in.SKU,
+	).Scan(&row)
+	return catalogRow(&row, res)
+}
+
+func (s *catalog) UpdateProduct(ctx context.Context, id string, in store.ProductInput) (*store.ProductRow, error) {
+	var row store.ProductRow
+	res := s.db.WithContext(ctx).Raw(
+		`UPDATE "Product"
+		    SET "name" = ?, "wattage" = ?, "category" = ?, "description2" = ?, "SKU" = ?
+		  WHERE "id" = ?
+		  RETURNING `+productColumns,
+		in.Name, in.Wattage, in.Category, in.Description, in.SKU, id,
+	).Scan(&row)
+	return catalogRow(&row, res)
+}
+
+func (s *catalog) ArchiveProduct(ctx context.Context, id string, archived bool) (*store.ProductRow, error) {
+	var row store.ProductRow
+	return catalogRow(&row, s.archive(ctx, `"Product"`, productColumns, id, archived, &row))
+}
+
+func (s *catalog) DeleteProduct(ctx context.Context, id string) (bool, error) {
+	return s.deleteOrArchive(ctx, `"Product"`, id,
+		`SELECT EXISTS (SELECT 1 FROM "OnSiteExistingProduct" WHERE "productId" = @id) AS "inUse"`)
+}
+
+func (s *catalog) CreateFixtureType(ctx context.Context, in store.FixtureTypeInput) (*store.FixtureTypeRow, error) {
+	var row store.FixtureTypeRow
+	res := s.db.WithContext(ctx).Raw(
+		`INSERT INTO "LightFixtureType" ("id","name","wattage","description","SKU","imageUrl","unitCost","listPrice","laborHours")
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		 RETURNING `+fixtureTypeColumns,
+		cuid.New(), in.Name, in.Wattage, in.Description, in.SKU, in.ImageURL, in.UnitCost, in.ListPrice, in.LaborHours,
+	).Scan(&row)
+	return catalogRow(&row, res)
+}
+
+func (s *catalog) UpdateFixtureType(ctx context.Context, id string, in store.FixtureTypeInput) (*store.FixtureTypeRow, error) {
+	var row store.FixtureTypeRow
+	res := s.db.WithContext(ctx).Raw(
+		`UPDATE "LightFixtureType"
+		    SET "name" = ?, "wattage" = ?, "description" = ?, "SKU" = ?, "imageUrl" = ?,
+		        "unitCost" = ?, "listPrice" = ?, "laborHours" = ?
+		  WHERE "id" = ?
+		  RETURNING `+fixtureTypeColumns,
+		in.Name, in.Wattage, in.Description, in.SKU, in.ImageURL, in.UnitCost, in.ListPrice, in.LaborHours, id,
+	).Scan(&row)
+	return catalogRow(&row, res)
+}
+
+func (s *catalog) ArchiveFixtureType(ctx context.Context, id string, archived bool) (*store.FixtureTypeRow, error) {
+	var row store.FixtureTypeRow
+	return catalogRow(&row, s.archive(ctx, `"LightFixtureType"`, fixtureTypeColumns, id, archived, &row))
+}
+
+func (s *catalog) DeleteFixtureType(ctx context.Context, id string) (bool, error) {
+	// OnSiteSuggestedProduct."productId" has no foreign key, so the check
+	// cannot rely on the delete failing.
+	return s.deleteOrArchive(ctx, `"LightFixtureType"`, id,
+		`SELECT EXISTS (SELECT 1 FROM "OnSiteSuggestedProduct" WHERE "productId" = @id)
+		     OR EXISTS (SELECT 1 FROM "CaseFixtureCount" WHERE "fixtureTypeId" = @id) AS "inUse"`)
+}
+
+func (s *catalog) ExportProducts(ctx context.Context, includeArchived bool) ([]store.ProductRow, error) {
+	var rows []store.ProductRow
+	err := s.db.WithContext(ctx).Raw(
+		`SELECT `+productColumns+` FROM "Product" WHERE ? OR "archivedAt" IS NULL ORDER BY "name", "id"`,
+		includeArchived,
+	).Scan(&rows).Error
+	return rows, err
+}
+
+func (s *catalog) ExportFixtureTypes(ctx context.Context, includeArchived bool) ([]store.FixtureTypeRow, error) {
+	var rows []store.FixtureTypeRow
+	err := s.db.WithContext(ctx).Raw(
+		`SELECT `+fixtureTypeColumns+` FROM "LightFixtureType" WHERE ? OR "archivedAt" IS NULL ORDER BY "name", "id"`,
+		includeArchived,
+	).Scan(&rows).Error
+	return rows, err
+}
+
+// The imports lock the table in EXCLUSIVE mode: pickers keep reading, but
+// other catalog writes wait, so the plan is made against what gets written.
+
+func (s *catalog) ImportProducts(ctx context.Context, plan func([]store.ProductRow) ([]store.ProductRow, []store.ProductRow, bool)) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`LOCK TABLE "Product" IN EXCLUSIVE MODE`).Error; err != nil {
+			return err
+		}
+		var existing []store.ProductRow
+		if err := tx.Raw(`SELECT ` + productColumns + ` FROM "Product" ORDER BY "name", "id"`).Scan(&existing).Error; err != nil {
+			return err
+		}
+		creates, updates, commit := plan(existing)
+		if !commit {
+			return nil
+		}
+		for _, p := range creates {
+			if err := tx.Exec(
+				`INSERT INTO "Product" ("id","name","wattage","category","description2","SKU") VALUES (?, ?, ?, ?, ?, ?)`,
+				cuid.New(), p.Name, p.Wattage, p.Category, p.Description, p.SKU,
+			).Error; err != nil {
+				return err
+			}
+		}
+		for _, p := range updates {
+			if err := tx.Exec(
+				`UPDATE "Product" SET "name" = ?, "wattage" = ?, "category" = ?, "description2" = ?, "SKU" = ? WHERE "id" = ?`,
+				p.Name, p.Wattage, p.Category, p.Description, p.SKU, p.ID,
+			).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if isUniqueViolation(err) {
+		return store.ErrConflict
+	}
+	return err
+}
+
+func (s *catalog) ImportFixtureTypes(ctx context.Context, plan func([]store.FixtureTypeRow) ([]store.FixtureTypeRow, []store.FixtureTypeRow, bool)) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`LOCK TABLE "LightFixtureType" IN EXCLUSIVE MODE`).Error; err != nil {
+			return err
+		}
+		var existing []store.FixtureTypeRow
+		if err := tx.Raw(`SELECT ` + fixtureTypeColumns + ` FROM "LightFixtureType" ORDER BY "name", "id"`).Scan(&existing).Error; err != nil {
+			return err
+		}
+		creates, updates, commit := plan(existing)
+		if !commit {
+			return nil
+		}
+		for _, t := range creates {
+			if err := tx.Exec(
+				`INSERT INTO "LightFixtureType" ("id","name","wattage","description","SKU","imageUrl","unitCost","listPrice","laborHours")
+				 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				cuid.New(), t.Name, t.Wattage, t.Description, t.SKU, t.ImageURL, t.UnitCost, t.ListPrice, t.LaborHours,
+			).Error; err != nil {
+				return err
+			}
+		}
+		for _, t := range updates {
+			if err := tx.Exec(
+				`UPDATE "LightFixtureType"
+				    SET "name" = ?, "wattage" = ?, "description" = ?, "SKU" = ?, "imageUrl" = ?,
+				        "unitCost" = ?, "listPrice" = ?, "laborHours" = ?
+				  WHERE "id" = ?`,
+				t.Name, t.Wattage, t.Description, t.SKU, t.ImageURL, t.UnitCost, t.ListPrice, t.LaborHours, t.ID,
+			).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if isUniqueViolation(err) {
+		return store.ErrConflict
+	}
+	return err
+}
+
+func (s *catalog) Accessories(ctx context.Context) ([]store.Accessory, error) {
+	var rows []store.Accessory
+	err := s.db.WithContext(ctx).Raw(
+		`SELECT "id","kind","name","SKU","unitCost","listPrice","laborHours","updatedAt"
+		   FROM "Accessory"
+		  ORDER BY "kind"`,
+	).Scan(&rows).Error
+	return rows, err
+}
+
+func (s *catalog) UpdateAccessory(ctx context.Context, kind string, in store.AccessoryInput) (*store.Accessory, error) {
+	var row store.Accessory
+	res := s.db.WithContext(ctx).Raw(
+		`UPDATE "Accessory"
+		    SET "name" = ?, "SKU" = ?, "unitCost" = ?, "listPrice" = ?, "laborHours" = ?, "updatedAt" = now()
+		  WHERE "kind" = ?
+		  RETURNING "id","kind","name","SKU","unitCost","listPrice","laborHours","updatedAt"`,
+		in.Name, in.SKU, in.UnitCost, in.ListPrice, in.LaborHours, kind,
+	).Scan(&row)
+	if err := notFoundIfNone(res); err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// recommendationSelect reads rules with the names of the rows they refer to.
+const recommendationSelect = `SELECT r."id", r."productId", p."name" AS "productName", r."category",
+        r."minCeilingHeight", r."maxCeilingHeight", r."locationTagId", t."name" AS "locationTag",
+        r."fixtureTypeId", f."name" AS "fixtureTypeName", f."archivedAt" IS NOT NULL AS "fixtureTypeArchived",
+        r."priority", r."createdAt", r."updatedAt"
+   FROM "FixtureRecommendation" r
+   JOIN "LightFixtureType" f ON f."id" = r."fixtureTypeId"
+   LEFT JOIN "Product" p ON p."id" = r."productId"
+   LEFT JOIN "OnSiteLocationTag" t ON t."id" = r."locationTagId"`
+
+func (s *catalog) Recommendations(ctx context.Context) ([]store.FixtureRecommendation, error) {
+	rows := []store.FixtureRecommendation{}
+	err := s.db.WithContext(ctx).Raw(recommendationSelect + ` ORDER BY r."createdAt", r."id"`).Scan(&rows).Error
+	return rows, err
+}
+
+func (s *catalog) CreateRecommendation(ctx context.Context, in store.FixtureRecommendationInput) (*store.FixtureRecommendation, error) {
+	id := cuid.New()
+	err := s.db.WithContext(ctx).Exec(
+		`INSERT INTO "FixtureRecommendation"
+		 ("id","productId","category","minCeilingHeight","maxCeilingHeight","locationTagId","fixtureTypeId","priority")
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, in.ProductID, in.Category, in.MinCeilingHeight, in.MaxCeilingHeight, in.LocationTagID, in.FixtureTypeID, in.Priority,
+	).Error
+	if isForeignKeyViolation(err) {
+		return nil, store.ErrInvalidReference
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.recommendation(ctx, id)
+}
+
+func (s *catalog) UpdateRecommendation(ctx context.Context, id string, in store.FixtureRecommendationInput) (*store.FixtureRecommendation, error) {
+	res := s.db.WithContext(ctx).Exec(
+		`UPDATE "FixtureRecommendation"
+		    SET "productId" = ?, "category" = ?, "minCeilingHeight" = ?, "maxCeilingHeight" = ?,
+		        "locationTagId" = ?, "fixtureTypeId" = ?, "priority" = ?, "updatedAt" = now()
+		  WHERE "id" = ?`,
+		in.ProductID, in.Category, in.MinCeilingHeight, in.MaxCeilingHeight, in.LocationTagID, in.FixtureTypeID, in.Priority, id,
+	)
+	err := notFoundIfNone(res)
+	if isForeignKeyViolation(err) {
+		return nil, store.ErrInvalidReference
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.recommendation(ctx, id)
+}
+
+func (s *catalog) DeleteRecommendation(ctx context.Context, id string) error {
+	return notFoundIfNone(s.db.WithContext(ctx).Exec(`DELETE FROM "FixtureRecommendation" WHERE "id" = ?`, id))
+}
+
+func (s *catalog) recommendation(ctx context.Context, id string) (*store.FixtureRecommendation, error) {
+	var row store.FixtureRecommendation
+	if err := notFoundIfNone(s.db.WithContext(ctx).Raw(recommendationSelect+` WHERE r."id" = ?`, id).Scan(&row)); err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// catalogRow maps a write's result: no row is ErrNotFound and a duplicate
+// name or SKU ErrConflict.
+func catalogRow[T any](row *T, res *gorm.DB) (*T, error) {
+	err := notFoundIfNone(res)
+	if isUniqueViolation(err) {
+		return nil, store.ErrConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+// archive sets or clears "archivedAt", keeping the first archive time when
+// an archived row is archived again.
+func (s *catalog) archive(ctx context.Context, table, columns, id string, archived bool, dst any) *gorm.DB {
+	return s.db.WithContext(ctx).Raw(
+		`UPDATE `+table+`
+		    SET "archivedAt" = CASE WHEN ? THEN COALESCE("archivedAt", now()) END
+		  WHERE "id" = ?
+		  RETURNING `+columns,
+		archived, id,
+	).Scan(dst)
+}
+
+// deleteOrArchive deletes the row unless inUse, a query over @id, reports
+// references to it; then the row is archived instead. The row lock makes a
+// concurrent delete of the same row wait for this one.
+func (s *catalog) deleteOrArchive(ctx context.Context, table, id, inUse string) (archived bool, err error) {
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var locked []string
+		if err := notFoundIfNone(tx.Raw(`SELECT "id" FROM `+table+` WHERE "id" = ? FOR UPDATE`, id).Scan(&locked)); err != nil {
+			return err
+		}
+		var ref struct {
+			InUse bool `gorm:"column:inUse"`
+		}
+		if err := tx.Raw(inUse, map[string]any{"id": id}).Scan(&ref).Error; err != nil {
+			return err
+		}
+		archived = ref.InUse
+		if archived {
+			return tx.Exec(`UPDATE `+table+` SET "archivedAt" = COALESCE("archivedAt", now()) WHERE "id" = ?`, id).Error
+		}
+		err := tx.Exec(`DELETE FROM `+table+` WHERE "id" = ?`, id).Error
+		if isForeignKeyViolation(err) {
+			return store.ErrConflict // referenced from a table the check does not cover
+		}
+		return err
+	})
+	return archived, err
+}
+
+// listByName runs a picker query: optional count, then one keyset page
+// ordered by name.
+func listByName[T any](db *gorm.DB, table, columns string, w where, p pagination.Params, position func(T) (any, string)) (pagination.Page[T], error) {
+	var page pagination.Page[T]
+
+	var total *int64
+	if p.WithTotal {
+		var n int64
+		if err := db.Raw(`SELECT count(*) FROM `+table+w.sql(), w.args...).Scan(&n).Error; err != nil {
+			return page, err
+		}
+		total = &n
+	}
+
+	after, afterArgs, err := p.After("name", catalogNameSort, `"id"`, false)
+	if err != nil {
+		return page, err
+	}
+	if after != "" {
+		w.add(after, afterArgs...)
+	}
+	sql := `SELECT ` + columns + ` FROM ` + table + w.sql() +
+		pagination.OrderBy(catalogNameSort, `"id"`, false) + ` LIMIT ?`
+	var rows []T
+	if err := db.Raw(sql, append(w.args, p.Limit+1)...).Scan(&rows).Error; err != nil {
+		return page, err
+	}
+	page = pagination.NewPage(rows, p, "name", false, position)
+	page.Total = total
+	return page, nil
+}
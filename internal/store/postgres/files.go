@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rick/go-neon-api/internal/models"
+	"github.com/rick/go-neon-api/internal/store"
+	"gorm.io/gorm"
+)
+
+type files struct{ db *gorm.DB }
+
+func casePhotos(db *gorm.DB, caseID string) ([]store.PhotoRow, error) {
+	rows := []store.PhotoRow{}
+	if err := db.Raw(`
+		SELECT "id","url","comment","customName","uploadedViaLink","createdAt",
+		       "thumbnailUrl","takenAt","latitude","longitude"
+		FROM "Photo"
+		WHERE "caseId" = ?
+		ORDER BY "createdAt" DESC`, caseID).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("load photos: %w", err)
+	}
+	return rows, nil
+}
+
+func caseDocuments(db *gorm.DB, caseID string) ([]store.DocumentRow, error) {
+	rows := []store.DocumentRow{}
+	if err := db.Raw(`
+		SELECT "id","url","fileName","customName","comment","uploadedViaLink","createdAt"
+		FROM "Document"
+		WHERE "caseId" = ?
+		ORDER BY "createdAt" DESC`, caseID).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("load documents: %w", err)
+	}
+	return rows, nil
+}
+
+func (s *files) Photos(ctx context.Context, caseID string) ([]store.PhotoRow, error) {
+	return casePhotos(s.db.WithContext(ctx), caseID)
+}
+
+func (s *files) Documents(ctx context.Context, caseID string) ([]store.DocumentRow, error) {
+	return caseDocuments(s.db.WithContext(ctx), caseID)
+}
+
+func (s *files) AddPhoto(ctx context.Context, actorID string, p *models.Photo) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(p).Error; err != nil {
+			return err
+		}
+		action := "Photo added"
+		if p.UploadedViaLink {
+			action = "Photo uploaded by customer"
+		}
+		return logActivity(tx, p.CaseID, actorID, action)
+	})
+}
+
+func (s *files) AddDocument(ctx context.Context, actorID string, d *models.Document) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(d).Error; err != nil {
+			return err
+		}
+		action := "Document added: "
+		if d.UploadedViaLink {
+			action = "Document uploaded by customer: "
+		}
+		return logActivity(tx, d.CaseID, actorID, action+d.FileName)
+	})
+}
+
+func (s *files) UpdatePhoto(ctx context.Context, actorID, caseID, id string, set map[string]any) error {
+	return s.update(ctx, actorID, caseID, id, `"Photo"`, "Photo", set)
+}
+
+func (s *files) UpdateDocument(ctx context.Context, actorID, caseID, id string, set map[string]any) error {
+	return s.update(ctx, actorID, caseID, id, `"Document"`, "Document", set)
+}
+
+// update sets columns on one of the case's files and logs "<label> updated:
+// cols".
+func (s *files) update(ctx context.Context, actorID, caseID, id, table, label string, set map[string]any) error {
+	if len(set) == 0 {
+		return nil
+	}
+	assign, cols, args := setClause(set)
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		sql := `UPDATE ` + table + ` SET ` + assign + ` WHERE "id" = ? AND "caseId" = ?`
+		if err := notFoundIfNone(tx.Exec(sql, append(args, id, caseID)...)); err != nil {
+			return err
+		}
+		return logActivity(tx, caseID, actorID, label+" updated: "+strings.Join(cols, ", "))
+	})
+}
+
+func (s *files) DeletePhoto(ctx context.Context, actorID, caseID, id string) (string, error) {
+	var row struct {
+		URL string `gorm:"column:url"`
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Raw(
+			`DELETE FROM "Photo" WHERE "id" = ? AND "caseId" = ? RETURNING "url"`, id, caseID,
+		).Scan(&row)
+		if err := notFoundIfNone(res); err != nil {
+			return err
+		}
+		return logActivity(tx, caseID, actorID, "Photo deleted")
+	})
+	return row.URL, err
+}
+
+func (s *files) DeleteDocument(ctx context.Context, actorID, caseID, id string) (string, error) {
+	var row struct {
+		URL      string `gorm:"column:url"`
+		FileName string `gorm:"column:fileName"`
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Raw(
+			`DELETE FROM "Document" WHERE "id" = ? AND "caseId" = ? RETURNING "url","fileName"`, id, caseID,
+		).Scan(&row)
+		if err := notFoundIfNone(res); err != nil {
+			return err
+		}
+		return logActivity(tx, caseID, actorID, "Document deleted: "+row.FileName)
+	})
+	return row.URL, err
+}
@@ -0,0 +1,518 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lucsky/cuid"
+	"github.com/rick/go-neon-api/internal/store"
+	"gorm.io/gorm"
+)
+
+type onSite struct{ db *gorm.DB }
+
+func (s *onSite) Visit(ctx context.Context, caseID string) (*store.Visit, error) {
+	var v store.Visit
+	res := s.db.WithContext(ctx).Raw(
+		`SELECT "id","caseId","createdAt" FROM "OnSiteVisit" WHERE "caseId" = ? LIMIT 1`,
+		caseID,
+	).Scan(&v)
+	if err := notFoundIfNone(res); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (s *onSite) EnsureVisit(ctx context.Context, caseID string) (*store.Visit, error) {
+	// "caseId" is unique, so a concurrent create leaves exactly one row.
+	var v store.Visit
+	if err := s.db.WithContext(ctx).Raw(
+		`INSERT INTO "OnSiteVisit" ("id","caseId","createdAt")
+		 VALUES (?, ?, now())
+		 ON CONFLICT ("caseId") DO NOTHING
+		 RETURNING "id","caseId","createdAt"`,
+		cuid.New(), caseID,
+	).Scan(&v).Error; err != nil {
+		return nil, err
+	}
+	if v.ID != "" {
+		return &v, nil
+	}
+	return s.Visit(ctx, caseID)
+}
+
+func (s *onSite) Rooms(ctx context.Context, visitID string) ([]store.Room, error) {
+	db := s.db.WithContext(ctx)
+
+	rooms := []store.Room{}
+	if err := db.Raw(
+		`SELECT r."id", r."onSiteVisitId", r."location", r."locationTagId", t."name" AS "locationTag",
+		        r."lightingIssue", r."customerRequest", r."mountingKitQty", r."motionSensorQty",
+		        r."createdAt", r."ceilingHeight"
+		   FROM "OnSiteVisitRoom" r
+		   LEFT JOIN "OnSiteLocationTag" t ON t."id" = r."locationTagId"
+		  WHERE r."onSiteVisitId" = ?
+		  ORDER BY r."createdAt" DESC`,
+		visitID,
+	).Scan(&rooms).Error; err != nil {
+		return nil, fmt.Errorf("load rooms: %w", err)
+	}
+
+	if len(rooms) == 0 {
+		return rooms, nil
+	}
+
+	// Fixtures for every room of the visit in one query per table, so the
+	// round trips stay constant however many rooms were surveyed.
+	byID := make(map[string]*store.Room, len(rooms))
+	for i := range rooms {
+		rooms[i].Existing = []store.ExistingLine{}
+		rooms[i].Suggested = []store.SuggestedLine{}
+		rooms[i].Photos = []store.RoomPhoto{}
+		byID[rooms[i].ID] = &rooms[i]
+	}
+
+	var existing []store.ExistingLine
+	if err := db.Raw(
+		`SELECT e."id", e."roomId", e."productId", p."name" AS "productName", p."wattage", p."category",
+		        e."quantity", e."bypassBallast"
+		   FROM "OnSiteExistingProduct" e
+		   JOIN "OnSiteVisitRoom" r ON r."id" = e."roomId"
+		   JOIN "Product" p ON p."id" = e."productId"
+		  WHERE r."onSiteVisitId" = ?
+		  ORDER BY e."id"`,
+		visitID,
+	).Scan(&existing).Error; err != nil {
+		return nil, fmt.Errorf("load existing: %w", err)
+	}
+	for _, e := range existing {
+		if r, ok := byID[e.RoomID]; ok {
+			r.Existing = append(r.Existing, e)
+		}
+	}
+
+	var suggested []store.SuggestedLine
+	if err := db.Raw(
+		`SELECT s."id",
+		        s."roomId",
+		        s."productId",                         -- this is LightFixtureType.id in your DB
+		        l."name"        AS "typeName",
+		        l."SKU",
+		        l."imageUrl",
+		        l."wattage",
+		        s."quantity",
+		        l."unitCost",
+		        l."listPrice",
+		        l."laborHours"
+		   FROM "OnSiteSuggestedProduct" s
+		   JOIN "OnSiteVisitRoom" r ON r."id" = s."roomId"
+		   JOIN "LightFixtureType" l ON l."id" = s."productId"
+		  WHERE r."onSiteVisitId" = ?
+		  ORDER BY s."id"`,
+		visitID,
+	).Scan(&suggested).Error; err != nil {
+		return nil, fmt.Errorf("load suggested: %w", err)
+	}
+	for _, sg := range suggested {
+		if r, ok := byID[sg.RoomID]; ok {
+			r.Suggested = append(r.Suggested, sg)
+		}
+	}
+
+	photos, err := loadPhotos(db, `r."onSiteVisitId" = ?`, visitID)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range photos {
+		if r, ok := byID[p.RoomID]; ok {
+			r.Photos = append(r.Photos, p)
+		}
+	}
+	return rooms, nil
+}
+
+// ---------- Rooms ----------
+
+func (s *onSite) RoomVisit(ctx context.Context, roomID string) (string, error) {
+	var row struct {
+		VisitID string `gorm:"column:onSiteVisitId"`
+	}
+	res := s.db.WithContext(ctx).Raw(
+		`SELECT "onSiteVisitId" FROM "OnSiteVisitRoom" WHERE "id" = ?`, roomID,
+	).Scan(&row)
+	if err := notFoundIfNone(res); err != nil {
+		return "", err
+	}
+	return row.VisitID, nil
+}
+
+func (s *onSite) CreateRoom(ctx context.Context, visitID string, in store.RoomInput) (*store.Room, error) {
+	var row store.Room
+	if err := s.db.WithContext(ctx).Raw(
+		`WITH r AS (
+		   INSERT INTO "OnSiteVisitRoom"
+		   ("id","onSiteVisitId","location","locationTagId","lightingIssue","customerRequest",
+		    "mountingKitQty","motionSensorQty","createdAt","ceilingHeight")
+		   VALUES (?, ?, ?, ?, ?, ?, ?, ?, now(), ?)
+		   RETURNING *)
+		 SELECT r."id", r."onSiteVisitId", r."location", r."locationTagId", t."name" AS "locationTag",
+		        r."lightingIssue", r."customerRequest", r."mountingKitQty", r."motionSensorQty",
+		        r."createdAt", r."ceilingHeight"
+		   FROM r
+		   LEFT JOIN "OnSiteLocationTag" t ON t."id" = r."locationTagId"`,
+		cuid.New(), visitID, in.Location, in.LocationTagID, in.LightingIssue, in.CustomerRequest,
+		in.MountingKitQty, in.MotionSensorQty, in.CeilingHeight,
+	).Scan(&row).Error; err != nil {
+		if isForeignKeyViolation(err) {
+			return nil, store.ErrNotFound
+		}
+		return nil, err
+	}
+	row.Existing = []store.ExistingLine{}
+	row.Suggested = []store.SuggestedLine{}
+	row.Photos = []store.RoomPhoto{}
+	return &row, nil
+}
+
+func (s *onSite) UpdateRoom(ctx context.Context, id string, set map[string]any) error {
+	err := s.update(ctx, `"OnSiteVisitRoom"`, id, set)
+	if isForeignKeyViolation(err) {
+		return store.ErrNotFound
+	}
+	return err
+}
+
+func (s *onSite) DeleteRoom(ctx context.Context, id string) ([]string, error) {
+	var urls []string
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(
+			`DELETE FROM "OnSiteVisitPhotoTagPivot"
+			  WHERE "photoId" IN (SELECT "id" FROM "OnSiteVisitPhoto" WHERE "roomId" = ?)`, id,
+		).Error; err != nil {
+			return fmt.Errorf("delete photo tags: %w", err)
+		}
+		if err := tx.Raw(`DELETE FROM "OnSiteVisitPhoto" WHERE "roomId" = ? RETURNING "url"`, id).Scan(&urls).Error; err != nil {
+			return fmt.Errorf("delete photos: %w", err)
+		}
+		for _, table := range []string{`"OnSiteSuggestedProduct"`, `"OnSiteExistingProduct"`} {
+			if err := tx.Exec(`DELETE FROM `+table+` WHERE "roomId" = ?`, id).Error; err != nil {
+				return fmt.Errorf("delete from %s: %w", table, err)
+			}
+		}
+		return notFoundIfNone(tx.Exec(`DELETE FROM "OnSiteVisitRoom" WHERE "id" = ?`, id))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
+
+// ---------- Fixtures ----------
+
+func (s *onSite) AddExisting(ctx context.Context, roomID, productID string, quantity int, bypassBallast bool) (string, error) {
+	var row struct {
+		ID string `gorm:"column:id"`
+	}
+	err := s.db.WithContext(ctx).Raw(
+		`INSERT INTO "OnSiteExistingProduct" ("id","roomId","productId","quantity","bypassBallast")
+		 VALUES (gen_random_uuid()::text, ?, ?, ?, ?)
+		 RETURNING "id"`,
+		roomID, productID, quantity, bypassBallast,
+	).Scan(&row).Error
+	return row.ID, err
+}
+
+func (s *onSite) UpdateExisting(ctx context.Context, id string, set map[string]any) error {
+	return s.update(ctx, `"OnSiteExistingProduct"`, id, set)
+}
+
+func (s *onSite) DeleteExisting(ctx context.Context, id string) error {
+	return notFoundIfNone(s.db.WithContext(ctx).Exec(`DELETE FROM "OnSiteExistingProduct" WHERE "id" = ?`, id))
+}
+
+func (s *onSite) AddSuggested(ctx context.Context, roomID, fixtureTypeID string, quantity int) (string, error) {
+	var row struct {
+		ID string `gorm:"column:id"`
+	}
+	err := s.db.WithContext(ctx).Raw(
+		`INSERT INTO "OnSiteSuggestedProduct" ("id","roomId","productId","quantity")
+		 VALUES (gen_random_uuid()::text, ?, ?, ?)
+		 RETURNING "id"`,
+		roomID, fixtureTypeID, quantity,
+	).Scan(&row).Error
+	return row.ID, err
+}
+
+func (s *onSite) AddSuggestedMissing(ctx context.Context, lines []store.NewSuggested) ([]store.NewSuggested, error) {
+	added := []store.NewSuggested{}
+	if len(lines) == 0 {
+		return added, nil
+	}
+	roomIDs := make([]string, 0, len(lines))
+	for _, l := range lines {
+		roomIDs = append(roomIDs, l.RoomID)
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock in id order so two calls over overlapping rooms cannot
+		// deadlock.
+		var locked []string
+		if err := tx.Raw(
+			`SELECT "id" FROM "OnSiteVisitRoom" WHERE "id" IN ? ORDER BY "id" FOR UPDATE`, roomIDs,
+		).Scan(&locked).Error; err != nil {
+			return fmt.Errorf("lock rooms: %w", err)
+		}
+		var present []struct {
+			RoomID    string `gorm:"column:roomId"`
+			ProductID string `gorm:"column:productId"`
+		}
+		if err := tx.Raw(
+			`SELECT "roomId","productId" FROM "OnSiteSuggestedProduct" WHERE "roomId" IN ?`, locked,
+		).Scan(&present).Error; err != nil {
+			return fmt.Errorf("load suggested: %w", err)
+		}
+		type key struct{ room, fixtureType string }
+		skip := map[key]bool{}
+		for _, p := range present {
+			skip[key{p.RoomID, p.ProductID}] = true
+		}
+		exists := map[string]bool{}
+		for _, id := range locked {
+			exists[id] = true
+		}
+		for _, l := range lines {
+			k := key{l.RoomID, l.FixtureTypeID}
+			if !exists[l.RoomID] || skip[k] {
+				continue
+			}
+			var row struct {
+				ID string `gorm:"column:id"`
+			}
+			if err := tx.Raw(
+				`INSERT INTO "OnSiteSuggestedProduct" ("id","roomId","productId","quantity")
+				 VALUES (gen_random_uuid()::text, ?, ?, ?)
+				 RETURNING "id"`,
+				l.RoomID, l.FixtureTypeID, l.Quantity,
+			).Scan(&row).Error; err != nil {
+				return fmt.Errorf("add suggested: %w", err)
+			}
+			l.ID = row.ID
+			added = append(added, l)
+			skip[k] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+func (s *onSite) UpdateSuggested(ctx context.Context, id string, set map[string]any) error {
+	return s.update(ctx, `"OnSiteSuggestedProduct"`, id, set)
+}
+
+func (s *onSite) DeleteSuggested(ctx context.Context, id string) error {
+	return notFoundIfNone(s.db.WithContext(ctx).Exec(`DELETE FROM "OnSiteSuggestedProduct" WHERE "id" = ?`, id))
+}
+
+// ---------- Photos ----------
+
+// loadPhotos returns the photos matching cond (over p = photo, r = room),
+// oldest first, with their tags attached.
+func loadPhotos(db *gorm.DB, cond string, args ...any) ([]store.RoomPhoto, error) {
+	photos := []store.RoomPhoto{}
+	if err := db.Raw(
+		`SELECT p."id", p."roomId", p."url", p."comment", p."createdAt",
+		        p."thumbnailUrl", p."takenAt", p."latitude", p."longitude"
+		   FROM "OnSiteVisitPhoto" p
+		   JOIN "OnSiteVisitRoom" r ON r."id" = p."roomId"
+		  WHERE `+cond+`
+		  ORDER BY p."createdAt", p."id"`,
+		args...,
+	).Scan(&photos).Error; err != nil {
+		return nil, fmt.Errorf("load photos: %w", err)
+	}
+	if len(photos) == 0 {
+		return photos, nil
+	}
+
+	ids := make([]string, len(photos))
+	byID := make(map[string]*store.RoomPhoto, len(photos))
+	for i := range photos {
+		photos[i].Tags = []store.Tag{}
+		ids[i] = photos[i].ID
+		byID[photos[i].ID] = &photos[i]
+	}
+
+	var tags []struct {
+		PhotoID string `gorm:"column:photoId"`
+		store.Tag
+	}
+	if err := db.Raw(
+		`SELECT x."photoId", t."id", t."name", t."createdAt"
+		   FROM "OnSiteVisitPhotoTagPivot" x
+		   JOIN "OnSitePhotoTag" t ON t."id" = x."tagId"
+		  WHERE x."photoId" IN ?
+		  ORDER BY t."name"`,
+		ids,
+	).Scan(&tags).Error; err != nil {
+		return nil, fmt.Errorf("load photo tags: %w", err)
+	}
+	for _, t := range tags {
+		if p, ok := byID[t.PhotoID]; ok {
+			p.Tags = append(p.Tags, t.Tag)
+		}
+	}
+	return photos, nil
+}
+
+func (s *onSite) RoomPhotos(ctx context.Context, roomID string) ([]store.RoomPhoto, error) {
+	return loadPhotos(s.db.WithContext(ctx), `p."roomId" = ?`, roomID)
+}
+
+func (s *onSite) AddRoomPhoto(ctx context.Context, roomID, url, comment string, meta store.PhotoMeta) (*store.RoomPhoto, error) {
+	var row store.RoomPhoto
+	if err := s.db.WithContext(ctx).Raw(
+		`INSERT INTO "OnSiteVisitPhoto"
+		 ("id","roomId","url","comment","createdAt","thumbnailUrl","takenAt","latitude","longitude")
+		 VALUES (?, ?, ?, ?, now(), ?, ?, ?, ?)
+		 RETURNING "id","roomId","url","comment","createdAt","thumbnailUrl","takenAt","latitude","longitude"`,
+		cuid.New(), roomID, url, comment, meta.ThumbnailURL, meta.TakenAt, meta.Latitude, meta.Longitude,
+	).Scan(&row).Error; err != nil {
+		if isForeignKeyViolation(err) {
+			return nil, store.ErrNotFound
+		}
+		return nil, err
+	}
+	row.Tags = []store.Tag{}
+	return &row, nil
+}
+
+func (s *onSite) SetRoomPhotoComment(ctx context.Context, id, comment string) error {
+	return s.update(ctx, `"OnSiteVisitPhoto"`, id, map[string]any{"comment": comment})
+}
+
+func (s *onSite) DeleteRoomPhoto(ctx context.Context, id string) (string, error) {
+	var row struct {
+		URL string `gorm:"column:url"`
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`DELETE FROM "OnSiteVisitPhotoTagPivot" WHERE "photoId" = ?`, id).Error; err != nil {
+			return err
+		}
+		return notFoundIfNone(tx.Raw(`DELETE FROM "OnSiteVisitPhoto" WHERE "id" = ? RETURNING "url"`, id).Scan(&row))
+	})
+	return row.URL, err
+}
+
+func (s *onSite) TagRoomPhoto(ctx context.Context, photoID, tagID string) error {
+	err := s.db.WithContext(ctx).Exec(
+		`INSERT INTO "OnSiteVisitPhotoTagPivot" ("id","photoId","tagId")
+		 VALUES (?, ?, ?)
+		 ON CONFLICT ("photoId","tagId") DO NOTHING`,
+		cuid.New(), photoID, tagID,
+	).Error
+	if isForeignKeyViolation(err) {
+		return store.ErrNotFound
+	}
+	return err
+}
+
+func (s *onSite) UntagRoomPhoto(ctx context.Context, photoID, tagID string) error {
+	return notFoundIfNone(s.db.WithContext(ctx).Exec(
+		`DELETE FROM "OnSiteVisitPhotoTagPivot" WHERE "photoId" = ? AND "tagId" = ?`,
+		photoID, tagID,
+	))
+}
+
+// ---------- Tag vocabularies ----------
+
+func (s *onSite) PhotoTags(ctx context.Context) ([]store.Tag, error) {
+	return s.listTags(ctx, `"OnSitePhotoTag"`)
+}
+
+func (s *onSite) CreatePhotoTag(ctx context.Context, name string) (*store.Tag, error) {
+	return s.createTag(ctx, `"OnSitePhotoTag"`, name)
+}
+
+func (s *onSite) RenamePhotoTag(ctx context.Context, id, name string) error {
+	return s.renameTag(ctx, `"OnSitePhotoTag"`, id, name)
+}
+
+func (s *onSite) DeletePhotoTag(ctx context.Context, id string) error {
+	// The pivot's tagId FK is ON DELETE RESTRICT.
+	err := notFoundIfNone(s.db.WithContext(ctx).Exec(`DELETE FROM "OnSitePhotoTag" WHERE "id" = ?`, id))
+	if isForeignKeyViolation(err) {
+		return store.ErrConflict
+	}
+	return err
+}
+
+func (s *onSite) LocationTags(ctx context.Context) ([]store.Tag, error) {
+	return s.listTags(ctx, `"OnSiteLocationTag"`)
+}
+
+func (s *onSite) CreateLocationTag(ctx context.Context, name string) (*store.Tag, error) {
+	return s.createTag(ctx, `"OnSiteLocationTag"`, name)
+}
+
+func (s *onSite) RenameLocationTag(ctx context.Context, id, name string) error {
+	return s.renameTag(ctx, `"OnSiteLocationTag"`, id, name)
+}
+
+func (s *onSite) DeleteLocationTag(ctx context.Context, id string) error {
+	// The schema's FK is ON DELETE SET NULL; untag explicitly so the delete
+	// also works where it was created without that action.
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(
+			`UPDATE "OnSiteVisitRoom" SET "locationTagId" = NULL WHERE "locationTagId" = ?`, id,
+		).Error; err != nil {
+			return err
+		}
+		return notFoundIfNone(tx.Exec(`DELETE FROM "OnSiteLocationTag" WHERE "id" = ?`, id))
+	})
+}
+
+func (s *onSite) listTags(ctx context.Context, table string) ([]store.Tag, error) {
+	tags := []store.Tag{}
+	err := s.db.WithContext(ctx).Raw(
+		`SELECT "id","name","createdAt" FROM ` + table + ` ORDER BY "name"`,
+	).Scan(&tags).Error
+	return tags, err
+}
+
+func (s *onSite) createTag(ctx context.Context, table, name string) (*store.Tag, error) {
+	var row store.Tag
+	err := s.db.WithContext(ctx).Raw(
+		`INSERT INTO `+table+` ("id","name","createdAt")
+		 VALUES (?, ?, now())
+		 RETURNING "id","name","createdAt"`,
+		cuid.New(), name,
+	).Scan(&row).Error
+	if isUniqueViolation(err) {
+		return nil, store.ErrConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+func (s *onSite) renameTag(ctx context.Context, table, id, name string) error {
+	err := s.update(ctx, table, id, map[string]any{"name": name})
+	if isUniqueViolation(err) {
+		return store.ErrConflict
+	}
+	return err
+}
+
+// update runs UPDATE table SET ... WHERE "id" = ?.
+func (s *onSite) update(ctx context.Context, table, id string, set map[string]any) error {
+	if len(set) == 0 {
+		return nil
+	}
+	assign, _, args := setClause(set)
+	return notFoundIfNone(s.db.WithContext(ctx).Exec(
+		`UPDATE `+table+` SET `+assign+` WHERE "id" = ?`, append(args, id)...,
+	))
+}
@@ -0,0 +1,171 @@
+package postgres_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/lucsky/cuid"
+	"github.com/rick/go-neon-api/internal/casestatus"
+	"github.com/rick/go-neon-api/internal/db"
+	"github.com/rick/go-neon-api/internal/migrate"
+	"github.com/rick/go-neon-api/internal/store"
+	"github.com/rick/go-neon-api/internal/store/postgres"
+	gormpg "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// connectTestDB connects to TEST_DATABASE_URL, a scratch database the
+// migrations are applied to. Tests that need to commit clean up after
+// themselves; the rest use openTestDB.
+func connectTestDB(tb testing.TB) *gorm.DB {
+	tb.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		tb.Skip("TEST_DATABASE_URL not set")
+	}
+	gdb, err := gorm.Open(gormpg.Open(dsn), &gorm.Config{
+		NamingStrategy: db.NewPrismaNamer(),
+		Logger:         logger.Discard,
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if _, err := migrate.Up(gdb); err != nil {
+		tb.Fatal(err)
+	}
+	return gdb
+}
+
+// openTestDB returns a transaction on the test database rolled back when tb
+// ends, plus a pointer to the number of statements run while *counting.
+func openTestDB(tb testing.TB) (tx *gorm.DB, statements *int, counting *bool) {
+	tb.Helper()
+	gdb := connectTestDB(tb)
+	statements, counting = new(int), new(bool)
+	count := func(*gorm.DB) {
+		if *counting {
+			*statements++
+		}
+	}
+	cb := gdb.Callback()
+	for _, err := range []error{
+		cb.Query().After("gorm:query").Register("test:count", count),
+		cb.Row().After("gorm:row").Register("test:count", count),
+		cb.Raw().After("gorm:raw").Register("test:count", count),
+	} {
+		if err != nil {
+			tb.Fatal(err)
+		}
+	}
+
+	tx = gdb.Begin()
+	if tx.Error != nil {
+		tb.Fatal(tx.Error)
+	}
+	tb.Cleanup(func() { tx.Rollback() })
+	return tx, statements, counting
+}
+
+// seedVisit creates a case whose visit has the given number of rooms, each
+// with an existing line, a suggested line and a tagged photo.
+func seedVisit(tb testing.TB, tx *gorm.DB, rooms int) string {
+	tb.Helper()
+	ctx := context.Background()
+	s := postgres.New(tx)
+
+	userID := cuid.New()
+	if err := tx.Exec(`INSERT INTO "User" ("id","email") VALUES (?, ?)`, userID, userID+"@example.com").Error; err != nil {
+		tb.Fatal(err)
+	}
+	c, err := s.Cases.Create(ctx, userID, store.CaseInput{UserID: userID, Status: casestatus.New, CustomerName: "Lincoln"})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	visit, err := s.OnSite.EnsureVisit(ctx, c.ID)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	product, err := s.Catalog.CreateProduct(ctx, store.ProductInput{Name: "T8 " + cuid.New(), Wattage: 32})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	watts := 15.0
+	fixture, err := s.Catalog.CreateFixtureType(ctx, store.FixtureTypeInput{Name: "LED tube " + cuid.New(), Wattage: &watts})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tag, err := s.OnSite.CreatePhotoTag(ctx, "Ballast "+cuid.New())
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	for i := 0; i < rooms; i++ {
+		room, err := s.OnSite.CreateRoom(ctx, visit.ID, store.RoomInput{Location: fmt.Sprintf("Room %d", i)})
+		if err != nil {
+			tb.Fatal(err)
+		}
+		if _, err := s.OnSite.AddExisting(ctx, room.ID, product.ID, 4, false); err != nil {
+			tb.Fatal(err)
+		}
+		if _, err := s.OnSite.AddSuggested(ctx, room.ID, fixture.ID, 4); err != nil {
+			tb.Fatal(err)
+		}
+		photo, err := s.OnSite.AddRoomPhoto(ctx, room.ID, "https://example.com/room.jpg", "", store.PhotoMeta{})
+		if err != nil {
+			tb.Fatal(err)
+		}
+		if err := s.OnSite.TagRoomPhoto(ctx, photo.ID, tag.ID); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return visit.ID
+}
+
+// TestRoomsStatementCount checks that loading a visit runs the same number
+// of statements however many rooms it has.
+func TestRoomsStatementCount(t *testing.T) {
+	tx, statements, counting := openTestDB(t)
+	s := postgres.New(tx)
+
+	load := func(rooms int) int {
+		visitID := seedVisit(t, tx, rooms)
+		*statements, *counting = 0, true
+		got, err := s.OnSite.Rooms(context.Background(), visitID)
+		*counting = false
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != rooms {
+			t.Fatalf("loaded %d rooms, want %d", len(got), rooms)
+		}
+		for _, r := range got {
+			if len(r.Existing) != 1 || len(r.Suggested) != 1 || len(r.Photos) != 1 {
+				t.Fatalf("room %s has %d existing, %d suggested and %d photos, want 1 each",
+					r.ID, len(r.Existing), len(r.Suggested), len(r.Photos))
+			}
+		}
+		return *statements
+	}
+
+	one, many := load(1), load(80)
+	if one == 0 || one != many {
+		t.Errorf("1 room took %d statements, 80 rooms took %d", one, many)
+	}
+}
+
+func BenchmarkRooms80(b *testing.B) {
+	tx, _, _ := openTestDB(b)
+	s := postgres.New(tx)
+	visitID := seedVisit(b, tx, 80)
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.OnSite.Rooms(ctx, visitID); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Package postgres implements the store interfaces with raw SQL against the
+// Prisma schema ("Case"."customerName", ...).
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lucsky/cuid"
+	"github.com/rick/go-neon-api/internal/store"
+	"gorm.io/gorm"
+)
+
+// New returns a Store whose aggregates share db.
+func New(db *gorm.DB) *store.Store {
+	return &store.Store{
+		Users:   &users{db: db},
+		Cases:   &cases{db: db},
+		OnSite:  &onSite{db: db},
+		Catalog: &catalog{db: db},
+		Files:   &files{db: db},
+		Quotes:  &quotes{db: db},
+	}
+}
+
+// logActivity appends an ActivityLog row for the case. Pass the transaction the
+// change ran in so the log entry commits or rolls back with it.
+func logActivity(tx *gorm.DB, caseID, userID, action string) error {
+	return tx.Exec(
+		`INSERT INTO "ActivityLog" ("id","caseId","action","createdAt","userId")
+		 VALUES (?, ?, ?, now(), ?)`,
+		cuid.New(), caseID, action, userID,
+	).Error
+}
+
+// isForeignKeyViolation reports whether err is Postgres' foreign_key_violation.
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
+
+// isUniqueViolation reports whether err is Postgres' unique_violation.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// notFoundIfNone maps a zero-row result to store.ErrNotFound.
+func notFoundIfNone(res *gorm.DB) error {
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+// setClause renders `"a" = ?, "b" = ?` for set in column order and returns the
+// sorted column names and matching args. Column names come from the
+// handlers' allow-lists, never from request keys directly.
+func setClause(set map[string]any) (string, []string, []any) {
+	cols := make([]string, 0, len(set))
+	for k := range set {
+		cols = append(cols, k)
+	}
+	sort.Strings(cols)
+	assign := make([]string, len(cols))
+	args := make([]any, len(cols))
+	for i, k := range cols {
+		assign[i] = fmt.Sprintf(`"%s" = ?`, k)
+		args[i] = set[k]
+	}
+	return strings.Join(assign, ", "), cols, args
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lucsky/cuid"
+	"github.com/rick/go-neon-api/internal/models"
+	"github.com/rick/go-neon-api/internal/store"
+	"gorm.io/gorm"
+)
+
+type quotes struct{ db *gorm.DB }
+
+const quoteColumns = `"id","number","revision","note","totalCost","totalPrice","laborHours","createdById","createdAt"`
+
+func (s *quotes) List(ctx context.Context, caseID string) ([]store.QuoteRow, error) {
+	rows := []store.QuoteRow{}
+	if err := s.db.WithContext(ctx).Raw(`
+		SELECT `+quoteColumns+`
+		FROM "Quote"
+		WHERE "caseId" = ?
+		ORDER BY "revision" DESC`, caseID).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("load quotes: %w", err)
+	}
+	return rows, nil
+}
+
+func (s *quotes) Get(ctx context.Context, caseID string, revision int) (*models.Quote, error) {
+	var row struct {
+		store.QuoteRow
+		Snapshot string `gorm:"column:snapshot"`
+	}
+	res := s.db.WithContext(ctx).Raw(`
+		SELECT `+quoteColumns+`, "snapshot"::text AS "snapshot"
+		FROM "Quote"
+		WHERE "caseId" = ? AND "revision" = ?`, caseID, revision).Scan(&row)
+	if err := notFoundIfNone(res); err != nil {
+		return nil, err
+	}
+	r := row.QuoteRow
+	return &models.Quote{
+		BaseStringID: models.BaseStringID{ID: r.ID},
+		CaseID:       caseID, Number: r.Number, Revision: r.Revision, Note: r.Note,
+		TotalCost: r.TotalCost, TotalPrice: r.TotalPrice, LaborHours: r.LaborHours,
+		Snapshot: []byte(row.Snapshot), CreatedByID: r.CreatedByID, CreatedAt: r.CreatedAt,
+	}, nil
+}
+
+func (s *quotes) Create(ctx context.Context, actorID string, q *models.Quote) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The upsert locks the case's counter row until commit, so concurrent
+		// creates for one case queue here and each gets its own revision.
+		var counter struct {
+			Count int `gorm:"column:count"`
+		}
+		if err := tx.Raw(`
+			INSERT INTO "QuoteCounter" ("id","caseId","count","updatedAt")
+			VALUES (?, ?, 1, now())
+			ON CONFLICT ("caseId") DO UPDATE
+			SET "count" = "QuoteCounter"."count" + 1, "updatedAt" = now()
+			RETURNING "count"`, cuid.New(), q.CaseID).Scan(&counter).Error; err != nil {
+			return err
+		}
+
+		q.ID = cuid.New()
+		q.Revision = counter.Count
+		q.Number = store.QuoteNumber(q.CaseID, q.Revision)
+		q.CreatedByID = actorID
+		var row struct {
+			CreatedAt time.Time `gorm:"column:createdAt"`
+		}
+		err := tx.Raw(`
+			INSERT INTO "Quote" ("id","caseId","number","revision","note","totalCost","totalPrice",
+			                     "laborHours","snapshot","createdById","createdAt")
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?::jsonb, ?, now())
+			RETURNING "createdAt"`,
+			q.ID, q.CaseID, q.Number, q.Revision, q.Note, q.TotalCost, q.TotalPrice,
+			q.LaborHours, string(q.Snapshot), actorID,
+		).Scan(&row).Error
+		if isForeignKeyViolation(err) {
+			return store.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		q.CreatedAt = row.CreatedAt
+		return logActivity(tx, q.CaseID, actorID, "Quote created: "+q.Number)
+	})
+}
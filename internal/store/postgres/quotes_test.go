@@ -0,0 +1,90 @@
+package postgres_test
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/lucsky/cuid"
+	"github.com/rick/go-neon-api/internal/casestatus"
+	"github.com/rick/go-neon-api/internal/models"
+	"github.com/rick/go-neon-api/internal/store"
+	"github.com/rick/go-neon-api/internal/store/postgres"
+)
+
+// TestQuoteCreateConcurrent checks that concurrent creates for one case get
+// revisions 1..n. It commits, since the creates need connections of their
+// own, and deletes what it made.
+func TestQuoteCreateConcurrent(t *testing.T) {
+	const n = 20
+	gdb := connectTestDB(t)
+	s := postgres.New(gdb)
+	ctx := context.Background()
+
+	userID := cuid.New()
+	if err := gdb.Exec(`INSERT INTO "User" ("id","email") VALUES (?, ?)`, userID, userID+"@example.com").Error; err != nil {
+		t.Fatal(err)
+	}
+	var caseID string
+	t.Cleanup(func() {
+		gdb.Exec(`DELETE FROM "QuoteCounter" WHERE "caseId" = ?`, caseID)
+		gdb.Exec(`DELETE FROM "Case" WHERE "id" = ?`, caseID)
+		gdb.Exec(`DELETE FROM "User" WHERE "id" = ?`, userID)
+	})
+	c, err := s.Cases.Create(ctx, userID, store.CaseInput{UserID: userID, Status: casestatus.New, CustomerName: "Lincoln"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	caseID = c.ID
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		revisions []int
+		errs      []error
+	)
+	start := make(chan struct{})
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			q := &models.Quote{CaseID: caseID, Snapshot: json.RawMessage(`{}`)}
+			err := s.Quotes.Create(ctx, userID, q)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			if q.Number != store.QuoteNumber(caseID, q.Revision) {
+				t.Errorf("revision %d numbered %s", q.Revision, q.Number)
+			}
+			revisions = append(revisions, q.Revision)
+		}()
+	}
+	close(start)
+	wg.Wait()
+	if len(errs) > 0 {
+		t.Fatalf("%d of %d creates failed; first: %v", len(errs), n, errs[0])
+	}
+
+	sort.Ints(revisions)
+	for i, r := range revisions {
+		if r != i+1 {
+			t.Fatalf("revisions %v, want 1..%d with no gaps or duplicates", revisions, n)
+		}
+	}
+	listed, err := s.Quotes.List(ctx, caseID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != n {
+		t.Fatalf("listed %d quotes, want %d", len(listed), n)
+	}
+	if listed[0].Revision != n {
+		t.Errorf("newest listed revision %d, want %d", listed[0].Revision, n)
+	}
+}
@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/lucsky/cuid"
+	"github.com/rick/go-neon-api/internal/models"
+	"github.com/rick/go-neon-api/internal/store"
+	"gorm.io/gorm"
+)
+
+type users struct{ db *gorm.DB }
+
+const userColumns = `"id","email","name","password","role","createdAt","updatedAt","passwordChangedAt"`
+
+func (s *users) ByID(ctx context.Context, id string) (*models.User, error) {
+	return s.one(ctx, `"id" = ?`, id)
+}
+
+func (s *users) ByEmail(ctx context.Context, email string) (*models.User, error) {
+	return s.one(ctx, `lower("email") = lower(?)`, email)
+}
+
+func (s *users) one(ctx context.Context, where string, arg any) (*models.User, error) {
+	var u models.User
+	res := s.db.WithContext(ctx).Raw(
+		`SELECT `+userColumns+` FROM "User" WHERE `+where+` LIMIT 1`, arg,
+	).Scan(&u)
+	if err := notFoundIfNone(res); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (s *users) SetPassword(ctx context.Context, userID, hash string) error {
+	return notFoundIfNone(s.db.WithContext(ctx).Exec(
+		`UPDATE "User" SET "password" = ?, "passwordChangedAt" = now(), "updatedAt" = now() WHERE "id" = ?`,
+		hash, userID,
+	))
+}
+
+func (s *users) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	var revoked bool
+	err := s.db.WithContext(ctx).Raw(
+		`SELECT EXISTS (SELECT 1 FROM "RevokedToken" WHERE "id" = ?)`, tokenID,
+	).Scan(&revoked).Error
+	return revoked, err
+}
+
+func (s *users) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	db := s.db.WithContext(ctx)
+	if err := db.Exec(
+		`INSERT INTO "RevokedToken" ("id","expiresAt","createdAt") VALUES (?, ?, now())
+		 ON CONFLICT ("id") DO NOTHING`,
+		tokenID, expiresAt,
+	).Error; err != nil {
+		return err
+	}
+	// Opportunistic cleanup; expired tokens are rejected by signature checks anyway.
+	db.Exec(`DELETE FROM "RevokedToken" WHERE "expiresAt" < now()`)
+	return nil
+}
+
+func (s *users) CreateResetToken(ctx context.Context, userID, digest string, expiresAt time.Time) error {
+	return s.db.WithContext(ctx).Exec(
+		`INSERT INTO "PasswordResetToken" ("id","userId","tokenHash","expiresAt","createdAt")
+		 VALUES (?, ?, ?, ?, now())`,
+		cuid.New(), userID, digest, expiresAt,
+	).Error
+}
+
+func (s *users) ConsumeResetToken(ctx context.Context, digest, hash string) (string, error) {
+	var userID string
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Consume the token atomically so it cannot be replayed.
+		var row struct {
+			UserID string `gorm:"column:userId"`
+		}
+		if err := tx.Raw(
+			`UPDATE "PasswordResetToken" SET "usedAt" = now()
+			  WHERE "tokenHash" = ? AND "usedAt" IS NULL AND "expiresAt" > now()
+			  RETURNING "userId"`,
+			digest,
+		).Scan(&row).Error; err != nil {
+			return err
+		}
+		if row.UserID == "" {
+			return store.ErrNotFound
+		}
+		userID = row.UserID
+
+		if err := tx.Exec(
+			`UPDATE "User" SET "password" = ?, "passwordChangedAt" = now(), "updatedAt" = now() WHERE "id" = ?`,
+			hash, userID,
+		).Error; err != nil {
+			return err
+		}
+		// Any other outstanding links for this user are now stale.
+		return tx.Exec(
+			`UPDATE "PasswordResetToken" SET "usedAt" = now() WHERE "userId" = ? AND "usedAt" IS NULL`,
+			userID,
+		).Error
+	})
+	if err != nil {
+		return "", err
+	}
+	return userID, nil
+}
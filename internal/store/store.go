@@ -0,0 +1,728 @@
+// Package store defines the data access the HTTP handlers depend on: one
+// interface per aggregate plus the row types they return. Package postgres
+// implements it against the Prisma schema; package memory implements it in
+// process for tests.
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rick/go-neon-api/internal/models"
+	"github.com/rick/go-neon-api/internal/pagination"
+)
+
+var (
+	// ErrNotFound is returned when the addressed row does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a write is blocked by rows that reference
+	// or duplicate it.
+	ErrConflict = errors.New("conflict")
+	// ErrInvalidReference is returned when a write names a related row, such
+	// as a product or fixture type, that does not exist.
+	ErrInvalidReference = errors.New("invalid reference")
+)
+
+// Store bundles the per-aggregate stores handed to handlers.New.
+type Store struct {
+	Users   UserStore
+	Cases   CaseStore
+	OnSite  OnSiteStore
+	Catalog CatalogStore
+	Files   FileStore
+	Quotes  QuoteStore
+}
+
+// ---------- Users ----------
+
+type UserStore interface {
+	// ByID and ByEmail return ErrNotFound for unknown users. ByEmail
+	// matches case-insensitively. The returned user carries the password hash.
+	ByID(ctx context.Context, id string) (*models.User, error)
+	ByEmail(ctx context.Context, email string) (*models.User, error)
+	// SetPassword stores a new hash and stamps passwordChangedAt, which
+	// invalidates every token issued before it.
+	SetPassword(ctx context.Context, userID, hash string) error
+
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+	// Revoke blocks a token id until it expires and prunes expired entries.
+	Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error
+
+	CreateResetToken(ctx context.Context, userID, digest string, expiresAt time.Time) error
+	// ConsumeResetToken spends an unused, unexpired token, sets the user's
+	// password and voids their other outstanding tokens in one transaction.
+	// ErrNotFound means the token is unknown, used or expired.
+	ConsumeResetToken(ctx context.Context, digest, hash string) (userID string, err error)
+}
+
+// ---------- Cases ----------
+
+// Resource names what an id points at for ownership checks.
+type Resource int
+
+const (
+	CaseResource Resource = iota
+	VisitResource
+	RoomResource
+	ExistingProductResource
+	SuggestedProductResource
+	RoomPhotoResource
+)
+
+// DeletedFiles are the urls of the rows a delete removed.
+type DeletedFiles struct {
+	Photos    []string // case and room photos
+	Documents []string
+}
+
+type CaseStore interface {
+	// OwnerOf resolves a resource id to the owning Case.userId by walking up
+	// fixture or photo → room → visit → case. ErrNotFound for unknown ids.
+	OwnerOf(ctx context.Context, res Resource, id string) (string, error)
+
+	// List returns pagination.ErrBadCursor for cursors issued for another sort.
+	List(ctx context.Context, f CaseFilter, p pagination.Params) (pagination.Page[CaseSummary], error)
+	Get(ctx context.Context, id string) (*CaseDetail, error)
+
+	// Create, Update and Transition record an ActivityLog row by actorID in
+	// the same transaction as the change.
+	Create(ctx context.Context, actorID string, in CaseInput) (*CaseRecord, error)
+	// Update sets the given columns; callers validate names and values.
+	Update(ctx context.Context, actorID, id string, set map[string]any) error
+	// Delete removes the case and everything hanging off it, its ActivityLog
+	// rows included, and records the deletion by actorID in an ActivityLog
+	// row with no caseId. ErrConflict if another row still references it.
+	// The urls of the deleted photo and document rows are returned so the
+	// caller can remove the stored objects.
+	Delete(ctx context.Context, actorID, id string) (DeletedFiles, error)
+
+	StatusHistory(ctx context.Context, id string) (status string, history []StatusChange, err error)
+	// Transition locks the case and moves it to `to` if allow(current status)
+	// holds, returning *TransitionError otherwise.
+	Transition(ctx context.Context, actorID, id, to string, note *string, allow func(from string) bool) (*StatusChange, error)
+
+	// UploadLink returns the case's customer upload token, expired or not.
+	// ByUploadToken resolves a token to its case and returns ErrNotFound if
+	// the token is unknown or expired.
+	UploadLink(ctx context.Context, caseID string) (*UploadLink, error)
+	ByUploadToken(ctx context.Context, token string) (*UploadLink, error)
+	// RotateUploadToken replaces the token, so the old link stops working,
+	// and sets its expiry (nil: never). ExpireUploadToken ends the current
+	// token now. Both log to ActivityLog.
+	RotateUploadToken(ctx context.Context, actorID, caseID string, expiresAt *time.Time) (*UploadLink, error)
+	ExpireUploadToken(ctx context.Context, actorID, caseID string) error
+
+	// Operation returns the case's operating schedule and PaybackSetting.
+	Operation(ctx context.Context, id string) (*CaseOperation, error)
+}
+
+// CaseFilter narrows the case list; zero fields do not filter.
+type CaseFilter struct {
+	OwnerID             string
+	Statuses            []string
+	SchoolName          string // substring, case-insensitive
+	InstallationService string // exact, case-insensitive
+	CreatedFrom         *time.Time
+	CreatedBefore       *time.Time
+	UpdatedFrom         *time.Time
+	UpdatedBefore       *time.Time
+	Words               []string // each must appear in one of the search columns
+	Sort                string   // key of CaseSorts
+	Desc                bool
+}
+
+// CaseSorts are the values accepted for CaseFilter.Sort.
+var CaseSorts = map[string]pagination.Kind{
+	"createdAt":            pagination.Time,
+	"updatedAt":            pagination.Time,
+	"customerName":         pagination.String,
+	"projectDetails":       pagination.String,
+	"schoolName":           pagination.String,
+	"contactPerson":        pagination.String,
+	"emailAddress":         pagination.String,
+	"phoneNumber":          pagination.String,
+	"schoolAddress":        pagination.String,
+	"status":               pagination.String,
+	"installationService":  pagination.String,
+	"operationDaysPerYear": pagination.Int,
+	"operationHoursPerDay": pagination.Int,
+	"userName":             pagination.String,
+	"userEmail":            pagination.String,
+}
+
+// CaseSummary is one row of the case list, including every sortable column
+// so the next-page cursor can be built from the last row.
+type CaseSummary struct {
+	ID                   string    `gorm:"column:id"`
+	CustomerName         string    `gorm:"column:customerName"`
+	ProjectDetails       string    `gorm:"column:projectDetails"`
+	ContactPerson        string    `gorm:"column:contactPerson"`
+	SchoolName           string    `gorm:"column:schoolName"`
+	EmailAddress         string    `gorm:"column:emailAddress"`
+	PhoneNumber          string    `gorm:"column:phoneNumber"`
+	SchoolAddress        string    `gorm:"column:schoolAddress"`
+	InstallationService  string    `gorm:"column:installationService"`
+	OperationDaysPerYear int       `gorm:"column:operationDaysPerYear"`
+	OperationHoursPerDay int       `gorm:"column:operationHoursPerDay"`
+	Status               string    `gorm:"column:status"`
+	CreatedAt            time.Time `gorm:"column:createdAt"`
+	UpdatedAt            time.Time `gorm:"column:updatedAt"`
+	UserName             *string   `gorm:"column:user_name"`
+	UserEmail            string    `gorm:"column:user_email"`
+}
+
+// SortValue returns the value of the CaseSorts column named by key.
+func (r CaseSummary) SortValue(key string) any {
+	switch key {
+	case "createdAt":
+		return r.CreatedAt
+	case "updatedAt":
+		return r.UpdatedAt
+	case "customerName":
+		return r.CustomerName
+	case "projectDetails":
+		return r.ProjectDetails
+	case "schoolName":
+		return r.SchoolName
+	case "contactPerson":
+		return r.ContactPerson
+	case "emailAddress":
+		return r.EmailAddress
+	case "phoneNumber":
+		return r.PhoneNumber
+	case "schoolAddress":
+		return r.SchoolAddress
+	case "status":
+		return r.Status
+	case "installationService":
+		return r.InstallationService
+	case "operationDaysPerYear":
+		return int64(r.OperationDaysPerYear)
+	case "operationHoursPerDay":
+		return int64(r.OperationHoursPerDay)
+	case "userName":
+		if r.UserName == nil {
+			return ""
+		}
+		return *r.UserName
+	case "userEmail":
+		return r.UserEmail
+	}
+	panic("store: unknown case sort " + key)
+}
+
+// CaseDetail is a case header with its owner, documents and photos.
+type CaseDetail struct {
+	ID             string    `gorm:"column:id"`
+	CustomerName   string    `gorm:"column:customerName"`
+	ProjectDetails string    `gorm:"column:projectDetails"`
+	ContactPerson  string    `gorm:"column:contactPerson"`
+	SchoolName     string    `gorm:"column:schoolName"`
+	EmailAddress   string    `gorm:"column:emailAddress"`
+	PhoneNumber    string    `gorm:"column:phoneNumber"`
+	SchoolAddress  string    `gorm:"column:schoolAddress"`
+	Status         string    `gorm:"column:status"`
+	CreatedAt      time.Time `gorm:"column:createdAt"`
+	UpdatedAt      time.Time `gorm:"column:updatedAt"`
+	UserName       *string   `gorm:"column:user_name"`
+	UserEmail      string    `gorm:"column:user_email"`
+
+	Documents []DocumentRow `gorm:"-"`
+	Photos    []PhotoRow    `gorm:"-"`
+}
+
+type DocumentRow struct {
+	ID              string    `json:"id"            gorm:"column:id"`
+	URL             string    `json:"url"           gorm:"column:url"`
+	FileName        string    `json:"fileName"      gorm:"column:fileName"`
+	CustomName      *string   `json:"customName"    gorm:"column:customName"`
+	Comment         *string   `json:"comment"       gorm:"column:comment"`
+	UploadedViaLink bool      `json:"uploadedViaLink" gorm:"column:uploadedViaLink"`
+	CreatedAt       time.Time `json:"createdAt"     gorm:"column:createdAt"`
+	DownloadURL     string    `json:"downloadUrl"   gorm:"-"` // filled in by handlers
+}
+
+type PhotoRow struct {
+	ID              string    `json:"id"            gorm:"column:id"`
+	URL             string    `json:"url"           gorm:"column:url"`
+	Comment         *string   `json:"comment"       gorm:"column:comment"`
+	CustomName      *string   `json:"customName"    gorm:"column:customName"`
+	UploadedViaLink bool      `json:"uploadedViaLink" gorm:"column:uploadedViaLink"`
+	CreatedAt       time.Time `json:"createdAt"     gorm:"column:createdAt"`
+	PhotoMeta
+	DownloadURL          string `json:"downloadUrl"          gorm:"-"` // filled in by handlers
+	ThumbnailDownloadURL string `json:"thumbnailDownloadUrl" gorm:"-"`
+}
+
+// PhotoMeta is what the upload pipeline derives from an image file: a
+// thumbnail's storage key and the EXIF capture time and GPS position. All
+// nil for photos added by URL or without EXIF.
+type PhotoMeta struct {
+	ThumbnailURL *string    `json:"thumbnailUrl" gorm:"column:thumbnailUrl"`
+	TakenAt      *time.Time `json:"takenAt"      gorm:"column:takenAt"`
+	Latitude     *float64   `json:"latitude"     gorm:"column:latitude"`
+	Longitude    *float64   `json:"longitude"    gorm:"column:longitude"`
+}
+
+// CaseInput is a new case; the store generates id and uploadToken.
+type CaseInput struct {
+	UserID               string
+	Status               string
+	CustomerName         string
+	ProjectDetails       string
+	SchoolName           string
+	ContactPerson        string
+	EmailAddress         string
+	PhoneNumber          string
+	SchoolAddress        string
+	LightingPurpose      string
+	FacilitiesUsedIn     string
+	InstallationService  string
+	OperationDaysPerYear int
+	OperationHoursPerDay int
+}
+
+// CaseRecord mirrors every scalar "Case" column returned by create.
+type CaseRecord struct {
+	ID                   string     `json:"id"                   gorm:"column:id"`
+	UserID               string     `json:"userId"               gorm:"column:userId"`
+	CustomerName         string     `json:"customerName"         gorm:"column:customerName"`
+	ProjectDetails       string     `json:"projectDetails"       gorm:"column:projectDetails"`
+	UploadToken          string     `json:"uploadToken"          gorm:"column:uploadToken"`
+	UploadTokenExpiresAt *time.Time `json:"uploadTokenExpiresAt" gorm:"column:uploadTokenExpiresAt"`
+	Status               string     `json:"status"               gorm:"column:status"`
+	CreatedAt            time.Time  `json:"createdAt"            gorm:"column:createdAt"`
+	UpdatedAt            time.Time  `json:"updatedAt"            gorm:"column:updatedAt"`
+	SchoolName           string     `json:"schoolName"           gorm:"column:schoolName"`
+	ContactPerson        string     `json:"contactPerson"        gorm:"column:contactPerson"`
+	EmailAddress         string     `json:"emailAddress"         gorm:"column:emailAddress"`
+	PhoneNumber          string     `json:"phoneNumber"          gorm:"column:phoneNumber"`
+	SchoolAddress        string     `json:"schoolAddress"        gorm:"column:schoolAddress"`
+	LightingPurpose      string     `json:"lightingPurpose"      gorm:"column:lightingPurpose"`
+	FacilitiesUsedIn     string     `json:"facilitiesUsedIn"     gorm:"column:facilitiesUsedIn"`
+	InstallationService  string     `json:"installationService"  gorm:"column:installationService"`
+	OperationDaysPerYear int        `json:"operationDaysPerYear" gorm:"column:operationDaysPerYear"`
+	OperationHoursPerDay int        `json:"operationHoursPerDay" gorm:"column:operationHoursPerDay"`
+}
+
+// CaseOperation is what the savings calculator reads from a case: how long
+// its lighting runs and the PaybackSetting value, nil when it has none.
+type CaseOperation struct {
+	DaysPerYear  int      `gorm:"column:operationDaysPerYear"`
+	HoursPerDay  int      `gorm:"column:operationHoursPerDay"`
+	PaybackValue *float64 `gorm:"column:paybackValue"`
+}
+
+// UploadLink is a case's customer upload token with what the upload page
+// shows about the case.
+type UploadLink struct {
+	CaseID       string     `json:"caseId"       gorm:"column:id"`
+	OwnerID      string     `json:"-"            gorm:"column:userId"`
+	CustomerName string     `json:"customerName" gorm:"column:customerName"`
+	SchoolName   string     `json:"schoolName"   gorm:"column:schoolName"`
+	Token        string     `json:"token"        gorm:"column:uploadToken"`
+	ExpiresAt    *time.Time `json:"expiresAt"    gorm:"column:uploadTokenExpiresAt"`
+}
+
+// Expired reports whether the token has stopped working as of now.
+func (l *UploadLink) Expired(now time.Time) bool {
+	return l.ExpiresAt != nil && !l.ExpiresAt.After(now)
+}
+
+type StatusChange struct {
+	ID         string    `json:"id"         gorm:"column:id"`
+	FromStatus string    `json:"fromStatus" gorm:"column:fromStatus"`
+	ToStatus   string    `json:"toStatus"   gorm:"column:toStatus"`
+	Note       *string   `json:"note"       gorm:"column:note"`
+	UserID     string    `json:"userId"     gorm:"column:userId"`
+	UserName   *string   `json:"userName"   gorm:"column:userName"`
+	CreatedAt  time.Time `json:"createdAt"  gorm:"column:createdAt"`
+}
+
+// TransitionError is returned by Transition when allow rejects the move.
+type TransitionError struct {
+	From, To string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot move case from %q to %q", e.From, e.To)
+}
+
+// ---------- On-site visits ----------
+
+type OnSiteStore interface {
+	// Visit returns the case's visit header, ErrNotFound if there is none.
+	Visit(ctx context.Context, caseID string) (*Visit, error)
+	// EnsureVisit returns the case's visit, creating it if missing.
+	EnsureVisit(ctx context.Context, caseID string) (*Visit, error)
+	// Rooms returns the visit's rooms, newest first, with their fixtures and
+	// tagged photos. The number of queries does not depend on the number of
+	// rooms.
+	Rooms(ctx context.Context, visitID string) ([]Room, error)
+	// RoomVisit returns the id of the room's visit, ErrNotFound for an
+	// unknown room.
+	RoomVisit(ctx context.Context, roomID string) (string, error)
+
+	// CreateRoom and UpdateRoom return ErrNotFound if locationTagId names no
+	// location tag.
+	CreateRoom(ctx context.Context, visitID string, in RoomInput) (*Room, error)
+	// UpdateRoom, UpdateExisting and UpdateSuggested set the given columns;
+	// callers validate the names.
+	UpdateRoom(ctx context.Context, id string, set map[string]any) error
+	// DeleteRoom returns the urls of the room's deleted photos.
+	DeleteRoom(ctx context.Context, id string) (photoURLs []string, err error)
+
+	AddExisting(ctx context.Context, roomID, productID string, quantity int, bypassBallast bool) (string, error)
+	UpdateExisting(ctx context.Context, id string, set map[string]any) error
+	DeleteExisting(ctx context.Context, id string) error
+
+	// AddSuggested stores a LightFixtureType id in "productId".
+	AddSuggested(ctx context.Context, roomID, fixtureTypeID string, quantity int) (string, error)
+	// AddSuggestedMissing adds the lines in one transaction, skipping each
+	// whose room already suggests its fixture type or no longer exists. The
+	// rooms are locked first, so concurrent calls cannot add a type twice.
+	// It returns the lines added, with their ids.
+	AddSuggestedMissing(ctx context.Context, lines []NewSuggested) ([]NewSuggested, error)
+	UpdateSuggested(ctx context.Context, id string, set map[string]any) error
+	DeleteSuggested(ctx context.Context, id string) error
+
+	// RoomPhotos lists a room's photos oldest first, each with its tags.
+	RoomPhotos(ctx context.Context, roomID string) ([]RoomPhoto, error)
+	AddRoomPhoto(ctx context.Context, roomID, url, comment string, meta PhotoMeta) (*RoomPhoto, error)
+	SetRoomPhotoComment(ctx context.Context, id, comment string) error
+	// DeleteRoomPhoto returns the deleted row's url so the caller can remove
+	// the stored object.
+	DeleteRoomPhoto(ctx context.Context, id string) (url string, err error)
+	// TagRoomPhoto is a no-op if the photo already has the tag; ErrNotFound
+	// if the tag does not exist. UntagRoomPhoto returns ErrNotFound if the
+	// photo does not have it.
+	TagRoomPhoto(ctx context.Context, photoID, tagID string) error
+	UntagRoomPhoto(ctx context.Context, photoID, tagID string) error
+
+	// PhotoTags is the shared tag vocabulary, by name. Create and Rename
+	// return ErrConflict for a name already in use; Delete returns
+	// ErrConflict while any photo carries the tag.
+	PhotoTags(ctx context.Context) ([]Tag, error)
+	CreatePhotoTag(ctx context.Context, name string) (*Tag, error)
+	RenamePhotoTag(ctx context.Context, id, name string) error
+	DeletePhotoTag(ctx context.Context, id string) error
+
+	// LocationTags is the room location vocabulary, by name. Create and
+	// Rename return ErrConflict for a name already in use; Delete untags the
+	// rooms that carried it.
+	LocationTags(ctx context.Context) ([]Tag, error)
+	CreateLocationTag(ctx context.Context, name string) (*Tag, error)
+	RenameLocationTag(ctx context.Context, id, name string) error
+	DeleteLocationTag(ctx context.Context, id string) error
+}
+
+type Visit struct {
+	ID        string    `json:"id"        gorm:"column:id"`
+	CaseID    string    `json:"caseId"    gorm:"column:caseId"`
+	CreatedAt time.Time `json:"createdAt" gorm:"column:createdAt"`
+}
+
+type RoomInput struct {
+	Location        string
+	LocationTagID   *string
+	LightingIssue   string
+	CustomerRequest string
+	MountingKitQty  string
+	MotionSensorQty int
+	CeilingHeight   *int
+}
+
+type Room struct {
+	ID              string          `json:"id"              gorm:"column:id"`
+	OnSiteVisitID   string          `json:"onSiteVisitId"   gorm:"column:onSiteVisitId"`
+	Location        string          `json:"location"        gorm:"column:location"`
+	LocationTagID   *string         `json:"locationTagId"   gorm:"column:locationTagId"`
+	LocationTag     *string         `json:"locationTag"     gorm:"column:locationTag"` // tag name
+	LightingIssue   string          `json:"lightingIssue"   gorm:"column:lightingIssue"`
+	CustomerRequest string          `json:"customerRequest" gorm:"column:customerRequest"`
+	MountingKitQty  string          `json:"mountingKitQty"  gorm:"column:mountingKitQty"`
+	MotionSensorQty int             `json:"motionSensorQty" gorm:"column:motionSensorQty"`
+	CreatedAt       time.Time       `json:"createdAt"       gorm:"column:createdAt"`
+	CeilingHeight   *int            `json:"ceilingHeight"   gorm:"column:ceilingHeight"`
+	Existing        []ExistingLine  `json:"existing"        gorm:"-"`
+	Suggested       []SuggestedLine `json:"suggested"       gorm:"-"`
+	Photos          []RoomPhoto     `json:"photos"          gorm:"-"`
+}
+
+type ExistingLine struct {
+	ID            string  `json:"id"            gorm:"column:id"`
+	RoomID        string  `json:"-"             gorm:"column:roomId"`
+	ProductID     string  `json:"productId"     gorm:"column:productId"`
+	ProductName   string  `json:"productName"   gorm:"column:productName"`
+	ProductWatt   float64 `json:"wattage"       gorm:"column:wattage"`
+	Category      *string `json:"category"      gorm:"column:category"` // of the product
+	Quantity      int     `json:"quantity"      gorm:"column:quantity"`
+	BypassBallast bool    `json:"bypassBallast" gorm:"column:bypassBallast"`
+}
+
+type SuggestedLine struct {
+	ID        string   `json:"id"          gorm:"column:id"`
+	RoomID    string   `json:"-"           gorm:"column:roomId"`
+	ProductID string   `json:"productId"   gorm:"column:productId"` // stores LightFixtureType.id
+	TypeName  string   `json:"typeName"    gorm:"column:typeName"`
+	SKU       *string  `json:"sku"         gorm:"column:SKU"`
+	ImageURL  *string  `json:"imageUrl"    gorm:"column:imageUrl"`
+	Wattage   *float64 `json:"wattage"     gorm:"column:wattage"`
+	Quantity  int      `json:"quantity"    gorm:"column:quantity"`
+	Pricing            // of the fixture type
+}
+
+// NewSuggested is a suggested line to add; ID is set once it is.
+type NewSuggested struct {
+	ID            string
+	RoomID        string
+	FixtureTypeID string
+	Quantity      int
+}
+
+type RoomPhoto struct {
+	ID        string    `json:"id"          gorm:"column:id"`
+	RoomID    string    `json:"roomId"      gorm:"column:roomId"`
+	URL       string    `json:"url"         gorm:"column:url"`
+	Comment   string    `json:"comment"     gorm:"column:comment"`
+	CreatedAt time.Time `json:"createdAt"   gorm:"column:createdAt"`
+	PhotoMeta
+	Tags                 []Tag  `json:"tags"                 gorm:"-"`
+	DownloadURL          string `json:"downloadUrl"          gorm:"-"` // filled in by handlers
+	ThumbnailDownloadURL string `json:"thumbnailDownloadUrl" gorm:"-"`
+}
+
+// Tag is an entry in one of the shared vocabularies: photo tags or location
+// tags.
+type Tag struct {
+	ID        string    `json:"id"        gorm:"column:id"`
+	Name      string    `json:"name"      gorm:"column:name"`
+	CreatedAt time.Time `json:"createdAt" gorm:"column:createdAt"`
+}
+
+// ---------- Catalog ----------
+
+// CatalogStore backs the pickers, which page by name, and the admin catalog
+// endpoints.
+type CatalogStore interface {
+	ListProducts(ctx context.Context, f ProductFilter, p pagination.Params) (pagination.Page[ProductRow], error)
+	ListFixtureTypes(ctx context.Context, f FixtureTypeFilter, p pagination.Params) (pagination.Page[FixtureTypeRow], error)
+
+	// Create and Update return ErrConflict if the name or SKU is taken.
+	// Update, Archive and Delete return ErrNotFound for unknown ids.
+	CreateProduct(ctx context.Context, in ProductInput) (*ProductRow, error)
+	UpdateProduct(ctx context.Context, id string, in ProductInput) (*ProductRow, error)
+	// ArchiveProduct hides the product from the picker; archived=false
+	// restores it.
+	ArchiveProduct(ctx context.Context, id string, archived bool) (*ProductRow, error)
+	// DeleteProduct removes a product no existing-fixture line uses. One
+	// that is still used is archived instead and archived=true is returned.
+	DeleteProduct(ctx context.Context, id string) (archived bool, err error)
+
+	CreateFixtureType(ctx context.Context, in FixtureTypeInput) (*FixtureTypeRow, error)
+	UpdateFixtureType(ctx context.Context, id string, in FixtureTypeInput) (*FixtureTypeRow, error)
+	ArchiveFixtureType(ctx context.Context, id string, archived bool) (*FixtureTypeRow, error)
+	// DeleteFixtureType archives instead of deleting while a suggested
+	// fixture line or case fixture count uses the type.
+	DeleteFixtureType(ctx context.Context, id string) (archived bool, err error)
+
+	// ExportProducts and ExportFixtureTypes return the whole catalog ordered
+	// by name.
+	ExportProducts(ctx context.Context, includeArchived bool) ([]ProductRow, error)
+	ExportFixtureTypes(ctx context.Context, includeArchived bool) ([]FixtureTypeRow, error)
+
+	// ImportProducts passes every product, archived ones included, to plan
+	// while holding other catalog writers off. If plan returns commit=true,
+	// its creates (ID ignored) and updates (matched by ID) are written in the
+	// same transaction. ErrConflict if they collide on a name or SKU.
+	ImportProducts(ctx context.Context, plan func(existing []ProductRow) (creates, updates []ProductRow, commit bool)) error
+	ImportFixtureTypes(ctx context.Context, plan func(existing []FixtureTypeRow) (creates, updates []FixtureTypeRow, commit bool)) error
+
+	// Accessories returns one row per kind, ordered by kind.
+	Accessories(ctx context.Context) ([]Accessory, error)
+	// UpdateAccessory replaces the name, SKU and prices of the accessory of
+	// kind; ErrNotFound for an unknown kind.
+	UpdateAccessory(ctx context.Context, kind string, in AccessoryInput) (*Accessory, error)
+
+	// Recommendations lists the automatic suggestion rules, oldest first.
+	// Create and Update return ErrInvalidReference if the product, location
+	// tag or fixture type does not exist; Update and Delete return
+	// ErrNotFound for unknown ids.
+	Recommendations(ctx context.Context) ([]FixtureRecommendation, error)
+	CreateRecommendation(ctx context.Context, in FixtureRecommendationInput) (*FixtureRecommendation, error)
+	UpdateRecommendation(ctx context.Context, id string, in FixtureRecommendationInput) (*FixtureRecommendation, error)
+	DeleteRecommendation(ctx context.Context, id string) error
+}
+
+type ProductFilter struct {
+	Q               string // name or description substring
+	Category        string
+	IncludeArchived bool
+}
+
+type FixtureTypeFilter struct {
+	Q               string // name, description or SKU substring
+	IncludeArchived bool
+}
+
+type ProductRow struct {
+	ID          string     `json:"id"          gorm:"column:id"`
+	Name        string     `json:"name"        gorm:"column:name"`
+	Wattage     float64    `json:"wattage"     gorm:"column:wattage"`
+	Category    *string    `json:"category"    gorm:"column:category"`
+	Description *string    `json:"description" gorm:"column:description2"`
+	SKU         *string    `json:"sku"         gorm:"column:SKU"`
+	ArchivedAt  *time.Time `json:"archivedAt"  gorm:"column:archivedAt"`
+}
+
+type FixtureTypeRow struct {
+	ID          string     `json:"id"          gorm:"column:id"`
+	Name        string     `json:"name"        gorm:"column:name"`
+	SKU         *string    `json:"sku"         gorm:"column:SKU"`
+	Wattage     *float64   `json:"wattage"     gorm:"column:wattage"`
+	ImageURL    *string    `json:"imageUrl"    gorm:"column:imageUrl"`
+	Description *string    `json:"description" gorm:"column:description"`
+	ArchivedAt  *time.Time `json:"archivedAt"  gorm:"column:archivedAt"`
+	Pricing
+}
+
+// ProductInput is every editable Product column; update replaces them all.
+type ProductInput struct {
+	Name        string
+	Wattage     float64
+	Category    *string
+	Description *string
+	SKU         *string
+}
+
+// FixtureTypeInput is every editable LightFixtureType column.
+type FixtureTypeInput struct {
+	Name        string
+	Wattage     *float64
+	Description *string
+	SKU         *string
+	ImageURL    *string
+	Pricing
+}
+
+// Pricing is what a fixture type or accessory costs per unit. Nil fields are
+// not priced yet.
+type Pricing struct {
+	UnitCost   *float64 `json:"unitCost"   gorm:"column:unitCost"`   // what we pay
+	ListPrice  *float64 `json:"listPrice"  gorm:"column:listPrice"`  // what the customer pays
+	LaborHours *float64 `json:"laborHours" gorm:"column:laborHours"` // to install one
+}
+
+// Accessory is the priced item behind a room accessory count.
+type Accessory struct {
+	ID        string    `json:"id"        gorm:"column:id"`
+	Kind      string    `json:"kind"      gorm:"column:kind"` // models.AccessoryMountingKit, ...
+	Name      string    `json:"name"      gorm:"column:name"`
+	SKU       *string   `json:"sku"       gorm:"column:SKU"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"column:updatedAt"`
+	Pricing
+}
+
+type AccessoryInput struct {
+	Name string
+	SKU  *string
+	Pricing
+}
+
+// FixtureRecommendation is a models.FixtureRecommendation with the names of
+// the rows it refers to. Deleting the product, location tag or fixture type
+// deletes the rule.
+type FixtureRecommendation struct {
+	ID                  string    `json:"id"                  gorm:"column:id"`
+	ProductID           *string   `json:"productId"           gorm:"column:productId"`
+	ProductName         *string   `json:"productName"         gorm:"column:productName"`
+	Category            *string   `json:"category"            gorm:"column:category"`
+	MinCeilingHeight    *int      `json:"minCeilingHeight"    gorm:"column:minCeilingHeight"`
+	MaxCeilingHeight    *int      `json:"maxCeilingHeight"    gorm:"column:maxCeilingHeight"`
+	LocationTagID       *string   `json:"locationTagId"       gorm:"column:locationTagId"`
+	LocationTag         *string   `json:"locationTag"         gorm:"column:locationTag"` // tag name
+	FixtureTypeID       string    `json:"fixtureTypeId"       gorm:"column:fixtureTypeId"`
+	FixtureTypeName     string    `json:"fixtureTypeName"     gorm:"column:fixtureTypeName"`
+	FixtureTypeArchived bool      `json:"fixtureTypeArchived" gorm:"column:fixtureTypeArchived"`
+	Priority            int       `json:"priority"            gorm:"column:priority"`
+	CreatedAt           time.Time `json:"createdAt"           gorm:"column:createdAt"`
+	UpdatedAt           time.Time `json:"updatedAt"           gorm:"column:updatedAt"`
+}
+
+// FixtureRecommendationInput is every editable rule column; update replaces
+// them all.
+type FixtureRecommendationInput struct {
+	ProductID        *string
+	Category         *string
+	MinCeilingHeight *int
+	MaxCeilingHeight *int
+	LocationTagID    *string
+	FixtureTypeID    string
+	Priority         int
+}
+
+// ---------- Case files ----------
+
+// FileStore manages a case's photos and documents. Every write records an
+// ActivityLog row by actorID in the same transaction.
+type FileStore interface {
+	// Photos and Documents list a case's files, newest first.
+	Photos(ctx context.Context, caseID string) ([]PhotoRow, error)
+	Documents(ctx context.Context, caseID string) ([]DocumentRow, error)
+
+	// AddPhoto and AddDocument fill in ID and CreatedAt. Files flagged
+	// UploadedViaLink are logged as customer uploads.
+	AddPhoto(ctx context.Context, actorID string, p *models.Photo) error
+	AddDocument(ctx context.Context, actorID string, d *models.Document) error
+
+	// UpdatePhoto and UpdateDocument set the given columns; callers validate
+	// the names. They and the deletes return ErrNotFound unless id is a file
+	// of caseID. The deletes return the row's url so the caller can remove
+	// the stored object.
+	UpdatePhoto(ctx context.Context, actorID, caseID, id string, set map[string]any) error
+	UpdateDocument(ctx context.Context, actorID, caseID, id string, set map[string]any) error
+	DeletePhoto(ctx context.Context, actorID, caseID, id string) (url string, err error)
+	DeleteDocument(ctx context.Context, actorID, caseID, id string) (url string, err error)
+}
+
+// ---------- Quotes ----------
+
+// QuoteRow is a quote without its snapshot, for listings.
+type QuoteRow struct {
+	ID          string    `json:"id"          gorm:"column:id"`
+	Number      string    `json:"number"      gorm:"column:number"`
+	Revision    int       `json:"revision"    gorm:"column:revision"`
+	Note        *string   `json:"note"        gorm:"column:note"`
+	TotalCost   float64   `json:"totalCost"   gorm:"column:totalCost"`
+	TotalPrice  float64   `json:"totalPrice"  gorm:"column:totalPrice"`
+	LaborHours  float64   `json:"laborHours"  gorm:"column:laborHours"`
+	CreatedByID string    `json:"createdById" gorm:"column:createdById"`
+	CreatedAt   time.Time `json:"createdAt"   gorm:"column:createdAt"`
+}
+
+// CaseDeletedAction is the ActivityLog action recording a case's deletion;
+// it names the case, since the row has no caseId.
+func CaseDeletedAction(caseID, customerName string) string {
+	return fmt.Sprintf("Case deleted: %s (%s)", caseID, customerName)
+}
+
+// QuoteNumber formats a case's quote revision for customers: Q-<case>-003.
+func QuoteNumber(caseID string, revision int) string {
+	return fmt.Sprintf("Q-%s-%03d", caseID, revision)
+}
+
+// QuoteStore keeps a case's quote revisions. Quotes are never changed; a new
+// revision is made instead.
+type QuoteStore interface {
+	// List returns the case's quotes, newest first.
+	List(ctx context.Context, caseID string) ([]QuoteRow, error)
+	// Get returns one revision with its snapshot, or ErrNotFound.
+	Get(ctx context.Context, caseID string, revision int) (*models.Quote, error)
+	// Create takes the next revision from the case's QuoteCounter, so
+	// concurrent calls never share a number, and fills in ID, Revision,
+	// Number and CreatedAt. It returns ErrNotFound for an unknown case and
+	// logs the quote by actorID.
+	Create(ctx context.Context, actorID string, q *models.Quote) error
+}
@@ -1,49 +1,53 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 
 	"github.com/joho/godotenv" // optional; add to go.mod if you want
+	"github.com/rick/go-neon-api/internal/auth"
 	"github.com/rick/go-neon-api/internal/db"
 	"github.com/rick/go-neon-api/internal/http"
 	"github.com/rick/go-neon-api/internal/http/handlers"
-	"github.com/rick/go-neon-api/internal/models"
+	"github.com/rick/go-neon-api/internal/mail"
+	"github.com/rick/go-neon-api/internal/migrate"
+	"github.com/rick/go-neon-api/internal/storage"
+	"github.com/rick/go-neon-api/internal/store/postgres"
 )
 
 func main() {
 	_ = godotenv.Load()
 
-	db.Connect()
-
-	// Auto-migrate all tables from your Prisma schema mapping
-	if err := db.DB.AutoMigrate(
-		&models.User{},
-		&models.Case{},
-		&models.ActivityLog{},
-		&models.LightFixtureType{},
-		&models.CaseFixtureCount{},
-		&models.InstallationDetail{},
-		&models.InstallationTag{},
-		&models.InstallationDetailTag{},
-		&models.OnSiteVisit{},
-		&models.Product{},
-		&models.OnSiteVisitRoom{},
-		&models.OnSiteExistingProduct{},
-		&models.OnSiteSuggestedProduct{},
-		&models.OnSiteVisitPhoto{},
-		&models.OnSitePhotoTag{},
-		&models.OnSiteVisitPhotoTagPivot{},
-		&models.OnSiteLocationTag{},
-		&models.Document{},
-		&models.Photo{},
-		&models.QuoteCounter{},
-		&models.PaybackSetting{},
-	); err != nil {
-		log.Fatalf("AutoMigrate failed: %v", err)
+	conn := db.Connect()
+
+	// `go-neon-api migrate up|down [n]|status`
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(conn, os.Args[2:]))
+	}
+
+	// Schema changes are applied explicitly with `migrate up`; refuse to serve
+	// against a database that is behind this binary.
+	pending, err := migrate.Pending(conn)
+	if err != nil {
+		log.Fatalf("checking migrations failed: %v", err)
+	}
+	if len(pending) > 0 {
+		for _, m := range pending {
+			log.Printf("pending migration %04d_%s", m.Version, m.Name)
+		}
+		log.Fatalf("database schema is behind by %d migration(s); run `%s migrate up`", len(pending), os.Args[0])
+	}
+
+	auth.Configure()
+
+	files, uploads, err := storage.FromEnv(context.Background())
+	if err != nil {
+		log.Fatalf("storage: %v", err)
 	}
 
-	h := handlers.New()
+	// No mail transport exists yet; reset links are dropped, not logged.
+	h := handlers.New(postgres.New(conn), files, uploads, mail.Discard{})
 	r := http.NewRouter(h)
 
 	port := os.Getenv("PORT")
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/rick/go-neon-api/internal/migrate"
+	"gorm.io/gorm"
+)
+
+const migrateUsage = `usage: go-neon-api migrate <command>
+
+commands:
+  up         apply all pending migrations
+  down [n]   revert the last n applied migrations (default 1); the
+             baseline cannot be reverted
+  status     list migrations and when they were applied`
+
+// runMigrate implements the migrate subcommand and returns the exit code.
+func runMigrate(conn *gorm.DB, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	switch args[0] {
+	case "up":
+		ran, err := migrate.Up(conn)
+		for _, m := range ran {
+			fmt.Printf("applied  %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(ran) == 0 {
+			fmt.Println("schema is up to date")
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				fmt.Fprintln(os.Stderr, "down: n must be a positive integer")
+				return 2
+			}
+			steps = n
+		}
+		reverted, err := migrate.Down(conn, steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+	case "status":
+		rows, err := migrate.StatusOf(conn)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, s := range rows {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Printf("%04d  %-40s %s\n", s.Version, s.Name, applied)
+		}
+
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	return 0
+}